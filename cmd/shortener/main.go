@@ -5,13 +5,23 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
 
 	"github.com/Kenny201/go-yandex-shortener.git/cmd/shortener/config"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/auth"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/dto"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/shortener"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/workspace"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http/handler"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/blocklist"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/oidc"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/utils/closer"
 )
@@ -28,6 +38,15 @@ func main() {
 	args := config.NewArgs()
 	args.ParseFlags(os.Args[1:])
 
+	keyset, err := middleware.LoadKeyset(viper.GetString("JWT_SECRET"), args.JWTKeys, args.JWTSigningKey)
+
+	if err != nil {
+		slog.Error("failed to load JWT keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	middleware.SetKeyset(keyset)
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -38,15 +57,116 @@ func main() {
 		os.Exit(1)
 	}
 
+	userRepository, err := initializeStorage(args, repository, ".users",
+		func(db *storage.ShortenerDatabase) auth.UserRepository { return storage.NewUserDatabase(db) },
+		func(filePath string) (auth.UserRepository, error) { return storage.NewUserFile(filePath) },
+		func() auth.UserRepository { return storage.NewUserMemory() },
+	)
+
+	if err != nil {
+		slog.Error("failed to initialize user repository", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	apiKeyRepository, err := initializeStorage(args, repository, ".apikeys",
+		func(db *storage.ShortenerDatabase) auth.APIKeyRepository { return storage.NewAPIKeyDatabase(db) },
+		func(filePath string) (auth.APIKeyRepository, error) { return storage.NewAPIKeyFile(filePath) },
+		func() auth.APIKeyRepository { return storage.NewAPIKeyMemory() },
+	)
+
+	if err != nil {
+		slog.Error("failed to initialize API key repository", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	sessionRepository, err := initializeStorage(args, repository, ".sessions",
+		func(db *storage.ShortenerDatabase) auth.SessionRepository { return storage.NewSessionDatabase(db) },
+		func(filePath string) (auth.SessionRepository, error) { return storage.NewSessionFile(filePath) },
+		func() auth.SessionRepository { return storage.NewSessionMemory() },
+	)
+
+	if err != nil {
+		slog.Error("failed to initialize session repository", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	workspaceRepository, err := initializeStorage(args, repository, ".workspaces",
+		func(db *storage.ShortenerDatabase) workspace.Repository { return storage.NewWorkspaceDatabase(db) },
+		func(filePath string) (workspace.Repository, error) { return storage.NewWorkspaceFile(filePath) },
+		func() workspace.Repository { return storage.NewWorkspaceMemory() },
+	)
+
+	if err != nil {
+		slog.Error("failed to initialize workspace repository", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	domains, err := valueobject.NewDomains(args.BaseURL, args.Domains)
+
+	if err != nil {
+		slog.Error("failed to initialize domains", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	keyGenerator, err := initializeKeyGenerator(args, repository)
+
+	if err != nil {
+		slog.Error("failed to initialize short key generator", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	defaultRedirect, err := valueobject.NewRedirectType(args.RedirectType)
+
+	if err != nil || defaultRedirect == 0 {
+		slog.Error("invalid default redirect type", slog.Int("redirectType", args.RedirectType))
+		os.Exit(1)
+	}
+
+	destinationBlocklist, err := initializeBlocklist(args)
+
+	if err != nil {
+		slog.Error("failed to initialize blocklist", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	deleteChannel := make(chan dto.DeleteTask, 100)
 
-	shortenerService := shortener.New(repository, args.BaseURL)
+	quota := shortener.Quota{
+		MaxActiveLinks:  args.MaxActiveLinks,
+		MaxDailyLinks:   args.MaxDailyLinks,
+		MaxBatchSize:    args.MaxBatchSize,
+		MaxIPDailyLinks: args.MaxIPDailyLinks,
+	}
+
+	shortenerService := shortener.New(repository, domains, keyGenerator, defaultRedirect, destinationBlocklist, quota)
 
 	go shortenerService.StartDeletionWorkers(deleteChannel, 5) // 5 воркеров
 
+	shortenerService.StartClickRecorder(100, time.Second)
+
+	if args.ReaperInterval > 0 {
+		shortenerService.StartExpirationReaper(args.ReaperInterval)
+	}
+
+	if args.PurgeAfter > 0 {
+		shortenerService.StartPurger(args.PurgeAfter, time.Hour)
+	}
+
 	urlHandler := handler.New(shortenerService, deleteChannel)
+	identityProvider, err := initializeIdentityProvider(ctx, args)
+
+	if err != nil {
+		slog.Error("failed to initialize OIDC provider", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	http.NewServer(ctx, args.ServerAddress, urlHandler).Start()
+	authService := auth.New(userRepository, apiKeyRepository, sessionRepository, args.AdminEmails, identityProvider)
+	authService.StartSessionCleaner(time.Hour)
+
+	authHandler := handler.NewAuth(authService, shortenerService)
+	workspaceHandler := handler.NewWorkspace(workspace.New(workspaceRepository, authService))
+
+	http.NewServer(ctx, args.ServerAddress, urlHandler, authHandler, workspaceHandler).Start()
 
 	close(deleteChannel)
 }
@@ -54,7 +174,7 @@ func main() {
 func initializeRepository(args *config.Args) (shortener.Repository, error) {
 	switch {
 	case args.DatabaseDNS != "":
-		repo, err := storage.NewShortenerDatabase(args.BaseURL, args.DatabaseDNS)
+		repo, err := storage.NewShortenerDatabase(args.DatabaseDNS)
 		if err != nil {
 			return nil, err
 		}
@@ -64,9 +184,77 @@ func initializeRepository(args *config.Args) (shortener.Repository, error) {
 		return repo, nil
 
 	case args.FileStoragePath != "":
-		return storage.NewShortenerFile(args.BaseURL, args.FileStoragePath)
+		return storage.NewShortenerFile(args.FileStoragePath)
 
 	default:
-		return storage.NewShortenerMemory(args.BaseURL), nil
+		return storage.NewShortenerMemory(), nil
 	}
 }
+
+// initializeStorage создает хранилище того же типа, что и хранилище ссылок: в базе данных, в файле или в памяти.
+// Файловое хранилище сохраняется рядом с файлом ссылок, к имени которого добавляется суффикс suffix.
+func initializeStorage[T any](
+	args *config.Args,
+	repository shortener.Repository,
+	suffix string,
+	inDatabase func(*storage.ShortenerDatabase) T,
+	inFile func(filePath string) (T, error),
+	inMemory func() T,
+) (T, error) {
+	switch repo := repository.(type) {
+	case *storage.ShortenerDatabase:
+		return inDatabase(repo), nil
+
+	case *storage.ShortenerFile:
+		return inFile(args.FileStoragePath + suffix)
+
+	default:
+		return inMemory(), nil
+	}
+}
+
+// initializeKeyGenerator создает генератор коротких ключей по стратегии из конфигурации.
+// Для последовательной стратегии счётчик предоставляет репозиторий.
+func initializeKeyGenerator(args *config.Args, repository shortener.Repository) (valueobject.KeyGenerator, error) {
+	seq, _ := repository.(valueobject.Sequence)
+	return valueobject.NewKeyGenerator(args.KeyStrategy, args.KeyLength, args.KeyAlphabet, seq)
+}
+
+// initializeBlocklist загружает список запрета адресов назначения и запускает отслеживание изменений файла.
+// Если файл не задан, возвращает nil и адреса назначения не ограничиваются.
+func initializeBlocklist(args *config.Args) (shortener.Blocklist, error) {
+	if args.BlocklistFile == "" {
+		return nil, nil
+	}
+
+	watcher, err := blocklist.NewFileWatcher(args.BlocklistFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Watch(); err != nil {
+		return nil, err
+	}
+
+	return watcher, nil
+}
+
+// initializeIdentityProvider настраивает вход через провайдера OpenID Connect, если задан его издатель.
+// Секрет клиента читается из OIDC_CLIENT_SECRET, а адрес возврата по умолчанию строится из базового URL.
+func initializeIdentityProvider(ctx context.Context, args *config.Args) (auth.IdentityProvider, error) {
+	if args.OIDCIssuer == "" {
+		return nil, nil
+	}
+
+	redirectURL := args.OIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimSuffix(args.BaseURL, "/") + "/api/auth/oidc/callback"
+	}
+
+	return oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       args.OIDCIssuer,
+		ClientID:     args.OIDCClientID,
+		ClientSecret: viper.GetString("OIDC_CLIENT_SECRET"),
+		RedirectURL:  redirectURL,
+	})
+}
@@ -0,0 +1,579 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/utils/closer"
+)
+
+const (
+	// minPasswordLength — минимальная длина пароля в символах.
+	minPasswordLength = 8
+
+	// maxPasswordLength — максимальная длина пароля в байтах; bcrypt учитывает только первые 72 байта.
+	maxPasswordLength = 72
+
+	// maxUsernameLength ограничивает длину имени пользователя в символах.
+	maxUsernameLength = 100
+
+	// maxAPIKeyNameLength ограничивает длину названия API-ключа в символах.
+	maxAPIKeyNameLength = 100
+
+	// apiKeyPrefix отличает API-ключи от других секретов, apiKeyBytes задаёт число случайных байт ключа.
+	apiKeyPrefix = "sk_"
+	apiKeyBytes  = 32
+
+	// apiKeyVisibleLength — длина начала ключа, которое сохраняется для отображения в списке ключей.
+	apiKeyVisibleLength = len(apiKeyPrefix) + 8
+
+	// refreshTokenBytes задаёт число случайных байт refresh-токена.
+	refreshTokenBytes = 32
+
+	// SessionTTL — время жизни сессии без обновления; каждое обновление продлевает сессию на этот срок.
+	SessionTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrEmailInvalid       = errors.New("email is invalid")
+	ErrPasswordTooShort   = fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	ErrPasswordTooLong    = fmt.Errorf("password must be at most %d bytes long", maxPasswordLength)
+	ErrUsernameTooLong    = fmt.Errorf("username must be at most %d characters long", maxUsernameLength)
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrAPIKeyNameTooLong  = fmt.Errorf("API key name must be at most %d characters long", maxAPIKeyNameLength)
+	ErrScopesEmpty        = errors.New("at least one scope must be set")
+	ErrScopeInvalid       = fmt.Errorf("scope must be one of %s", strings.Join(entity.Scopes, ", "))
+	ErrAPIKeyInvalid      = errors.New("API key is invalid or revoked")
+	ErrSessionInvalid     = errors.New("refresh token is invalid, expired or revoked")
+	ErrUserDisabled       = errors.New("user account is disabled")
+	ErrRoleInvalid        = fmt.Errorf("role must be one of %s, %s", entity.RoleUser, entity.RoleAdmin)
+)
+
+// dummyHash сравнивается с паролем, когда пользователь не найден,
+// чтобы время ответа не выдавало, зарегистрирован ли email.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// UserRepository определяет интерфейс хранилища зарегистрированных пользователей.
+type UserRepository interface {
+	// CreateUser сохраняет нового пользователя; email должен быть уникальным.
+	CreateUser(user *entity.User) error
+	// GetUserByEmail возвращает пользователя по email.
+	GetUserByEmail(email string) (*entity.User, error)
+	// GetUserByID возвращает пользователя по идентификатору.
+	GetUserByID(id string) (*entity.User, error)
+	// GetUserByOIDCSubject возвращает пользователя, привязанного к пользователю subject провайдера issuer.
+	GetUserByOIDCSubject(issuer, subject string) (*entity.User, error)
+	// UpdateUser сохраняет роль, признак отключения и привязку к провайдеру OpenID Connect пользователя.
+	UpdateUser(user *entity.User) error
+}
+
+// APIKeyRepository определяет интерфейс хранилища API-ключей.
+type APIKeyRepository interface {
+	// CreateAPIKey сохраняет новый API-ключ.
+	CreateAPIKey(key *entity.APIKey) error
+	// GetAPIKeyByHash возвращает API-ключ по хешу.
+	GetAPIKeyByHash(hash string) (*entity.APIKey, error)
+	// ListAPIKeys возвращает API-ключи пользователя в порядке создания.
+	ListAPIKeys(userID string) ([]*entity.APIKey, error)
+	// RevokeAPIKey отзывает действующий API-ключ пользователя.
+	RevokeAPIKey(id, userID string, revokedAt time.Time) error
+}
+
+// SessionRepository определяет интерфейс хранилища сессий.
+type SessionRepository interface {
+	// CreateSession сохраняет новую сессию.
+	CreateSession(session *entity.Session) error
+	// GetSession возвращает сессию по идентификатору.
+	GetSession(id string) (*entity.Session, error)
+	// RotateSession заменяет хеш refresh-токена и срок действия неотозванной сессии,
+	// только если текущий хеш совпадает с oldHash.
+	RotateSession(id, oldHash, newHash string, expiresAt time.Time) error
+	// RevokeSession отзывает сессию.
+	RevokeSession(id string, revokedAt time.Time) error
+	// RevokeUserSessions отзывает все действующие сессии пользователя и возвращает их количество.
+	RevokeUserSessions(userID string, revokedAt time.Time) (int64, error)
+	// DeleteExpired удаляет сессии, истёкшие к моменту before, и возвращает их количество.
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// Auth регистрирует пользователей, проверяет их учётные данные, управляет ролями, сессиями и API-ключами.
+type Auth struct {
+	users       UserRepository
+	keys        APIKeyRepository
+	sessions    SessionRepository
+	adminEmails []string
+	idp         IdentityProvider
+}
+
+// New создает новый сервис учётных записей пользователей.
+// Пользователи с email из adminEmails получают роль администратора при входе через провайдера OpenID Connect,
+// подтвердившего этот email. Регистрация и вход по паролю владение email не проверяют, поэтому роль не назначают.
+// Если idp равен nil, вход через провайдера OpenID Connect недоступен.
+func New(users UserRepository, keys APIKeyRepository, sessions SessionRepository, adminEmails []string, idp IdentityProvider) *Auth {
+	admins := make([]string, 0, len(adminEmails))
+	for _, email := range adminEmails {
+		admins = append(admins, strings.ToLower(strings.TrimSpace(email)))
+	}
+
+	return &Auth{users: users, keys: keys, sessions: sessions, adminEmails: admins, idp: idp}
+}
+
+// Register создает учётную запись с хешированным паролем и возвращает её.
+func (a *Auth) Register(email, password, username string) (*entity.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+
+	if len(password) > maxPasswordLength {
+		return nil, ErrPasswordTooLong
+	}
+
+	username = strings.TrimSpace(username)
+	if utf8.RuneCountInString(username) > maxUsernameLength {
+		return nil, ErrUsernameTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := entity.NewUser(username, email, string(hash))
+
+	if err := a.users.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Login проверяет email и пароль и возвращает пользователя.
+// Для неизвестного email и неверного пароля возвращается одна и та же ошибка ErrInvalidCredentials.
+func (a *Auth) Login(email, password string) (*entity.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := a.users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+
+	return user, nil
+}
+
+// grantAdminRole назначает роль администратора пользователю, вошедшему через провайдера OpenID Connect,
+// если провайдер подтвердил email личности identity и этот email указан в списке администраторов.
+func (a *Auth) grantAdminRole(user *entity.User, identity *entity.Identity) error {
+	if !identity.EmailVerified || user.UserRole() == entity.RoleAdmin {
+		return nil
+	}
+
+	email, err := normalizeEmail(identity.Email)
+	if err != nil || !slices.Contains(a.adminEmails, email) {
+		return nil
+	}
+
+	user.Role = entity.RoleAdmin
+	if err := a.users.UpdateUser(user); err != nil {
+		return err
+	}
+
+	slog.Info("Admin role granted", slog.String("userID", user.ID.String()))
+	return nil
+}
+
+// UserRole возвращает роль пользователя для токена доступа.
+// Анонимные посетители, у которых нет учётной записи, имеют роль обычного пользователя.
+func (a *Auth) UserRole(userID string) (string, error) {
+	user, err := a.users.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return entity.RoleUser, nil
+		}
+		return "", err
+	}
+
+	return user.UserRole(), nil
+}
+
+// SetUserRole назначает пользователю роль role и возвращает его учётную запись.
+// Позволяет администратору, в том числе вошедшему по ADMIN_TOKEN, выдать роль администратора без входа через провайдера.
+func (a *Auth) SetUserRole(userID, role string) (*entity.User, error) {
+	if role != entity.RoleUser && role != entity.RoleAdmin {
+		return nil, ErrRoleInvalid
+	}
+
+	user, err := a.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.UserRole() == role {
+		return user, nil
+	}
+
+	user.Role = role
+	if err := a.users.UpdateUser(user); err != nil {
+		return nil, err
+	}
+
+	slog.Info("User role changed", slog.String("userID", userID), slog.String("role", role))
+	return user, nil
+}
+
+// SetUserDisabled отключает или снова включает учётную запись и возвращает её.
+// Отключённый пользователь не может войти, его сессии завершаются, а API-ключи перестают приниматься.
+func (a *Auth) SetUserDisabled(userID string, disabled bool) (*entity.User, error) {
+	user, err := a.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() == disabled {
+		return user, nil
+	}
+
+	user.DisabledAt = nil
+	if disabled {
+		now := time.Now().UTC()
+		user.DisabledAt = &now
+	}
+
+	if err := a.users.UpdateUser(user); err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		if _, err := a.EndUserSessions(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	slog.Info("User disabled state changed", slog.String("userID", userID), slog.Bool("disabled", disabled))
+	return user, nil
+}
+
+// IsRegistered сообщает, принадлежит ли идентификатор зарегистрированному пользователю.
+// Идентификаторы, выданные анонимным посетителям, не принадлежат ни одной учётной записи.
+func (a *Auth) IsRegistered(userID string) (bool, error) {
+	if _, err := a.users.GetUserByID(userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AccountEmail возвращает email зарегистрированного пользователя userID.
+// Для анонимных посетителей возвращает storage.ErrUserNotFound.
+func (a *Auth) AccountEmail(userID string) (string, error) {
+	user, err := a.users.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	return user.Email, nil
+}
+
+// AccountID возвращает идентификатор зарегистрированного пользователя с email
+// или storage.ErrUserNotFound, если такого пользователя нет.
+func (a *Auth) AccountID(email string) (string, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := a.users.GetUserByEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	return user.ID.String(), nil
+}
+
+// CreateAPIKey выпускает API-ключ пользователя с заданными областями доступа.
+// Возвращает сохранённый ключ и сам ключ, который показывается только один раз.
+func (a *Auth) CreateAPIKey(userID, name string, scopes []string) (*entity.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return nil, "", ErrAPIKeyNameTooLong
+	}
+
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	rawKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	key := entity.NewAPIKey(userID, name, rawKey[:apiKeyVisibleLength], hashAPIKey(rawKey), scopes)
+
+	if err := a.keys.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+// ListAPIKeys возвращает API-ключи пользователя, включая отозванные.
+func (a *Auth) ListAPIKeys(userID string) ([]*entity.APIKey, error) {
+	return a.keys.ListAPIKeys(userID)
+}
+
+// RevokeAPIKey отзывает API-ключ пользователя; отозванный ключ больше не принимается.
+func (a *Auth) RevokeAPIKey(userID, id string) error {
+	return a.keys.RevokeAPIKey(id, userID, time.Now().UTC())
+}
+
+// AuthenticateAPIKey проверяет API-ключ и возвращает идентификатор владельца и области доступа ключа.
+func (a *Auth) AuthenticateAPIKey(rawKey string) (string, []string, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return "", nil, ErrAPIKeyInvalid
+	}
+
+	key, err := a.keys.GetAPIKeyByHash(hashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return "", nil, ErrAPIKeyInvalid
+		}
+		return "", nil, err
+	}
+
+	if key.IsRevoked() {
+		return "", nil, ErrAPIKeyInvalid
+	}
+
+	owner, err := a.users.GetUserByID(key.UserID)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return "", nil, err
+	}
+
+	if owner != nil && owner.IsDisabled() {
+		return "", nil, ErrAPIKeyInvalid
+	}
+
+	return key.UserID, key.Scopes, nil
+}
+
+// StartSession создает сессию пользователя.
+// Возвращает сохранённую сессию и refresh-токен, который хранится только у клиента.
+func (a *Auth) StartSession(userID string) (*entity.Session, string, error) {
+	secret, err := generateRefreshSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session := entity.NewSession(userID, hashAPIKey(secret), time.Now().UTC().Add(SessionTTL))
+
+	if err := a.sessions.CreateSession(session); err != nil {
+		return nil, "", err
+	}
+
+	return session, session.ID.String() + "." + secret, nil
+}
+
+// RefreshSession обменивает refresh-токен на новый и продлевает сессию.
+// Повторное предъявление уже обменянного токена означает его утечку, поэтому сессия отзывается.
+func (a *Auth) RefreshSession(refreshToken string) (*entity.Session, string, error) {
+	session, secret, err := a.findSession(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if session.TokenHash != hashAPIKey(secret) {
+		slog.Warn("Refresh token reuse detected, revoking session",
+			slog.String("sessionID", session.ID.String()), slog.String("userID", session.UserID))
+
+		if err := a.sessions.RevokeSession(session.ID.String(), time.Now().UTC()); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrSessionInvalid
+	}
+
+	newSecret, err := generateRefreshSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	expiresAt := time.Now().UTC().Add(SessionTTL)
+
+	err = a.sessions.RotateSession(session.ID.String(), session.TokenHash, hashAPIKey(newSecret), expiresAt)
+	if err != nil {
+		// Токен успели обменять параллельным запросом.
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, "", ErrSessionInvalid
+		}
+		return nil, "", err
+	}
+
+	session.TokenHash = hashAPIKey(newSecret)
+	session.ExpiresAt = expiresAt
+
+	return session, session.ID.String() + "." + newSecret, nil
+}
+
+// LookupSession возвращает действующую сессию по refresh-токену, не обменивая его.
+func (a *Auth) LookupSession(refreshToken string) (*entity.Session, error) {
+	session, secret, err := a.findSession(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.TokenHash != hashAPIKey(secret) {
+		return nil, ErrSessionInvalid
+	}
+
+	return session, nil
+}
+
+// IsSessionActive сообщает, что сессия существует, не отозвана и не истекла.
+func (a *Auth) IsSessionActive(sessionID string) (bool, error) {
+	session, err := a.sessions.GetSession(sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return session.IsActive(time.Now()), nil
+}
+
+// EndSession отзывает сессию; выпущенные в ней токены доступа и refresh-токен больше не принимаются.
+func (a *Auth) EndSession(sessionID string) error {
+	return a.sessions.RevokeSession(sessionID, time.Now().UTC())
+}
+
+// EndUserSessions отзывает все сессии пользователя и возвращает их количество.
+func (a *Auth) EndUserSessions(userID string) (int64, error) {
+	return a.sessions.RevokeUserSessions(userID, time.Now().UTC())
+}
+
+// StartSessionCleaner запускает фоновую задачу, которая с заданным интервалом удаляет истёкшие сессии.
+// Задача останавливается при закрытии приложения.
+func (a *Auth) StartSessionCleaner(interval time.Duration) {
+	closer.RunPeriodically("session cleaner", interval, func() error {
+		count, err := a.sessions.DeleteExpired(time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			slog.Info("Expired sessions deleted", slog.Int64("count", count))
+		}
+		return nil
+	})
+}
+
+// findSession разбирает refresh-токен вида "<идентификатор сессии>.<секрет>"
+// и возвращает действующую сессию и секрет токена.
+func (a *Auth) findSession(refreshToken string) (*entity.Session, string, error) {
+	sessionID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" || secret == "" {
+		return nil, "", ErrSessionInvalid
+	}
+
+	session, err := a.sessions.GetSession(sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, "", ErrSessionInvalid
+		}
+		return nil, "", err
+	}
+
+	if !session.IsActive(time.Now()) {
+		return nil, "", ErrSessionInvalid
+	}
+
+	return session, secret, nil
+}
+
+// generateRefreshSecret возвращает случайный секрет refresh-токена.
+func generateRefreshSecret() (string, error) {
+	secret := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashAPIKey возвращает хеш API-ключа или секрета refresh-токена для хранения и поиска.
+// Секрет содержит 256 случайных бит, поэтому медленный хеш, как для паролей, не нужен.
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes проверяет области доступа и возвращает их без повторов в порядке объявления.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrScopesEmpty
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(entity.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrScopeInvalid, scope)
+		}
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range entity.Scopes {
+		if slices.Contains(scopes, scope) {
+			result = append(result, scope)
+		}
+	}
+
+	return result, nil
+}
+
+// normalizeEmail проверяет адрес электронной почты и приводит его к нижнему регистру.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrEmailInvalid
+	}
+
+	return strings.ToLower(email), nil
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+// oidcSecretBytes задаёт число случайных байт параметров state и nonce и кода проверки PKCE.
+const oidcSecretBytes = 32
+
+var (
+	ErrOIDCDisabled     = errors.New("OIDC login is not configured")
+	ErrOIDCLoginFailed  = errors.New("OIDC login failed")
+	ErrOIDCEmailMissing = errors.New("identity provider did not return an email")
+	ErrOIDCEmailTaken   = errors.New("email belongs to another account and is not verified by the identity provider")
+)
+
+// IdentityProvider определяет внешний провайдер OpenID Connect.
+type IdentityProvider interface {
+	// AuthCodeURL возвращает адрес страницы входа провайдера, запрашивающий код авторизации с PKCE.
+	AuthCodeURL(state, nonce, codeChallenge string) string
+	// Exchange обменивает код авторизации на ID-токен, проверяет его и возвращает личность пользователя.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*entity.Identity, error)
+}
+
+// OIDCFlow описывает начатый вход через провайдера. State, Nonce и CodeVerifier хранятся у клиента
+// до возврата от провайдера и предъявляются вместе с кодом авторизации.
+type OIDCFlow struct {
+	URL          string // Адрес страницы входа провайдера
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// StartOIDC начинает вход через провайдера OpenID Connect: генерирует state, nonce и код проверки PKCE
+// и возвращает адрес страницы входа провайдера.
+func (a *Auth) StartOIDC() (*OIDCFlow, error) {
+	if a.idp == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	secrets := make([]string, 3)
+	for i := range secrets {
+		secret, err := generateOIDCSecret()
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = secret
+	}
+
+	flow := &OIDCFlow{State: secrets[0], Nonce: secrets[1], CodeVerifier: secrets[2]}
+
+	challenge := sha256.Sum256([]byte(flow.CodeVerifier))
+	flow.URL = a.idp.AuthCodeURL(flow.State, flow.Nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+
+	return flow, nil
+}
+
+// FinishOIDC обменивает код авторизации на ID-токен и возвращает локального пользователя, которому он выдан.
+// Пользователь ищется по издателю и идентификатору у провайдера. Если такого нет, к личности привязывается
+// учётная запись с тем же email, но только если провайдер подтвердил email; иначе создаётся новая учётная запись.
+func (a *Auth) FinishOIDC(ctx context.Context, code, codeVerifier, nonce string) (*entity.User, error) {
+	if a.idp == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	identity, err := a.idp.Exchange(ctx, code, codeVerifier, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	user, err := a.userForIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+
+	if err := a.grantAdminRole(user, identity); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// userForIdentity возвращает пользователя, привязанного к личности identity, привязывая или создавая его при первом входе.
+func (a *Auth) userForIdentity(identity *entity.Identity) (*entity.User, error) {
+	user, err := a.users.GetUserByOIDCSubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+
+	email, err := normalizeEmail(identity.Email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	user, err = a.users.GetUserByEmail(email)
+	if err == nil {
+		return a.linkIdentity(user, identity)
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return nil, err
+	}
+
+	username := strings.TrimSpace(identity.Name)
+	if utf8.RuneCountInString(username) > maxUsernameLength {
+		username = ""
+	}
+
+	user = entity.NewUser(username, email, "")
+	user.OIDCIssuer = identity.Issuer
+	user.OIDCSubject = identity.Subject
+
+	if err := a.users.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	slog.Info("User registered via OIDC", slog.String("userID", user.ID.String()), slog.String("issuer", identity.Issuer))
+	return user, nil
+}
+
+// linkIdentity привязывает личность провайдера к существующей учётной записи с тем же email.
+// Без подтверждения email провайдером привязка позволила бы войти в чужую учётную запись,
+// поэтому в этом случае, как и при уже привязанной другой личности, возвращается ErrOIDCEmailTaken.
+func (a *Auth) linkIdentity(user *entity.User, identity *entity.Identity) (*entity.User, error) {
+	if !identity.EmailVerified || user.OIDCSubject != "" {
+		return nil, ErrOIDCEmailTaken
+	}
+
+	user.OIDCIssuer = identity.Issuer
+	user.OIDCSubject = identity.Subject
+
+	if err := a.users.UpdateUser(user); err != nil {
+		return nil, err
+	}
+
+	slog.Info("OIDC identity linked", slog.String("userID", user.ID.String()), slog.String("issuer", identity.Issuer))
+	return user, nil
+}
+
+// generateOIDCSecret возвращает случайное значение для параметров state и nonce или кода проверки PKCE.
+func generateOIDCSecret() (string, error) {
+	secret := make([]byte, oidcSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate OIDC secret: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
@@ -0,0 +1,11 @@
+package dto
+
+// BlockedURL описывает сохранённую ссылку, адрес назначения которой запрещён списком запрета.
+type BlockedURL struct {
+	ShortURL    string `json:"short_url"`
+	ShortKey    string `json:"short_key"`
+	Domain      string `json:"domain,omitempty"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	Rule        string `json:"rule"`
+}
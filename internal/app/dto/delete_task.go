@@ -1,6 +1,9 @@
 package dto
 
+import "github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+
 type DeleteTask struct {
+	Domain    string
 	ShortKeys []string
-	UserID    string
+	Owner     entity.Owner
 }
@@ -0,0 +1,16 @@
+package dto
+
+import "time"
+
+// QuotaUsage описывает квоты пользователя и его IP-адреса на создание ссылок и их текущее использование.
+// Нулевой лимит означает, что ограничение не задано.
+type QuotaUsage struct {
+	MaxActiveLinks  int
+	ActiveLinks     int
+	MaxDailyLinks   int
+	DailyLinks      int
+	DailyResetAt    time.Time // Начало следующих суток по UTC, когда обнуляется суточная квота
+	MaxBatchSize    int
+	MaxIPDailyLinks int
+	IPDailyLinks    int // Ссылки, созданные с IP-адреса за текущие сутки по UTC
+}
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+// ShortenOptions содержит необязательные параметры создания короткой ссылки.
+type ShortenOptions struct {
+	Alias        string
+	ExpiresAt    *time.Time
+	TTL          time.Duration
+	Tags         []string
+	Domain       string // Домен из запроса или заголовка Host; пустая строка означает домен по умолчанию
+	RedirectType int    // Код статуса редиректа; 0 означает значение по умолчанию
+	PassQuery    bool   // Передавать параметры запроса в адрес назначения при редиректе
+	UTM          *entity.UTM
+	Title        string // Заголовок для страницы предпросмотра
+}
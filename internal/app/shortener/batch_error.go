@@ -0,0 +1,37 @@
+package shortener
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BatchItemError описывает ошибку проверки элемента пакетного запроса с идентификатором корреляции ID.
+type BatchItemError struct {
+	ID  string
+	Err error
+}
+
+// BatchError перечисляет все некорректные элементы пакетного запроса, чтобы клиент мог исправить их за один раз.
+type BatchError struct {
+	Items []BatchItemError
+}
+
+// Error возвращает ошибки всех элементов пачки через точку с запятой.
+func (e *BatchError) Error() string {
+	messages := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		messages = append(messages, fmt.Sprintf("correlation_id %s: %v", item.ID, item.Err))
+	}
+
+	return "invalid batch items: " + strings.Join(messages, "; ")
+}
+
+// Unwrap возвращает ошибки элементов, чтобы errors.Is находил причину отказа.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Items))
+	for _, item := range e.Items {
+		errs = append(errs, item.Err)
+	}
+
+	return errs
+}
@@ -2,87 +2,366 @@ package shortener
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/dto"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/utils/closer"
+)
+
+const (
+	// defaultListLimit и maxListLimit ограничивают размер страницы при выводе ссылок пользователя.
+	defaultListLimit = 100
+	maxListLimit     = 1000
+
+	// clickBufferSize задаёт размер буфера событий перехода; при его переполнении события отбрасываются,
+	// чтобы запись статистики не замедляла редирект.
+	clickBufferSize = 1024
+
+	// maxKeyAttempts ограничивает число попыток сгенерировать свободный короткий ключ.
+	maxKeyAttempts = 5
+
+	// maxUTMLength ограничивает длину значения UTM-метки.
+	maxUTMLength = 255
+
+	// maxTitleLength ограничивает длину заголовка ссылки в символах.
+	maxTitleLength = 200
+)
+
+var (
+	ErrExpirationConflict = errors.New("only one of expires_at and ttl can be set")
+	ErrExpirationInPast   = errors.New("expiration time must be in the future")
+	ErrInvalidTTL         = errors.New("ttl must be positive")
+	ErrKeyspaceExhausted  = errors.New("failed to generate a unique short key, the key space may be exhausted")
+	ErrNothingToUpdate    = errors.New("at least one of url, redirect_type, pass_query, utm and title must be set")
+	ErrTitleTooLong       = fmt.Errorf("title must be at most %d characters long", maxTitleLength)
+	ErrUTMTooLong         = fmt.Errorf("utm values must be at most %d characters long", maxUTMLength)
+	ErrDuplicateInBatch   = errors.New("url is repeated in the batch")
+	ErrURLBlocked         = errors.New("url destination is blocked")
+	ErrQuotaExceeded      = errors.New("link quota exceeded")
+	ErrBatchTooLarge      = errors.New("batch is too large")
+	ErrNothingRestored    = errors.New("no deleted links found to restore; expired links cannot be restored")
 )
 
 // Repository определяет интерфейс для работы с хранилищем сокращённых ссылок.
 // Реализации этого интерфейса могут быть на базе различных хранилищ данных (память, файл, база данных и т.д.).
 type Repository interface {
-	// Get возвращает URL-объект по его идентификатору (короткому ключу).
-	Get(shortKey string) (*entity.URL, error)
+	// Get возвращает URL-объект по домену и короткому ключу.
+	Get(domain, shortKey string) (*entity.URL, error)
 	// Create создает новый короткий URL и возвращает его.
 	Create(url *entity.URL) (*entity.URL, error)
 	// CreateList создает несколько коротких URL и возвращает список созданных элементов.
 	CreateList(userID interface{}, urls []*entity.URLItem) ([]*entity.URLItem, error)
-	// GetAll получает все сокращённые ссылки пользователя
-	GetAll(userID string) ([]*entity.URLItem, error)
-	// MarkAsDeleted помечает определённые ссылки как удалённые
-	MarkAsDeleted(batch []string, userID string) error
+	// GetAll получает страницу сокращённых ссылок владельца и курсор следующей страницы
+	GetAll(owner entity.Owner, opts entity.ListOptions) ([]*entity.URLItem, string, error)
+	// FindAll получает страницу ссылок всех пользователей и курсор следующей страницы.
+	FindAll(opts entity.ListOptions) ([]*entity.URLItem, string, error)
+	// Update применяет изменения к ссылке на домене, принадлежащей владельцу.
+	Update(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URL, error)
+	// AddTags добавляет теги к ссылке владельца на домене и возвращает итоговый набор тегов.
+	AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error)
+	// RemoveTags удаляет теги у ссылки владельца на домене и возвращает итоговый набор тегов.
+	RemoveTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error)
+	// MarkAsDeleted помечает определённые ссылки на домене как удалённые
+	MarkAsDeleted(domain string, batch []string, owner entity.Owner) error
+	// Restore снимает пометку об удалении с определённых ссылок на домене, срок жизни которых не истёк,
+	// и возвращает их количество.
+	Restore(domain string, batch []string, owner entity.Owner) (int64, error)
+	// ForceMarkAsDeleted помечает ссылки на домене как удалённые независимо от владельца и возвращает их количество.
+	ForceMarkAsDeleted(domain string, shortKeys []string) (int64, error)
+	// ForceRestore снимает пометку об удалении со ссылок на домене, срок жизни которых не истёк, независимо от владельца
+	// и возвращает их количество.
+	ForceRestore(domain string, shortKeys []string) (int64, error)
+	// TransferOwnership передаёт все ссылки одного пользователя другому и возвращает их количество.
+	TransferOwnership(fromUserID, toUserID string) (int64, error)
+	// MarkExpiredAsDeleted помечает как удалённые ссылки с истёкшим сроком жизни и возвращает их количество.
+	MarkExpiredAsDeleted(now time.Time) (int64, error)
+	// Purge физически удаляет ссылки, помеченные как удалённые не позднее deletedBefore, и возвращает их количество.
+	Purge(deletedBefore time.Time) (int64, error)
+	// SaveClicks сохраняет пачку событий перехода по ссылкам.
+	SaveClicks(clicks []*entity.Click) error
+	// GetClickStats возвращает статистику переходов по ссылке на домене, принадлежащей владельцу.
+	GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error)
+	// CountUsage возвращает количество активных ссылок пользователя, ссылок, созданных им начиная с createdSince,
+	// и ссылок, созданных начиная с createdSince с IP-адреса с хешем ipHash. Пустые userID и ipHash не подсчитываются.
+	CountUsage(userID, ipHash string, createdSince time.Time) (entity.URLUsage, error)
+	// FindActive возвращает не удалённые ссылки, адрес назначения которых удовлетворяет match.
+	FindActive(match func(originalURL string) bool) ([]*entity.URL, error)
 	// CheckHealth проверяет состояние хранилища (доступность, целостность и т.д.).
 	CheckHealth() error
 }
 
+// Blocklist определяет список запрещённых адресов назначения.
+type Blocklist interface {
+	// Match сообщает, запрещён ли адрес назначения, и возвращает сработавшее правило.
+	Match(rawURL string) (string, bool)
+}
+
+// Quota задаёт ограничения на создание ссылок одним пользователем и с одного IP-адреса.
+// Нулевое значение снимает ограничение.
+type Quota struct {
+	MaxActiveLinks  int // Максимальное число активных ссылок пользователя
+	MaxDailyLinks   int // Максимальное число ссылок, создаваемых пользователем за сутки по UTC
+	MaxBatchSize    int // Максимальное число ссылок в одном пакетном запросе
+	MaxIPDailyLinks int // Максимальное число ссылок, создаваемых с одного IP-адреса за сутки по UTC
+}
+
 // Shortener представляет собой основной сервис для работы с сокращёнными ссылками.
 // Он использует репозиторий для сохранения и получения данных.
 type Shortener struct {
-	repo    Repository
-	baseURL string
+	repo            Repository
+	domains         *valueobject.Domains
+	keyGenerator    valueobject.KeyGenerator
+	defaultRedirect valueobject.RedirectType
+	blocklist       Blocklist
+	quota           Quota
+	clicks          chan *entity.Click
+}
+
+// New создает новый экземпляр сервиса Shortener с заданным репозиторием, набором обслуживаемых доменов,
+// генератором коротких ключей, типом редиректа для ссылок, у которых он не задан, списком запрета
+// адресов назначения и квотами пользователей. Если blocklist равен nil, адреса назначения не ограничиваются.
+func New(repository Repository, domains *valueobject.Domains, keyGenerator valueobject.KeyGenerator, defaultRedirect valueobject.RedirectType, blocklist Blocklist, quota Quota) *Shortener {
+	return &Shortener{
+		repo:            repository,
+		domains:         domains,
+		keyGenerator:    keyGenerator,
+		defaultRedirect: defaultRedirect,
+		blocklist:       blocklist,
+		quota:           quota,
+		clicks:          make(chan *entity.Click, clickBufferSize),
+	}
+}
+
+// GetShortURL возвращает сокращённую ссылку по домену и короткому ключу или ошибку, если ссылка не найдена.
+// Для ссылки, адрес назначения которой запрещён, возвращает ErrURLBlocked.
+func (s *Shortener) GetShortURL(domain, shortKey string) (*entity.URL, error) {
+	url, err := s.repo.Get(domain, shortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkBlocked(url.OriginalURL); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// checkBlocked возвращает ErrURLBlocked, если адрес назначения запрещён списком запрета.
+func (s *Shortener) checkBlocked(originalURL string) error {
+	if s.blocklist == nil {
+		return nil
+	}
+
+	if rule, blocked := s.blocklist.Match(originalURL); blocked {
+		slog.Warn("Blocked destination", slog.String("originalURL", originalURL), slog.String("rule", rule))
+		return ErrURLBlocked
+	}
+
+	return nil
+}
+
+// BlockedURLs возвращает сохранённые активные ссылки, адреса назначения которых запрещены текущим списком запрета.
+func (s *Shortener) BlockedURLs() ([]*dto.BlockedURL, error) {
+	if s.blocklist == nil {
+		return []*dto.BlockedURL{}, nil
+	}
+
+	urls, err := s.repo.FindActive(func(originalURL string) bool {
+		_, blocked := s.blocklist.Match(originalURL)
+		return blocked
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make([]*dto.BlockedURL, 0, len(urls))
+	for _, url := range urls {
+		rule, _ := s.blocklist.Match(url.OriginalURL)
+		userID, _ := url.UserID.(string)
+		blocked = append(blocked, &dto.BlockedURL{
+			ShortURL:    s.domains.ShortURL(url.Domain, url.ShortKey),
+			ShortKey:    url.ShortKey,
+			Domain:      url.Domain,
+			OriginalURL: url.OriginalURL,
+			UserID:      userID,
+			Rule:        rule,
+		})
+	}
+
+	return blocked, nil
 }
 
-// New создает новый экземпляр сервиса Shortener с заданным репозиторием.
-func New(repository Repository, baseURL string) *Shortener {
-	return &Shortener{repo: repository, baseURL: baseURL}
+// RedirectType возвращает тип редиректа ссылки или тип по умолчанию, если для ссылки он не задан.
+func (s *Shortener) RedirectType(url *entity.URL) valueobject.RedirectType {
+	if url.RedirectType == 0 {
+		return s.defaultRedirect
+	}
+	return valueobject.RedirectType(url.RedirectType)
 }
 
-// GetShortURL возвращает сокращённую ссылку по короткому ключу или ошибку, если ссылка не найдена.
-func (s *Shortener) GetShortURL(shortKey string) (*entity.URL, error) {
-	return s.repo.Get(shortKey)
+// ResolveDomain возвращает домен для хранения по имени из запроса. Пустое имя означает домен по умолчанию.
+// Возвращает ErrUnknownDomain, если домен не настроен.
+func (s *Shortener) ResolveDomain(name string) (string, error) {
+	return s.domains.Resolve(name)
 }
 
-// CreateShortURL сохраняет оригинальный URL в хранилище и возвращает сокращённую ссылку.
+// DomainFromHost возвращает домен, к которому относится запрос с заголовком Host.
+// Запросы на неизвестные хосты обслуживаются доменом по умолчанию.
+func (s *Shortener) DomainFromHost(host string) string {
+	return s.domains.FromHost(host)
+}
+
+// CreateShortURL проверяет и приводит оригинальный URL к каноническому виду, сохраняет его в хранилище
+// и возвращает сокращённую ссылку.
+// Если в opts передан алиас, он используется в качестве короткого ключа вместо сгенерированного,
+// expires_at или ttl задают срок жизни ссылки, domain — домен, на котором создаётся ссылка,
+// redirect_type — код статуса редиректа, а pass_query и utm — параметры, добавляемые к адресу назначения.
 // В случае ошибки возвращает пустую ссылку и ошибку.
-func (s *Shortener) CreateShortURL(ctx context.Context, originalURL string) (string, error) {
+func (s *Shortener) CreateShortURL(ctx context.Context, originalURL string, opts dto.ShortenOptions) (string, error) {
 	var userID interface{}
 	var ok bool
 
 	userID, ok = ctx.Value(middleware.UserIDContextKey).(string)
 
-	baseURL, err := valueobject.NewBaseURL(s.baseURL)
+	originalURL, err := valueobject.NormalizeURL(originalURL)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.checkBlocked(originalURL); err != nil {
+		return "", err
+	}
+
+	domain, err := s.domains.Resolve(opts.Domain)
+	if err != nil {
+		return "", err
+	}
+	baseURL := s.domains.BaseURL(domain)
+
+	expiresAt, err := resolveExpiration(opts.ExpiresAt, opts.TTL, time.Now())
 	if err != nil {
 		return "", err
 	}
 
-	shortURL := valueobject.NewShortURL(baseURL)
+	tags, err := valueobject.NormalizeTags(opts.Tags)
+	if err != nil {
+		return "", err
+	}
+
+	redirectType, err := valueobject.NewRedirectType(opts.RedirectType)
+	if err != nil {
+		return "", err
+	}
+
+	utm, err := normalizeUTM(opts.UTM)
+	if err != nil {
+		return "", err
+	}
+
+	title, err := normalizeTitle(opts.Title)
+	if err != nil {
+		return "", err
+	}
 
 	if !ok || userID == "" {
 		userID = nil // Передаем NULL
 	}
 
-	urlEntity := entity.NewURL(userID, originalURL, shortURL.ShortKey())
-	shortURLStr := fmt.Sprintf("%s/%s", baseURL.ToString(), urlEntity.ShortKey)
-	url, err := s.repo.Create(urlEntity)
+	ip := clientIPFromContext(ctx)
+	if err := s.checkQuota(userID, ip, 1); err != nil {
+		return "", err
+	}
+
+	var shortURLStr string
+
+	// Сгенерированный ключ может совпасть с уже существующим: в этом случае генерируем новый.
+	// Занятый пользовательский алиас повторно не генерируется, ошибка возвращается сразу.
+	for attempt := 0; ; attempt++ {
+		shortURL, err := s.newShortURL(baseURL, originalURL, opts.Alias, attempt)
+		if err != nil {
+			return "", err
+		}
+
+		urlEntity := entity.NewURL(userID, originalURL, shortURL.ShortKey())
+		urlEntity.WorkspaceID = workspaceIDFromContext(ctx)
+		urlEntity.Domain = domain
+		urlEntity.ExpiresAt = expiresAt
+		urlEntity.Tags = tags
+		urlEntity.RedirectType = redirectType.StatusCode()
+		urlEntity.PassQuery = opts.PassQuery
+		urlEntity.UTM = utm
+		urlEntity.Title = title
+		urlEntity.CreatorIPHash = hashIP(ip)
+		shortURLStr = shortURL.ToString()
+		url, err := s.repo.Create(urlEntity)
+
+		if err == nil {
+			break
+		}
 
-	if err != nil {
 		if errors.Is(err, storage.ErrURLAlreadyExist) {
-			return fmt.Sprintf("%s/%s", baseURL.ToString(), url.ShortKey), storage.ErrURLAlreadyExist
+			return s.domains.ShortURL(url.Domain, url.ShortKey), storage.ErrURLAlreadyExist
 		}
 
-		return "", err
+		if !errors.Is(err, storage.ErrShortKeyTaken) || opts.Alias != "" {
+			return "", err
+		}
+
+		if attempt+1 >= maxKeyAttempts {
+			slog.Error("Short key space exhausted", slog.String("originalURL", originalURL), slog.Int("attempts", maxKeyAttempts))
+			return "", ErrKeyspaceExhausted
+		}
+
+		slog.Warn("Short key collision, regenerating", slog.String("shortKey", urlEntity.ShortKey), slog.Int("attempt", attempt+1))
 	}
 
 	slog.Info("URL created", slog.String("originalURL", originalURL), slog.String("shortURL", shortURLStr))
 	return shortURLStr, nil
 }
 
-// CreateListShortURL сохраняет список оригинальных URL в хранилище и возвращает список сокращённых ссылок.
+// newShortURL создает короткую ссылку из пользовательского алиаса или с ключом от генератора, если алиас не задан.
+func (s *Shortener) newShortURL(baseURL valueobject.BaseURL, originalURL, alias string, attempt int) (valueobject.ShortURL, error) {
+	if alias == "" {
+		return valueobject.NewShortURL(baseURL, s.keyGenerator, originalURL, attempt)
+	}
+
+	return valueobject.NewShortURLFromAlias(baseURL, alias)
+}
+
+// resolveExpiration вычисляет момент истечения ссылки из абсолютного времени или времени жизни.
+// Возвращает nil, если срок жизни не задан.
+func resolveExpiration(expiresAt *time.Time, ttl time.Duration, now time.Time) (*time.Time, error) {
+	switch {
+	case expiresAt != nil && ttl != 0:
+		return nil, ErrExpirationConflict
+	case ttl < 0:
+		return nil, ErrInvalidTTL
+	case ttl > 0:
+		t := now.Add(ttl)
+		return &t, nil
+	case expiresAt != nil && !expiresAt.After(now):
+		return nil, ErrExpirationInPast
+	}
+
+	return expiresAt, nil
+}
+
+// CreateListShortURL проверяет и приводит к каноническому виду список оригинальных URL, сохраняет его в хранилище
+// и возвращает список сокращённых ссылок. Ошибка проверки любого элемента отклоняет всю пачку.
+// Короткие ключи для всех элементов генерируются до обращения к хранилищу и перегенерируются при коллизии.
 // В случае ошибки возвращает список частично созданных ссылок и ошибку.
 func (s *Shortener) CreateListShortURL(ctx context.Context, urls []*entity.URLItem) ([]*entity.URLItem, error) {
 
@@ -90,6 +369,23 @@ func (s *Shortener) CreateListShortURL(ctx context.Context, urls []*entity.URLIt
 		return nil, storage.ErrEmptyURL
 	}
 
+	if s.quota.MaxBatchSize > 0 && len(urls) > s.quota.MaxBatchSize {
+		return nil, fmt.Errorf("%w: at most %d URLs are allowed in a batch", ErrBatchTooLarge, s.quota.MaxBatchSize)
+	}
+
+	now := time.Now()
+	seen := make(map[string]struct{}, len(urls))
+	batchErr := &BatchError{}
+	for _, urlItem := range urls {
+		if err := s.prepareBatchItem(urlItem, seen, now); err != nil {
+			batchErr.Items = append(batchErr.Items, BatchItemError{ID: urlItem.ID, Err: err})
+		}
+	}
+
+	if len(batchErr.Items) > 0 {
+		return nil, batchErr
+	}
+
 	userID, ok := ctx.Value(middleware.UserIDContextKey).(string)
 
 	var userIDValue interface{}
@@ -100,24 +396,447 @@ func (s *Shortener) CreateListShortURL(ctx context.Context, urls []*entity.URLIt
 		userIDValue = userID
 	}
 
-	savedURLs, err := s.repo.CreateList(userIDValue, urls)
+	ip := clientIPFromContext(ctx)
+	if err := s.checkQuota(userIDValue, ip, len(urls)); err != nil {
+		return nil, err
+	}
+
+	workspaceID := workspaceIDFromContext(ctx)
+	ipHash := hashIP(ip)
+
+	var savedURLs []*entity.URLItem
+	var err error
+
+	// Хранилище сохраняет пачку целиком или не сохраняет ничего, поэтому при коллизии
+	// ключи перегенерируются для всей пачки.
+	for attempt := 0; ; attempt++ {
+		for _, urlItem := range urls {
+			urlItem.WorkspaceID = workspaceID
+			urlItem.CreatorIPHash = ipHash
+			shortKey, err := s.keyGenerator.Generate(urlItem.OriginalURL, attempt)
+			if err != nil {
+				return nil, err
+			}
+			urlItem.ShortKey = shortKey
+		}
+
+		savedURLs, err = s.repo.CreateList(userIDValue, urls)
+		if !errors.Is(err, storage.ErrShortKeyTaken) {
+			break
+		}
+
+		if attempt+1 >= maxKeyAttempts {
+			slog.Error("Short key space exhausted", slog.Int("count", len(urls)), slog.Int("attempts", maxKeyAttempts))
+			return nil, ErrKeyspaceExhausted
+		}
+
+		slog.Warn("Short key collision in batch, regenerating", slog.Int("attempt", attempt+1))
+	}
 
 	if err != nil {
 		if errors.Is(err, storage.ErrURLAlreadyExist) {
-			return savedURLs, storage.ErrURLAlreadyExist
+			return s.toShortURLItems(savedURLs), storage.ErrURLAlreadyExist
 		}
 
 		return nil, err
 	}
 
 	slog.Info("Batch URL creation successful", slog.Int("count", len(savedURLs)))
-	return savedURLs, nil
+	return s.toShortURLItems(savedURLs), nil
 }
 
-// GetAllShortURL сохраняет список оригинальных URL в хранилище и возвращает список сокращённых ссылок.
-// В случае ошибки возвращает список частично созданных ссылок и ошибку.
-func (s *Shortener) GetAllShortURL(userID string) ([]*entity.URLItem, error) {
-	return s.repo.GetAll(userID)
+// prepareBatchItem проверяет и нормализует элемент пакетного запроса. seen содержит нормализованные URL
+// предыдущих элементов пачки: повторный URL возвращает ErrDuplicateInBatch.
+func (s *Shortener) prepareBatchItem(urlItem *entity.URLItem, seen map[string]struct{}, now time.Time) error {
+	originalURL, err := valueobject.NormalizeURL(urlItem.OriginalURL)
+	if err != nil {
+		return err
+	}
+	if _, duplicate := seen[originalURL]; duplicate {
+		return ErrDuplicateInBatch
+	}
+	seen[originalURL] = struct{}{}
+	urlItem.OriginalURL = originalURL
+
+	if err := s.checkBlocked(originalURL); err != nil {
+		return err
+	}
+
+	expiresAt, err := resolveExpiration(urlItem.ExpiresAt, time.Duration(urlItem.TTL)*time.Second, now)
+	if err != nil {
+		return err
+	}
+	urlItem.ExpiresAt = expiresAt
+
+	tags, err := valueobject.NormalizeTags(urlItem.Tags)
+	if err != nil {
+		return err
+	}
+	urlItem.Tags = tags
+
+	domain, err := s.domains.Resolve(urlItem.Domain)
+	if err != nil {
+		return err
+	}
+	urlItem.Domain = domain
+
+	if _, err := valueobject.NewRedirectType(urlItem.RedirectType); err != nil {
+		return err
+	}
+
+	utm, err := normalizeUTM(urlItem.UTM)
+	if err != nil {
+		return err
+	}
+	urlItem.UTM = utm
+
+	title, err := normalizeTitle(urlItem.Title)
+	if err != nil {
+		return err
+	}
+	urlItem.Title = title
+
+	return nil
+}
+
+// QuotaUsage возвращает квоты пользователя и IP-адреса ip на создание ссылок и их текущее использование.
+// Использование считается по сохранённым ссылкам, поэтому не сбрасывается при перезапуске и общее для всех экземпляров сервиса.
+// Если ограничения на количество ссылок не заданы, хранилище не запрашивается.
+func (s *Shortener) QuotaUsage(userID, ip string) (dto.QuotaUsage, error) {
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	quotaUsage := dto.QuotaUsage{
+		MaxActiveLinks:  s.quota.MaxActiveLinks,
+		MaxDailyLinks:   s.quota.MaxDailyLinks,
+		DailyResetAt:    dayStart.AddDate(0, 0, 1),
+		MaxBatchSize:    s.quota.MaxBatchSize,
+		MaxIPDailyLinks: s.quota.MaxIPDailyLinks,
+	}
+
+	var ipHash string
+	if s.quota.MaxIPDailyLinks > 0 {
+		ipHash = hashIP(ip)
+	}
+
+	if s.quota.MaxActiveLinks <= 0 && s.quota.MaxDailyLinks <= 0 {
+		userID = ""
+	}
+
+	if userID == "" && ipHash == "" {
+		return quotaUsage, nil
+	}
+
+	usage, err := s.repo.CountUsage(userID, ipHash, dayStart)
+	if err != nil {
+		return dto.QuotaUsage{}, err
+	}
+
+	quotaUsage.ActiveLinks = usage.Active
+	quotaUsage.DailyLinks = usage.Created
+	quotaUsage.IPDailyLinks = usage.IPCreated
+
+	return quotaUsage, nil
+}
+
+// checkQuota возвращает ErrQuotaExceeded, если создание count ссылок превысит квоту пользователя
+// на активные ссылки или на ссылки, созданные за сутки, либо суточную квоту IP-адреса ip.
+// Квота IP-адреса ограничивает анонимных пользователей, которые могут получать новый идентификатор
+// при каждом запросе. Квоты проверяются до обращения к хранилищу, поэтому одновременные запросы
+// могут незначительно превысить лимит. Для запросов без пользователя проверяется только квота IP-адреса.
+func (s *Shortener) checkQuota(userID interface{}, ip string, count int) error {
+	id, _ := userID.(string)
+
+	usage, err := s.QuotaUsage(id, ip)
+	if err != nil {
+		return err
+	}
+
+	if usage.MaxIPDailyLinks > 0 && ip != "" && usage.IPDailyLinks+count > usage.MaxIPDailyLinks {
+		slog.Warn("IP daily links quota exceeded", slog.String("ip", ip), slog.Int("created", usage.IPDailyLinks), slog.Int("requested", count))
+		return fmt.Errorf("%w: at most %d links can be created per day from one IP address", ErrQuotaExceeded, usage.MaxIPDailyLinks)
+	}
+
+	if id == "" {
+		return nil
+	}
+
+	if usage.MaxActiveLinks > 0 && usage.ActiveLinks+count > usage.MaxActiveLinks {
+		slog.Warn("Active links quota exceeded", slog.String("userID", id), slog.Int("active", usage.ActiveLinks), slog.Int("requested", count))
+		return fmt.Errorf("%w: at most %d active links are allowed", ErrQuotaExceeded, usage.MaxActiveLinks)
+	}
+
+	if usage.MaxDailyLinks > 0 && usage.DailyLinks+count > usage.MaxDailyLinks {
+		slog.Warn("Daily links quota exceeded", slog.String("userID", id), slog.Int("created", usage.DailyLinks), slog.Int("requested", count))
+		return fmt.Errorf("%w: at most %d links can be created per day", ErrQuotaExceeded, usage.MaxDailyLinks)
+	}
+
+	return nil
+}
+
+// workspaceIDFromContext возвращает рабочее пространство, в котором создаётся ссылка, или пустую строку для личных ссылок.
+func workspaceIDFromContext(ctx context.Context) string {
+	workspaceID, _ := ctx.Value(middleware.WorkspaceIDContextKey).(string)
+	return workspaceID
+}
+
+// hashIP возвращает хеш IP-адреса клиента, который сохраняется вместо самого адреса,
+// или пустую строку, если адрес неизвестен.
+func hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIPFromContext возвращает IP-адрес клиента, создающего ссылку, или пустую строку, если он неизвестен.
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(middleware.ClientIPContextKey).(string)
+	return ip
+}
+
+// toShortURLItems формирует ответ на пакетное создание: идентификатор корреляции и полный короткий URL.
+func (s *Shortener) toShortURLItems(urls []*entity.URLItem) []*entity.URLItem {
+	items := make([]*entity.URLItem, 0, len(urls))
+	for _, url := range urls {
+		items = append(items, &entity.URLItem{ID: url.ID, ShortURL: s.domains.ShortURL(url.Domain, url.ShortKey)})
+	}
+	return items
+}
+
+// GetAllShortURL возвращает страницу ссылок владельца — пользователя или рабочего пространства — и курсор следующей страницы.
+// Если размер страницы не задан или превышает допустимый, используется ограничение по умолчанию.
+func (s *Shortener) GetAllShortURL(owner entity.Owner, opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	urls, nextCursor, err := s.repo.GetAll(owner, normalizeListOptions(opts))
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, url := range urls {
+		url.ShortURL = s.domains.ShortURL(url.Domain, url.ShortKey)
+	}
+
+	return urls, nextCursor, nil
+}
+
+// FindAllShortURL возвращает страницу ссылок всех пользователей с их владельцами и курсор следующей страницы.
+// Используется администраторами для поиска ссылок, в том числе по адресу назначения.
+func (s *Shortener) FindAllShortURL(opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	urls, nextCursor, err := s.repo.FindAll(normalizeListOptions(opts))
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, url := range urls {
+		url.ShortURL = s.domains.ShortURL(url.Domain, url.ShortKey)
+	}
+
+	return urls, nextCursor, nil
+}
+
+// ForceDeleteShortURL удаляет ссылки на домене по коротким ключам независимо от владельца и возвращает их количество.
+func (s *Shortener) ForceDeleteShortURL(domain string, shortKeys []string) (int64, error) {
+	count, err := s.repo.ForceMarkAsDeleted(domain, shortKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("URLs force deleted", slog.Int64("count", count))
+	return count, nil
+}
+
+// ForceRestoreShortURL восстанавливает ссылки на домене по коротким ключам независимо от владельца и возвращает их количество.
+func (s *Shortener) ForceRestoreShortURL(domain string, shortKeys []string) (int64, error) {
+	count, err := s.repo.ForceRestore(domain, shortKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("URLs force restored", slog.Int64("count", count))
+	return count, nil
+}
+
+// normalizeListOptions ограничивает размер страницы и задаёт сортировку по умолчанию.
+func normalizeListOptions(opts entity.ListOptions) entity.ListOptions {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultListLimit
+	} else if opts.Limit > maxListLimit {
+		opts.Limit = maxListLimit
+	}
+
+	if opts.SortBy == "" {
+		opts.SortBy = entity.SortByCreatedAt
+	}
+
+	return opts
+}
+
+// RestoreShortURL восстанавливает удалённые ссылки владельца на домене по коротким ключам и возвращает их количество.
+// Ссылки с истёкшим сроком жизни не восстанавливаются: их снова удалила бы фоновая задача.
+// Если не восстановлено ни одной ссылки, возвращает ErrNothingRestored.
+func (s *Shortener) RestoreShortURL(domain string, shortKeys []string, owner entity.Owner) (int64, error) {
+	count, err := s.repo.Restore(domain, shortKeys, owner)
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 0 {
+		return 0, ErrNothingRestored
+	}
+
+	slog.Info("URLs restored", slog.Int64("count", count), slog.String("owner", owner.String()))
+	return count, nil
+}
+
+// ClaimLinks передаёт ссылки анонимного пользователя anonymousID зарегистрированному пользователю userID
+// и возвращает количество переданных ссылок.
+func (s *Shortener) ClaimLinks(anonymousID, userID string) (int64, error) {
+	if anonymousID == "" || anonymousID == userID {
+		return 0, nil
+	}
+
+	count, err := s.repo.TransferOwnership(anonymousID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		slog.Info("Anonymous URLs claimed", slog.Int64("count", count), slog.String("userID", userID))
+	}
+	return count, nil
+}
+
+// UpdateShortURL меняет адрес назначения и параметры редиректа существующей ссылки владельца на домене,
+// сохраняя короткий ключ.
+func (s *Shortener) UpdateShortURL(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URLItem, error) {
+	if update.IsEmpty() {
+		return nil, ErrNothingToUpdate
+	}
+
+	if update.OriginalURL != "" {
+		originalURL, err := valueobject.NormalizeURL(update.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkBlocked(originalURL); err != nil {
+			return nil, err
+		}
+		update.OriginalURL = originalURL
+	}
+
+	if update.RedirectType != nil {
+		if _, err := valueobject.NewRedirectType(*update.RedirectType); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.UTM != nil {
+		utm, err := normalizeUTM(update.UTM)
+		if err != nil {
+			return nil, err
+		}
+		if utm == nil {
+			utm = &entity.UTM{} // Пустой набор удаляет UTM-метки ссылки
+		}
+		update.UTM = utm
+	}
+
+	if update.Title != nil {
+		title, err := normalizeTitle(*update.Title)
+		if err != nil {
+			return nil, err
+		}
+		update.Title = &title
+	}
+
+	url, err := s.repo.Update(domain, shortKey, owner, update)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("URL updated", slog.String("shortKey", shortKey), slog.String("originalURL", url.OriginalURL), slog.Int("redirectType", url.RedirectType))
+	return &entity.URLItem{
+		ShortURL:     s.domains.ShortURL(url.Domain, url.ShortKey),
+		ShortKey:     url.ShortKey,
+		Domain:       url.Domain,
+		OriginalURL:  url.OriginalURL,
+		ExpiresAt:    url.ExpiresAt,
+		Tags:         url.Tags,
+		RedirectType: url.RedirectType,
+		PassQuery:    url.PassQuery,
+		UTM:          url.UTM,
+		Title:        url.Title,
+	}, nil
+}
+
+// AddTags нормализует и добавляет теги к ссылке владельца на домене, возвращая итоговый набор тегов.
+func (s *Shortener) AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	normalized, err := normalizeTagsArg(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.AddTags(domain, shortKey, owner, normalized)
+}
+
+// RemoveTags нормализует и удаляет теги у ссылки владельца на домене, возвращая итоговый набор тегов.
+func (s *Shortener) RemoveTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	normalized, err := normalizeTagsArg(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.RemoveTags(domain, shortKey, owner, normalized)
+}
+
+// normalizeTitle удаляет пробелы по краям заголовка ссылки и проверяет его длину.
+func normalizeTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return "", ErrTitleTooLong
+	}
+	return title, nil
+}
+
+// normalizeUTM удаляет пробелы по краям UTM-меток и проверяет их длину.
+// Возвращает nil, если ни одна метка не задана.
+func normalizeUTM(utm *entity.UTM) (*entity.UTM, error) {
+	if utm.IsEmpty() {
+		return nil, nil
+	}
+
+	normalized := &entity.UTM{
+		Source:   strings.TrimSpace(utm.Source),
+		Medium:   strings.TrimSpace(utm.Medium),
+		Campaign: strings.TrimSpace(utm.Campaign),
+	}
+
+	for _, value := range []string{normalized.Source, normalized.Medium, normalized.Campaign} {
+		if len(value) > maxUTMLength {
+			return nil, ErrUTMTooLong
+		}
+	}
+
+	if normalized.IsEmpty() {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// normalizeTagsArg нормализует теги запроса на изменение; пустой список тегов считается ошибкой.
+func normalizeTagsArg(tags []string) ([]string, error) {
+	normalized, err := valueobject.NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(normalized) == 0 {
+		return nil, valueobject.ErrTagInvalid
+	}
+
+	return normalized, nil
 }
 
 func (s *Shortener) StartDeletionWorkers(deleteChannel chan dto.DeleteTask, numWorkers int) {
@@ -125,8 +844,8 @@ func (s *Shortener) StartDeletionWorkers(deleteChannel chan dto.DeleteTask, numW
 		go func(workerID int) {
 			slog.Info("Worker started", slog.Int("workerID", workerID))
 			for task := range deleteChannel {
-				slog.Info("Worker processing task", slog.Int("workerID", workerID), slog.Int("batchSize", len(task.ShortKeys)), slog.String("userID", task.UserID))
-				if err := s.repo.MarkAsDeleted(task.ShortKeys, task.UserID); err != nil {
+				slog.Info("Worker processing task", slog.Int("workerID", workerID), slog.Int("batchSize", len(task.ShortKeys)), slog.String("owner", task.Owner.String()))
+				if err := s.repo.MarkAsDeleted(task.Domain, task.ShortKeys, task.Owner); err != nil {
 					slog.Error("Failed to mark batch as deleted", slog.Int("workerID", workerID), slog.String("error", err.Error()))
 				}
 			}
@@ -135,6 +854,118 @@ func (s *Shortener) StartDeletionWorkers(deleteChannel chan dto.DeleteTask, numW
 	}
 }
 
+// RecordClick ставит событие перехода по ссылке в очередь на сохранение и не блокирует вызывающего.
+// Если очередь переполнена, событие отбрасывается.
+func (s *Shortener) RecordClick(domain, shortKey, referrer, userAgent, remoteAddr string) {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+
+	select {
+	case s.clicks <- entity.NewClick(domain, shortKey, referrer, userAgent, ip, time.Now()):
+	default:
+		slog.Warn("Click buffer is full, dropping click", slog.String("shortKey", shortKey))
+	}
+}
+
+// GetClickStats возвращает статистику переходов по ссылке владельца на домене.
+func (s *Shortener) GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error) {
+	return s.repo.GetClickStats(domain, shortKey, owner)
+}
+
+// StartClickRecorder запускает фоновую запись событий перехода в репозиторий.
+// События сохраняются пачками по batchSize штук или раз в flushInterval, если пачка не набралась.
+// При закрытии приложения оставшиеся в очереди события сохраняются до закрытия хранилища.
+func (s *Shortener) StartClickRecorder(batchSize int, flushInterval time.Duration) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]*entity.Click, 0, batchSize)
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			if err := s.repo.SaveClicks(batch); err != nil {
+				slog.Error("Failed to save clicks", slog.Int("count", len(batch)), slog.String("error", err.Error()))
+			}
+			batch = make([]*entity.Click, 0, batchSize)
+		}
+
+		for {
+			select {
+			case click := <-s.clicks:
+				batch = append(batch, click)
+				if len(batch) >= batchSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			case <-stop:
+				for {
+					select {
+					case click := <-s.clicks:
+						batch = append(batch, click)
+					default:
+						flush()
+						slog.Info("Click recorder stopped")
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	closer.CL.Add(func(ctx context.Context) error {
+		close(stop)
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// StartExpirationReaper запускает фоновую задачу, которая с заданным интервалом помечает
+// ссылки с истёкшим сроком жизни как удалённые. Задача останавливается при закрытии приложения.
+func (s *Shortener) StartExpirationReaper(interval time.Duration) {
+	closer.RunPeriodically("expiration reaper", interval, func() error {
+		count, err := s.repo.MarkExpiredAsDeleted(time.Now())
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			slog.Info("Expired URLs marked as deleted", slog.Int64("count", count))
+		}
+		return nil
+	})
+}
+
+// StartPurger запускает фоновую задачу, которая с заданным интервалом физически удаляет
+// ссылки, находящиеся в удалённых дольше retention.
+func (s *Shortener) StartPurger(retention, interval time.Duration) {
+	closer.RunPeriodically("purger", interval, func() error {
+		count, err := s.repo.Purge(time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			slog.Info("Deleted URLs purged", slog.Int64("count", count))
+		}
+		return nil
+	})
+}
+
 // CheckHealth проверяет состояние репозитория, с которым работает сервис.
 func (s *Shortener) CheckHealth() error {
 	return s.repo.CheckHealth()
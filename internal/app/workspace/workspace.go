@@ -0,0 +1,200 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+// maxNameLength ограничивает длину названия рабочего пространства в символах.
+const maxNameLength = 100
+
+var (
+	ErrNameEmpty         = errors.New("workspace name cannot be empty")
+	ErrNameTooLong       = fmt.Errorf("workspace name must be at most %d characters long", maxNameLength)
+	ErrRoleInvalid       = fmt.Errorf("role must be one of %s, %s, %s", entity.RoleViewer, entity.RoleEditor, entity.RoleOwner)
+	ErrAccountRequired   = errors.New("workspaces are available only to registered users")
+	ErrOwnerRoleRequired = errors.New("only workspace owners can manage other members")
+	ErrUserNotRegistered = errors.New("no registered user with this email")
+)
+
+// Repository определяет интерфейс хранилища рабочих пространств и их участников.
+type Repository interface {
+	// CreateWorkspace сохраняет новое рабочее пространство вместе с его владельцем.
+	CreateWorkspace(workspace *entity.Workspace, owner *entity.Member) error
+	// ListWorkspaces возвращает рабочие пространства, участником которых является пользователь, с его ролью в каждом.
+	ListWorkspaces(userID string) ([]*entity.Membership, error)
+	// GetMember возвращает участника рабочего пространства.
+	GetMember(workspaceID, userID string) (*entity.Member, error)
+	// ListMembers возвращает участников рабочего пространства в порядке добавления.
+	ListMembers(workspaceID string) ([]*entity.Member, error)
+	// AddMember добавляет участника; пользователь не должен уже быть участником.
+	AddMember(member *entity.Member) error
+	// UpdateMember сохраняет роль участника. Возвращает storage.ErrLastOwner, если роль понижается
+	// у последнего владельца; проверка и изменение выполняются атомарно.
+	UpdateMember(member *entity.Member) error
+	// RemoveMember удаляет участника из рабочего пространства. Возвращает storage.ErrLastOwner,
+	// если исключается последний владелец; проверка и удаление выполняются атомарно.
+	RemoveMember(workspaceID, userID string) error
+}
+
+// Accounts определяет поиск зарегистрированных пользователей, которых добавляют в рабочие пространства.
+type Accounts interface {
+	// AccountEmail возвращает email зарегистрированного пользователя или storage.ErrUserNotFound.
+	AccountEmail(userID string) (string, error)
+	// AccountID возвращает идентификатор зарегистрированного пользователя с email или storage.ErrUserNotFound.
+	AccountID(email string) (string, error)
+}
+
+// Workspaces управляет рабочими пространствами и составом их участников.
+// Права участника на ссылки пространства определяются его ролью и проверяются до обращения к ссылкам.
+type Workspaces struct {
+	repo     Repository
+	accounts Accounts
+}
+
+// New создает новый сервис рабочих пространств.
+func New(repository Repository, accounts Accounts) *Workspaces {
+	return &Workspaces{repo: repository, accounts: accounts}
+}
+
+// Create создает рабочее пространство, владельцем которого становится пользователь userID.
+// Анонимные пользователи не могут создавать рабочие пространства.
+func (w *Workspaces) Create(userID, name string) (*entity.Membership, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrNameEmpty
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return nil, ErrNameTooLong
+	}
+
+	email, err := w.accounts.AccountEmail(userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, ErrAccountRequired
+		}
+		return nil, err
+	}
+
+	workspace := entity.NewWorkspace(name)
+	owner := entity.NewMember(workspace.ID, userID, email, entity.RoleOwner)
+
+	if err := w.repo.CreateWorkspace(workspace, owner); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Workspace created", slog.String("workspaceID", workspace.ID.String()), slog.String("userID", userID))
+	return &entity.Membership{Workspace: *workspace, Role: owner.Role}, nil
+}
+
+// List возвращает рабочие пространства пользователя с его ролью в каждом.
+func (w *Workspaces) List(userID string) ([]*entity.Membership, error) {
+	return w.repo.ListWorkspaces(userID)
+}
+
+// MemberRole возвращает роль пользователя в рабочем пространстве или пустую строку, если он не участник.
+func (w *Workspaces) MemberRole(workspaceID, userID string) (string, error) {
+	if _, err := uuid.Parse(workspaceID); err != nil {
+		return "", nil
+	}
+
+	member, err := w.repo.GetMember(workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrMemberNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// Members возвращает участников рабочего пространства.
+func (w *Workspaces) Members(workspaceID string) ([]*entity.Member, error) {
+	return w.repo.ListMembers(workspaceID)
+}
+
+// AddMember добавляет в рабочее пространство зарегистрированного пользователя с email и ролью role.
+func (w *Workspaces) AddMember(workspaceID, email, role string) (*entity.Member, error) {
+	if !entity.IsValidRole(role) {
+		return nil, ErrRoleInvalid
+	}
+
+	id, err := uuid.Parse(workspaceID)
+	if err != nil {
+		return nil, storage.ErrWorkspaceNotFound
+	}
+
+	userID, err := w.accounts.AccountID(email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, ErrUserNotRegistered
+		}
+		return nil, err
+	}
+
+	// Email сохраняется в том виде, в котором он записан в учётной записи.
+	email, err = w.accounts.AccountEmail(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	member := entity.NewMember(id, userID, email, role)
+	if err := w.repo.AddMember(member); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Workspace member added", slog.String("workspaceID", workspaceID), slog.String("userID", member.UserID), slog.String("role", role))
+	return member, nil
+}
+
+// ChangeRole меняет роль участника рабочего пространства. Последнего владельца понизить нельзя.
+func (w *Workspaces) ChangeRole(workspaceID, userID, role string) (*entity.Member, error) {
+	if !entity.IsValidRole(role) {
+		return nil, ErrRoleInvalid
+	}
+
+	member, err := w.repo.GetMember(workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	member.Role = role
+	if err := w.repo.UpdateMember(member); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Workspace member role changed", slog.String("workspaceID", workspaceID), slog.String("userID", userID), slog.String("role", role))
+	return member, nil
+}
+
+// RemoveMember исключает пользователя userID из рабочего пространства по запросу участника actorID.
+// Владелец может исключить любого участника, остальные — только покинуть пространство сами.
+// Последний владелец покинуть пространство не может: иначе его ссылками стало бы некому управлять.
+// Ссылки остаются в пространстве, но исключённый пользователь теряет к ним доступ.
+func (w *Workspaces) RemoveMember(workspaceID, actorID, userID string) error {
+	if actorID != userID {
+		actor, err := w.repo.GetMember(workspaceID, actorID)
+		if err != nil {
+			return err
+		}
+		if !actor.IsOwner() {
+			return ErrOwnerRoleRequired
+		}
+	}
+
+	if err := w.repo.RemoveMember(workspaceID, userID); err != nil {
+		return err
+	}
+
+	slog.Info("Workspace member removed", slog.String("workspaceID", workspaceID), slog.String("userID", userID), slog.String("removedBy", actorID))
+	return nil
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Области доступа API-ключей.
+const (
+	ScopeLinksRead   = "links:read"
+	ScopeLinksWrite  = "links:write"
+	ScopeLinksDelete = "links:delete"
+)
+
+// Scopes перечисляет все допустимые области доступа API-ключей.
+var Scopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeLinksDelete}
+
+// APIKey — ключ доступа к API для машинных клиентов. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name,omitempty"`
+	Prefix    string     `json:"prefix"` // Начало ключа, по которому владелец узнаёт его в списке
+	Hash      string     `json:"hash"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func NewAPIKey(userID, name, prefix, hash string, scopes []string) *APIKey {
+	id := uuid.New()
+
+	return &APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// IsRevoked сообщает, отозван ли ключ.
+func (k APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
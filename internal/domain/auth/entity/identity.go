@@ -0,0 +1,10 @@
+package entity
+
+// Identity описывает пользователя, подтверждённого внешним провайдером OpenID Connect.
+type Identity struct {
+	Issuer        string // Идентификатор издателя провайдера
+	Subject       string // Постоянный идентификатор пользователя у провайдера
+	Email         string
+	EmailVerified bool // Провайдер подтвердил, что email принадлежит пользователю
+	Name          string
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session — сессия пользователя, продлеваемая refresh-токеном.
+// Хранится только хеш текущего refresh-токена; при каждом обновлении токен заменяется новым.
+type Session struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"token_hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func NewSession(userID, tokenHash string, expiresAt time.Time) *Session {
+	id := uuid.New()
+
+	return &Session{
+		ID:        id,
+		UserID:    userID,
+		TokenHash: tokenHash,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+}
+
+// IsActive сообщает, что сессия не отозвана и не истекла на момент now.
+func (s Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
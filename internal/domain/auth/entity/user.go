@@ -1,21 +1,51 @@
 package entity
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Роли пользователей. Роль передаётся в токене доступа.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type User struct {
-	ID       uuid.UUID `json:"id,omitempty"`
-	Email    string    `json:"email"`
-	Password string    `json:"password"`
-	Username string    `json:"username,omitempty"`
+	ID           uuid.UUID  `json:"id,omitempty"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"password_hash"` // Хеш пароля bcrypt, сам пароль не хранится; пуст при входе только через OpenID Connect
+	Username     string     `json:"username,omitempty"`
+	Role         string     `json:"role,omitempty"` // Пустая роль у записей, созданных до появления ролей, означает RoleUser
+	CreatedAt    time.Time  `json:"created_at"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`  // Момент отключения учётной записи администратором
+	OIDCIssuer   string     `json:"oidc_issuer,omitempty"`  // Провайдер OpenID Connect, через который входит пользователь
+	OIDCSubject  string     `json:"oidc_subject,omitempty"` // Идентификатор пользователя у провайдера
 }
 
-func NewUser(username, email, password string) *User {
+func NewUser(username, email, passwordHash string) *User {
 	id := uuid.New()
 
 	return &User{
-		ID:       id,
-		Username: username,
-		Email:    email,
-		Password: password,
+		ID:           id,
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		Role:         RoleUser,
+		CreatedAt:    time.Now().UTC(),
 	}
 }
+
+// UserRole возвращает роль пользователя.
+func (u User) UserRole() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+// IsDisabled сообщает, отключена ли учётная запись.
+func (u User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"net"
+	"time"
+)
+
+// Click описывает одно обращение к короткой ссылке.
+type Click struct {
+	Domain    string    `json:"domain,omitempty"`
+	ShortKey  string    `json:"short_key"`
+	Timestamp time.Time `json:"timestamp"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+}
+
+// DailyClicks содержит количество переходов по ссылке за один день (UTC).
+type DailyClicks struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// ClickStats содержит агрегированную статистику переходов по короткой ссылке.
+type ClickStats struct {
+	ShortKey string        `json:"short_key"`
+	Total    int64         `json:"total"`
+	Daily    []DailyClicks `json:"daily"`
+}
+
+// NewClick создает событие перехода, анонимизируя IP-адрес клиента.
+func NewClick(domain, shortKey, referrer, userAgent, ip string, timestamp time.Time) *Click {
+	return &Click{
+		Domain:    domain,
+		ShortKey:  shortKey,
+		Timestamp: timestamp.UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IP:        anonymizeIP(ip),
+	}
+}
+
+// anonymizeIP обнуляет последний октет IPv4-адреса и последние 80 бит IPv6-адреса.
+// Для строк, не являющихся IP-адресом, возвращает пустую строку.
+func anonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
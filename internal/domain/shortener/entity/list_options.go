@@ -0,0 +1,17 @@
+package entity
+
+const (
+	SortByCreatedAt   = "created_at"
+	SortByOriginalURL = "original_url"
+)
+
+// ListOptions задаёт параметры выборки ссылок пользователя: фильтрацию, сортировку и постраничный вывод.
+type ListOptions struct {
+	Limit    int    // Максимальное количество ссылок на странице, 0 — без ограничения
+	Cursor   string // Непрозрачный курсор, полученный вместе с предыдущей страницей
+	SortBy   string // Поле сортировки: SortByCreatedAt или SortByOriginalURL
+	Desc     bool   // Сортировка по убыванию
+	Contains string // Подстрока, которую должен содержать оригинальный URL
+	Tag      string // Тег, которым должна быть отмечена ссылка
+	Deleted  bool   // Выбирать удалённые ссылки вместо активных
+}
@@ -0,0 +1,27 @@
+package entity
+
+// Owner определяет, от чьего имени выбираются и изменяются ссылки: личные ссылки пользователя
+// или ссылки рабочего пространства. Членство пользователя в рабочем пространстве проверяется
+// до обращения к хранилищу.
+type Owner struct {
+	UserID      string
+	WorkspaceID string // Непустое значение означает ссылки рабочего пространства независимо от их создателя
+}
+
+// Owns сообщает, принадлежит ли ссылка владельцу. Ссылки рабочего пространства не считаются
+// личными ссылками создавшего их пользователя, поэтому после исключения из пространства
+// он теряет к ним доступ.
+func (o Owner) Owns(url URL) bool {
+	if o.WorkspaceID != "" {
+		return url.WorkspaceID == o.WorkspaceID
+	}
+	return url.WorkspaceID == "" && url.UserID == o.UserID
+}
+
+// String возвращает владельца для сообщений об ошибках и журнала.
+func (o Owner) String() string {
+	if o.WorkspaceID != "" {
+		return "workspace " + o.WorkspaceID
+	}
+	return o.UserID
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTM содержит UTM-метки по умолчанию, которые добавляются к адресу назначения при редиректе.
+type UTM struct {
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+}
+
+// IsEmpty сообщает, что ни одна UTM-метка не задана.
+func (u *UTM) IsEmpty() bool {
+	return u == nil || (u.Source == "" && u.Medium == "" && u.Campaign == "")
+}
+
+// values возвращает заданные UTM-метки в виде параметров запроса.
+func (u *UTM) values() url.Values {
+	values := url.Values{}
+	if u == nil {
+		return values
+	}
+
+	for name, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	return values
+}
+
+// Destination возвращает адрес, на который нужно перенаправить запрос с параметрами incoming.
+// Параметры, уже заданные в OriginalURL, никогда не перезаписываются. Если у ссылки включена
+// передача параметров, к адресу добавляются параметры запроса, затем — недостающие UTM-метки по умолчанию.
+func (u URL) Destination(incoming url.Values) string {
+	if !u.PassQuery && u.UTM.IsEmpty() {
+		return u.OriginalURL
+	}
+
+	destination, err := url.Parse(u.OriginalURL)
+	if err != nil {
+		return u.OriginalURL
+	}
+
+	present := destination.Query()
+	extra := url.Values{}
+
+	if u.PassQuery {
+		for name, values := range incoming {
+			if _, ok := present[name]; !ok {
+				extra[name] = values
+			}
+		}
+	}
+
+	for name, values := range u.UTM.values() {
+		_, inOriginal := present[name]
+		_, inExtra := extra[name]
+		if !inOriginal && !inExtra {
+			extra[name] = values
+		}
+	}
+
+	if len(extra) == 0 {
+		return u.OriginalURL
+	}
+
+	// Исходная строка запроса сохраняется как есть, новые параметры дописываются в конец.
+	destination.RawQuery = strings.TrimPrefix(destination.RawQuery+"&"+extra.Encode(), "&")
+	return destination.String()
+}
@@ -1,23 +1,62 @@
 package entity
 
 import (
+	"slices"
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type URLItem struct {
-	ID          string `json:"correlation_id,omitempty"`
-	UserID      string `json:"user_id,omitempty"`
-	ShortURL    string `json:"short_url"`
-	ShortKey    string `json:"short_key,omitempty"`
-	OriginalURL string `json:"original_url,omitempty"`
+	ID            string     `json:"correlation_id,omitempty"`
+	UserID        string     `json:"user_id,omitempty"`
+	WorkspaceID   string     `json:"workspace_id,omitempty"`
+	ShortURL      string     `json:"short_url"`
+	ShortKey      string     `json:"short_key,omitempty"`
+	Domain        string     `json:"domain,omitempty"` // Пустая строка означает домен по умолчанию
+	OriginalURL   string     `json:"original_url,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"` // Время жизни ссылки в секундах, используется только при создании
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	RedirectType  int        `json:"redirect_type,omitempty"` // Код статуса редиректа, 0 — значение по умолчанию
+	PassQuery     bool       `json:"pass_query,omitempty"`    // Передавать параметры запроса в адрес назначения
+	UTM           *UTM       `json:"utm,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	CreatorIPHash string     `json:"-"` // Хеш IP-адреса, с которого создаётся ссылка
 }
 
 type URL struct {
-	ID          string      `json:"uuid"`
-	UserID      interface{} `json:"user_id"`
-	ShortKey    string      `json:"short_key"`
-	OriginalURL string      `json:"original_url"`
-	DeletedFlag bool        `json:"is_deleted"`
+	ID            string      `json:"uuid"`
+	UserID        interface{} `json:"user_id"`
+	WorkspaceID   string      `json:"workspace_id,omitempty"` // Рабочее пространство, которому принадлежит ссылка; пусто у личных ссылок
+	ShortKey      string      `json:"short_key"`
+	Domain        string      `json:"domain,omitempty"` // Пустая строка означает домен по умолчанию
+	OriginalURL   string      `json:"original_url"`
+	DeletedFlag   bool        `json:"is_deleted"`
+	DeletedAt     *time.Time  `json:"deleted_at,omitempty"`
+	ExpiresAt     *time.Time  `json:"expires_at,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Tags          []string    `json:"tags,omitempty"`
+	RedirectType  int         `json:"redirect_type,omitempty"` // Код статуса редиректа, 0 — значение по умолчанию
+	PassQuery     bool        `json:"pass_query,omitempty"`    // Передавать параметры запроса в адрес назначения
+	UTM           *UTM        `json:"utm,omitempty"`
+	Title         string      `json:"title,omitempty"`           // Заголовок, который владелец показывает на странице предпросмотра
+	CreatorIPHash string      `json:"creator_ip_hash,omitempty"` // Хеш IP-адреса, с которого создана ссылка, для квоты IP-адреса
+}
+
+// URLUpdate описывает изменения ссылки. Незаданные поля остаются без изменений.
+type URLUpdate struct {
+	OriginalURL  string // Пустая строка означает, что адрес назначения не меняется
+	RedirectType *int
+	PassQuery    *bool
+	UTM          *UTM // Заменяет UTM-метки целиком; пустой набор удаляет их
+	Title        *string
+}
+
+// IsEmpty сообщает, что изменение не затрагивает ни одного поля.
+func (u URLUpdate) IsEmpty() bool {
+	return u.OriginalURL == "" && u.RedirectType == nil && u.PassQuery == nil && u.UTM == nil && u.Title == nil
 }
 
 func NewURL(userID interface{}, originalURL string, shortKey string) *URL {
@@ -28,5 +67,39 @@ func NewURL(userID interface{}, originalURL string, shortKey string) *URL {
 		UserID:      userID,
 		ShortKey:    shortKey,
 		OriginalURL: originalURL,
+		CreatedAt:   time.Now().UTC(),
 	}
 }
+
+// IsExpired сообщает, истёк ли срок жизни ссылки на момент now.
+// Ссылки без срока жизни не истекают никогда.
+func (u URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// IsActive сообщает, что ссылка не удалена и её срок жизни не истёк на момент now.
+func (u URL) IsActive(now time.Time) bool {
+	return !u.DeletedFlag && !u.IsExpired(now)
+}
+
+// MarkDeleted помечает ссылку как удалённую в момент now.
+func (u *URL) MarkDeleted(now time.Time) {
+	u.DeletedFlag = true
+	u.DeletedAt = &now
+}
+
+// Restore снимает с ссылки пометку об удалении.
+func (u *URL) Restore() {
+	u.DeletedFlag = false
+	u.DeletedAt = nil
+}
+
+// IsPurgeable сообщает, была ли ссылка удалена не позднее deletedBefore и может быть удалена физически.
+func (u URL) IsPurgeable(deletedBefore time.Time) bool {
+	return u.DeletedFlag && u.DeletedAt != nil && !u.DeletedAt.After(deletedBefore)
+}
+
+// HasTag сообщает, отмечена ли ссылка тегом tag.
+func (u URL) HasTag(tag string) bool {
+	return slices.Contains(u.Tags, tag)
+}
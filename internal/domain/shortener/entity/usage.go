@@ -0,0 +1,8 @@
+package entity
+
+// URLUsage описывает количество ссылок пользователя и IP-адреса, по которому проверяются квоты на создание ссылок.
+type URLUsage struct {
+	Active    int // Не удалённые ссылки с неистёкшим сроком жизни
+	Created   int // Ссылки, созданные начиная с заданного момента, в том числе уже удалённые
+	IPCreated int // Ссылки, созданные с IP-адреса начиная с заданного момента, в том числе уже удалённые
+}
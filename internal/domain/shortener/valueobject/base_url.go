@@ -44,6 +44,14 @@ func (bu BaseURL) ToString() string {
 	return fmt.Sprintf("%s://%s:%s", bu.scheme, bu.host, bu.port)
 }
 
+// Host возвращает хост с портом, если он задан, в нижнем регистре в формате "host:port".
+func (bu BaseURL) Host() string {
+	if bu.port == "" {
+		return strings.ToLower(bu.host)
+	}
+	return strings.ToLower(net.JoinHostPort(bu.host, bu.port))
+}
+
 // ParseBaseURL парсит переданную строку URL на компоненты схема, хост и порт.
 // Входная строка должна быть в формате "scheme://host:port" или "host:port".
 func ParseBaseURL(s string) (map[string]string, error) {
@@ -61,7 +69,8 @@ func ParseBaseURL(s string) (map[string]string, error) {
 
 	host, port, err := net.SplitHostPort(u.Host)
 
-	if err != nil && err.Error() == "missing port" {
+	var addrErr *net.AddrError
+	if errors.As(err, &addrErr) && strings.Contains(addrErr.Err, "missing port") {
 		host = u.Host
 		port = ""
 	} else if err != nil {
@@ -0,0 +1,122 @@
+package valueobject
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var ErrBlocklistRuleInvalid = errors.New("invalid blocklist rule")
+
+// blocklistRegexPrefix отмечает правило-регулярное выражение, которое проверяется по всему адресу назначения.
+const blocklistRegexPrefix = "re:"
+
+// Blocklist содержит правила запрета адресов назначения. Поддерживаются правила трёх видов:
+// точное имя хоста ("evil.com"), все поддомены ("*.evil.com", сам домен не включается)
+// и регулярное выражение по всему адресу ("re:^https?://[^/]*paypal[^/]*\.").
+// Нулевой указатель означает пустой список.
+type Blocklist struct {
+	hosts    map[string]string
+	suffixes []blocklistRule
+	patterns []blocklistPattern
+}
+
+type blocklistRule struct {
+	suffix string
+	rule   string
+}
+
+type blocklistPattern struct {
+	re   *regexp.Regexp
+	rule string
+}
+
+// ParseBlocklist читает правила из r, по одному на строку. Пустые строки и строки,
+// начинающиеся с "#", пропускаются. Возвращает ошибку с номером строки, если правило некорректно.
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	b := &Blocklist{hosts: make(map[string]string)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		rule := strings.TrimSpace(scanner.Text())
+		if rule == "" || strings.HasPrefix(rule, "#") {
+			continue
+		}
+
+		if err := b.add(rule); err != nil {
+			return nil, fmt.Errorf("%w at line %d: %q: %v", ErrBlocklistRuleInvalid, line, rule, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+
+	return b, nil
+}
+
+// add разбирает одно правило и добавляет его в список.
+func (b *Blocklist) add(rule string) error {
+	if pattern, ok := strings.CutPrefix(rule, blocklistRegexPrefix); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		b.patterns = append(b.patterns, blocklistPattern{re: re, rule: rule})
+		return nil
+	}
+
+	wildcard := strings.HasPrefix(rule, "*.")
+	host, err := normalizeHost(strings.TrimPrefix(rule, "*."))
+	if err != nil || host == "" || strings.ContainsAny(host, "/*") {
+		return errors.New("expected a host name, *.domain or re:pattern")
+	}
+
+	if wildcard {
+		b.suffixes = append(b.suffixes, blocklistRule{suffix: "." + host, rule: rule})
+	} else {
+		b.hosts[host] = rule
+	}
+	return nil
+}
+
+// Len возвращает количество правил в списке.
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.hosts) + len(b.suffixes) + len(b.patterns)
+}
+
+// Match сообщает, запрещён ли адрес назначения rawURL, и возвращает сработавшее правило.
+func (b *Blocklist) Match(rawURL string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+		if rule, ok := b.hosts[host]; ok {
+			return rule, true
+		}
+
+		for _, s := range b.suffixes {
+			if strings.HasSuffix(host, s.suffix) {
+				return s.rule, true
+			}
+		}
+	}
+
+	for _, p := range b.patterns {
+		if p.re.MatchString(rawURL) {
+			return p.rule, true
+		}
+	}
+
+	return "", false
+}
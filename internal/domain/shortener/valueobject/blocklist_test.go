@@ -0,0 +1,64 @@
+package valueobject
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestBlocklist проверяет разбор правил списка запрета и сопоставление адресов с ними.
+func TestBlocklist(t *testing.T) {
+	rules := `
+# точные хосты
+evil.com
+Пример.рф
+
+*.phish.io
+re:^https?://[^/]*paypal[^/]*\.(ru|xyz)/
+`
+
+	blocklist, err := ParseBlocklist(strings.NewReader(rules))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if blocklist.Len() != 4 {
+		t.Errorf("expected rules count: got %d, want 4", blocklist.Len())
+	}
+
+	tests := []struct {
+		url      string
+		wantRule string
+	}{
+		{url: "https://evil.com/login", wantRule: "evil.com"},
+		{url: "https://EVIL.com./", wantRule: "evil.com"},
+		{url: "https://sub.evil.com/", wantRule: ""},
+		{url: "https://xn--e1afmkfd.xn--p1ai/", wantRule: "Пример.рф"},
+		{url: "https://login.phish.io/", wantRule: "*.phish.io"},
+		{url: "https://a.b.phish.io/", wantRule: "*.phish.io"},
+		{url: "https://phish.io/", wantRule: ""},
+		{url: "http://secure-paypal-login.xyz/", wantRule: `re:^https?://[^/]*paypal[^/]*\.(ru|xyz)/`},
+		{url: "https://paypal.com/", wantRule: ""},
+		{url: "https://yandex.ru/", wantRule: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rule, blocked := blocklist.Match(tt.url)
+			if blocked != (tt.wantRule != "") || rule != tt.wantRule {
+				t.Errorf("unexpected match: got (%q, %v), want %q", rule, blocked, tt.wantRule)
+			}
+		})
+	}
+}
+
+// TestParseBlocklistInvalidRule проверяет, что некорректные правила отклоняются.
+func TestParseBlocklistInvalidRule(t *testing.T) {
+	for _, rule := range []string{"re:([a-z", "evil.com/path", "*.", "exa_mple.com"} {
+		t.Run(rule, func(t *testing.T) {
+			if _, err := ParseBlocklist(strings.NewReader(rule)); !errors.Is(err, ErrBlocklistRuleInvalid) {
+				t.Errorf("expected error: got %v, want %v", err, ErrBlocklistRuleInvalid)
+			}
+		})
+	}
+}
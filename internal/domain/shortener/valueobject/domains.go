@@ -0,0 +1,99 @@
+package valueobject
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var ErrUnknownDomain = errors.New("domain is not configured")
+
+// Domains содержит набор доменов, которые обслуживает сервис.
+// Домен по умолчанию задаётся базовым URL и хранится в ссылках как пустая строка,
+// дополнительные домены хранятся под своим именем хоста.
+type Domains struct {
+	defaultBase BaseURL
+	defaultHost string
+	bases       map[string]BaseURL
+}
+
+// NewDomains создает набор доменов из базового URL по умолчанию и списка дополнительных доменов.
+// Дополнительный домен задаётся как "host[:port]" или "scheme://host[:port]"; без схемы используется схема базового URL.
+func NewDomains(defaultBaseURL string, extra []string) (*Domains, error) {
+	defaultBase, err := NewBaseURL(defaultBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Domains{
+		defaultBase: defaultBase,
+		defaultHost: defaultBase.Host(),
+		bases:       make(map[string]BaseURL, len(extra)),
+	}
+
+	for _, raw := range extra {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		if !strings.Contains(raw, "://") {
+			raw = fmt.Sprintf("%s://%s", defaultBase.scheme, raw)
+		}
+
+		base, err := NewBaseURL(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		if host := base.Host(); host != d.defaultHost {
+			d.bases[host] = base
+		}
+	}
+
+	return d, nil
+}
+
+// Resolve возвращает имя домена для хранения в ссылке: пустую строку для домена по умолчанию
+// или имя хоста дополнительного домена. Возвращает ErrUnknownDomain, если домен не настроен.
+func (d *Domains) Resolve(name string) (string, error) {
+	host := strings.ToLower(strings.TrimSpace(name))
+
+	if host == "" || host == d.defaultHost || host == strings.ToLower(d.defaultBase.host) {
+		return "", nil
+	}
+
+	if _, ok := d.bases[host]; ok {
+		return host, nil
+	}
+
+	// Заголовок Host может содержать порт, не указанный в настройках домена.
+	if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+		return d.Resolve(withoutPort)
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownDomain, name)
+}
+
+// FromHost возвращает домен для значения заголовка Host. Неизвестные хосты относятся к домену по умолчанию.
+func (d *Domains) FromHost(host string) string {
+	domain, err := d.Resolve(host)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// BaseURL возвращает базовый URL домена. Для неизвестного домена возвращается базовый URL по умолчанию.
+func (d *Domains) BaseURL(domain string) BaseURL {
+	if base, ok := d.bases[domain]; ok {
+		return base
+	}
+	return d.defaultBase
+}
+
+// ShortURL возвращает короткую ссылку для ключа на домене в формате scheme://host:port/shortKey.
+func (d *Domains) ShortURL(domain, shortKey string) string {
+	return fmt.Sprintf("%s/%s", d.BaseURL(domain).ToString(), shortKey)
+}
@@ -0,0 +1,231 @@
+package valueobject
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Стратегии генерации коротких ключей.
+const (
+	KeyStrategyRandom   = "random"
+	KeyStrategySequence = "sequence"
+	KeyStrategyHash     = "hash"
+)
+
+const (
+	// base62Alphabet используется для кодирования значений счётчика и хеша.
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// DefaultKeyLength и DefaultKeyAlphabet соответствуют исходному формату ключей: 5 латинских букв.
+	DefaultKeyLength   = shortURLLength
+	DefaultKeyAlphabet = letterBytes
+)
+
+var (
+	ErrUnknownKeyStrategy = errors.New("unknown short key strategy")
+	ErrKeyLengthInvalid   = fmt.Errorf("short key length must be between 1 and %d", aliasMaxLength)
+	ErrKeyAlphabetInvalid = errors.New("short key alphabet must contain at least 2 unique latin letters, digits, '-' or '_'")
+	ErrSequenceRequired   = errors.New("sequence strategy requires a storage with a counter")
+)
+
+// KeyGenerator генерирует короткие ключи для новых ссылок.
+type KeyGenerator interface {
+	// Generate возвращает короткий ключ для оригинального URL. attempt — номер попытки, начиная с нуля:
+	// при коллизии ключа генерация повторяется со следующим номером, и детерминированные генераторы
+	// должны учитывать его, чтобы получить другой ключ.
+	Generate(originalURL string, attempt int) (string, error)
+}
+
+// Sequence выдаёт монотонно возрастающие значения счётчика, хранящегося в репозитории.
+type Sequence interface {
+	// NextSequence возвращает следующее значение счётчика.
+	NextSequence() (uint64, error)
+}
+
+// NewKeyGenerator создает генератор коротких ключей по названию стратегии.
+// Для стратегии sequence требуется счётчик seq, для остальных он игнорируется.
+func NewKeyGenerator(strategy string, length int, alphabet string, seq Sequence) (KeyGenerator, error) {
+	switch strategy {
+	case "", KeyStrategyRandom:
+		return NewRandomKeyGenerator(length, alphabet)
+	case KeyStrategySequence:
+		if seq == nil {
+			return nil, ErrSequenceRequired
+		}
+		return NewSequenceKeyGenerator(length, seq)
+	case KeyStrategyHash:
+		return NewHashKeyGenerator(length)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyStrategy, strategy)
+	}
+}
+
+// RandomKeyGenerator генерирует ключи заданной длины из символов алфавита
+// с помощью криптографически стойкого генератора случайных чисел.
+type RandomKeyGenerator struct {
+	length   int
+	alphabet string
+}
+
+// NewRandomKeyGenerator создает генератор случайных ключей. Возвращает ошибку, если длина или алфавит некорректны.
+func NewRandomKeyGenerator(length int, alphabet string) (*RandomKeyGenerator, error) {
+	if err := validateKeyLength(length); err != nil {
+		return nil, err
+	}
+
+	if err := validateKeyAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+
+	return &RandomKeyGenerator{length: length, alphabet: alphabet}, nil
+}
+
+// Generate возвращает случайный ключ, пропуская зарезервированные слова; оригинальный URL и номер попытки не используются.
+func (g *RandomKeyGenerator) Generate(string, int) (string, error) {
+	b := make([]byte, g.length)
+	limit := big.NewInt(int64(len(g.alphabet)))
+
+	for {
+		for i := range b {
+			n, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate short key: %w", err)
+			}
+			b[i] = g.alphabet[n.Int64()]
+		}
+
+		if key := string(b); !isReserved(key) {
+			return key, nil
+		}
+	}
+}
+
+// SequenceKeyGenerator кодирует значения счётчика в base62. Ключи короткие и уникальные, но предсказуемые.
+type SequenceKeyGenerator struct {
+	offset *big.Int
+	seq    Sequence
+}
+
+// NewSequenceKeyGenerator создает генератор последовательных ключей длиной не менее length символов.
+func NewSequenceKeyGenerator(length int, seq Sequence) (*SequenceKeyGenerator, error) {
+	if err := validateKeyLength(length); err != nil {
+		return nil, err
+	}
+
+	// Смещение 62^(length-1) гарантирует, что даже первые значения счётчика дают ключ нужной длины.
+	offset := new(big.Int).Exp(big.NewInt(int64(len(base62Alphabet))), big.NewInt(int64(length-1)), nil)
+
+	return &SequenceKeyGenerator{offset: offset, seq: seq}, nil
+}
+
+// Generate возвращает ключ для следующего значения счётчика, пропуская зарезервированные слова.
+func (g *SequenceKeyGenerator) Generate(string, int) (string, error) {
+	for {
+		n, err := g.seq.NextSequence()
+		if err != nil {
+			return "", fmt.Errorf("failed to get next sequence value: %w", err)
+		}
+
+		key := encodeBase62(new(big.Int).Add(new(big.Int).SetUint64(n), g.offset))
+		if !isReserved(key) {
+			return key, nil
+		}
+	}
+}
+
+// HashKeyGenerator выводит ключ из SHA-256 оригинального URL: один и тот же URL всегда получает один и тот же ключ.
+type HashKeyGenerator struct {
+	length  int
+	modulus *big.Int
+}
+
+// NewHashKeyGenerator создает генератор ключей на основе хеша длиной length символов.
+func NewHashKeyGenerator(length int) (*HashKeyGenerator, error) {
+	if err := validateKeyLength(length); err != nil {
+		return nil, err
+	}
+
+	modulus := new(big.Int).Exp(big.NewInt(int64(len(base62Alphabet))), big.NewInt(int64(length)), nil)
+
+	return &HashKeyGenerator{length: length, modulus: modulus}, nil
+}
+
+// Generate возвращает length младших base62-разрядов хеша оригинального URL. Старшие разряды не используются:
+// старший разряд 256-битного числа в base62 принимает лишь несколько значений, а младшие распределены равномерно.
+// При повторных попытках к URL добавляется номер попытки, чтобы разрешить коллизию усечённого хеша.
+// Если ключ совпадает с зарезервированным словом, хеш вычисляется повторно от предыдущего хеша.
+func (g *HashKeyGenerator) Generate(originalURL string, attempt int) (string, error) {
+	input := originalURL
+	if attempt > 0 {
+		input = fmt.Sprintf("%s#%d", originalURL, attempt)
+	}
+
+	sum := sha256.Sum256([]byte(input))
+	for {
+		key := encodeBase62(new(big.Int).Mod(new(big.Int).SetBytes(sum[:]), g.modulus))
+
+		// Дополняем ведущими нулями до длины ключа.
+		if len(key) < g.length {
+			key = strings.Repeat(base62Alphabet[0:1], g.length-len(key)) + key
+		}
+
+		if !isReserved(key) {
+			return key, nil
+		}
+
+		sum = sha256.Sum256(sum[:])
+	}
+}
+
+// encodeBase62 кодирует неотрицательное число в base62.
+func encodeBase62(n *big.Int) string {
+	if n.Sign() == 0 {
+		return base62Alphabet[0:1]
+	}
+
+	base := big.NewInt(int64(len(base62Alphabet)))
+	mod := new(big.Int)
+	n = new(big.Int).Set(n)
+
+	var b []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		b = append(b, base62Alphabet[mod.Int64()])
+	}
+
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
+
+// validateKeyLength проверяет, что длина ключа укладывается в ограничения на длину алиаса.
+func validateKeyLength(length int) error {
+	if length < 1 || length > aliasMaxLength {
+		return ErrKeyLengthInvalid
+	}
+	return nil
+}
+
+// validateKeyAlphabet проверяет, что алфавит содержит только допустимые в пути символы и не менее двух уникальных.
+func validateKeyAlphabet(alphabet string) error {
+	seen := make(map[rune]struct{}, len(alphabet))
+
+	for _, r := range alphabet {
+		if !strings.ContainsRune(aliasBytes, r) {
+			return ErrKeyAlphabetInvalid
+		}
+		seen[r] = struct{}{}
+	}
+
+	if len(seen) < 2 {
+		return ErrKeyAlphabetInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package valueobject
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// counter реализует Sequence поверх обычного счётчика.
+type counter uint64
+
+func (c *counter) NextSequence() (uint64, error) {
+	*c++
+	return uint64(*c), nil
+}
+
+// TestNewKeyGenerator проверяет выбор стратегии и формат генерируемых ключей.
+func TestNewKeyGenerator(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   string
+		length     int
+		alphabet   string
+		wantErr    error
+		wantLength int
+	}{
+		{name: "random_default", strategy: KeyStrategyRandom, length: DefaultKeyLength, alphabet: DefaultKeyAlphabet, wantLength: 5},
+		{name: "random_digits", strategy: KeyStrategyRandom, length: 8, alphabet: "0123456789", wantLength: 8},
+		{name: "sequence_min_length", strategy: KeyStrategySequence, length: 4, wantLength: 4},
+		{name: "hash", strategy: KeyStrategyHash, length: 7, wantLength: 7},
+		{name: "unknown_strategy", strategy: "uuid", length: 5, alphabet: DefaultKeyAlphabet, wantErr: ErrUnknownKeyStrategy},
+		{name: "invalid_length", strategy: KeyStrategyHash, length: 0, wantErr: ErrKeyLengthInvalid},
+		{name: "invalid_alphabet", strategy: KeyStrategyRandom, length: 5, alphabet: "a/b", wantErr: ErrKeyAlphabetInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := NewKeyGenerator(tt.strategy, tt.length, tt.alphabet, new(counter))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			key, err := generator.Generate("https://practicum.yandex.ru", 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(key) != tt.wantLength {
+				t.Errorf("expected key length: got %d (%q), want %d", len(key), key, tt.wantLength)
+			}
+
+			if tt.alphabet != "" && strings.Trim(key, tt.alphabet) != "" {
+				t.Errorf("key %q contains characters outside of alphabet %q", key, tt.alphabet)
+			}
+		})
+	}
+}
+
+// TestSequenceKeyGenerator проверяет, что ключи кодируют последовательные значения счётчика со смещением.
+func TestSequenceKeyGenerator(t *testing.T) {
+	generator, err := NewSequenceKeyGenerator(1, new(counter))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"2", "3", "4"}
+	for _, w := range want {
+		key, err := generator.Generate("", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if key != w {
+			t.Errorf("expected key: got %q, want %q", key, w)
+		}
+	}
+}
+
+// TestHashKeyGenerator проверяет детерминированность ключей на основе хеша.
+func TestHashKeyGenerator(t *testing.T) {
+	generator, err := NewHashKeyGenerator(6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, _ := generator.Generate("https://practicum.yandex.ru", 0)
+	second, _ := generator.Generate("https://practicum.yandex.ru", 0)
+	other, _ := generator.Generate("https://yandex.ru", 0)
+	retry, _ := generator.Generate("https://practicum.yandex.ru", 1)
+
+	if first != second {
+		t.Errorf("expected equal keys for the same URL: got %q and %q", first, second)
+	}
+
+	if first == other {
+		t.Errorf("expected different keys for different URLs: got %q for both", first)
+	}
+
+	if first == retry {
+		t.Errorf("expected a different key on retry: got %q for both", first)
+	}
+}
+
+// TestKeyGeneratorsSkipReserved проверяет, что случайные ключи и ключи на основе хеша не совпадают с зарезервированными словами.
+func TestKeyGeneratorsSkipReserved(t *testing.T) {
+	random, err := NewRandomKeyGenerator(3, "aip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hash, err := NewHashKeyGenerator(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 50000; i++ {
+		key, err := random.Generate("", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if isReserved(key) {
+			t.Fatalf("random generator returned reserved key %q", key)
+		}
+
+		key, err = hash.Generate(fmt.Sprintf("https://example.com/%d", i), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if isReserved(key) {
+			t.Fatalf("hash generator returned reserved key %q", key)
+		}
+	}
+}
+
+// TestHashKeyGeneratorLeadingCharacter проверяет, что первый символ ключа на основе хеша принимает все значения алфавита.
+func TestHashKeyGeneratorLeadingCharacter(t *testing.T) {
+	generator, err := NewHashKeyGenerator(6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := make(map[byte]struct{})
+	for i := 0; i < 5000; i++ {
+		key, err := generator.Generate(fmt.Sprintf("https://example.com/%d", i), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		seen[key[0]] = struct{}{}
+	}
+
+	if len(seen) != len(base62Alphabet) {
+		t.Errorf("expected every leading character of the alphabet: got %d of %d", len(seen), len(base62Alphabet))
+	}
+}
@@ -0,0 +1,95 @@
+package valueobject
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrURLInvalid       = errors.New("url is not a valid absolute URL")
+	ErrURLSchemeInvalid = errors.New("url scheme must be http or https")
+	ErrURLHostRequired  = errors.New("url must contain a host")
+	ErrURLHostInvalid   = errors.New("url host is not a valid domain name or IP address")
+)
+
+// defaultPorts содержит разрешённые схемы и их порты по умолчанию.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL проверяет оригинальный URL и приводит его к каноническому виду, по которому
+// ссылки сравниваются между собой: схема и хост в нижнем регистре, интернационализированный домен
+// в punycode, без порта по умолчанию, пустой путь заменяется на "/", пустой фрагмент удаляется.
+// Непустой фрагмент сохраняется, так как от него может зависеть содержимое страницы.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrURLInvalid, raw)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	defaultPort, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrURLSchemeInvalid, raw)
+	}
+
+	if u.Opaque != "" {
+		return "", fmt.Errorf("%w: %q", ErrURLInvalid, raw)
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if host == "" {
+		return "", fmt.Errorf("%w: %q", ErrURLHostRequired, raw)
+	}
+
+	host, err = normalizeHost(host)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrURLHostInvalid, raw)
+	}
+
+	if port == defaultPort {
+		port = ""
+	}
+
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]" // IPv6-адрес
+	} else {
+		u.Host = host
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	u.ForceQuery = false
+	if u.Fragment == "" {
+		u.RawFragment = ""
+	}
+
+	return u.String(), nil
+}
+
+// normalizeHost приводит хост к нижнему регистру и переводит интернационализированное имя в punycode.
+// IP-адреса возвращаются в каноническом виде.
+func normalizeHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(ascii), nil
+}
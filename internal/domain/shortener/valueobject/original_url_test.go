@@ -0,0 +1,45 @@
+package valueobject
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestNormalizeURL проверяет проверку и приведение оригинальных URL к каноническому виду.
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr error
+	}{
+		{name: "lowercase_scheme_and_host", raw: "HTTP://Example.COM/Path", want: "http://example.com/Path"},
+		{name: "empty_path", raw: "http://example.com", want: "http://example.com/"},
+		{name: "default_port_removed", raw: "https://example.com:443/a?b=1", want: "https://example.com/a?b=1"},
+		{name: "custom_port_kept", raw: "http://example.com:8080/", want: "http://example.com:8080/"},
+		{name: "idn_to_punycode", raw: "https://пример.рф/путь", want: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{name: "empty_fragment_removed", raw: "https://example.com/#", want: "https://example.com/"},
+		{name: "fragment_kept", raw: "https://example.com/#/route", want: "https://example.com/#/route"},
+		{name: "ipv6_host", raw: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "surrounding_spaces", raw: "  https://example.com/  ", want: "https://example.com/"},
+		{name: "no_scheme", raw: "hello", wantErr: ErrURLSchemeInvalid},
+		{name: "javascript_scheme", raw: "javascript:alert(1)", wantErr: ErrURLSchemeInvalid},
+		{name: "ftp_scheme", raw: "ftp://example.com/file", wantErr: ErrURLSchemeInvalid},
+		{name: "no_host", raw: "http:///path", wantErr: ErrURLHostRequired},
+		{name: "opaque", raw: "http:example.com", wantErr: ErrURLInvalid},
+		{name: "invalid_host", raw: "http://exa_mple.com/", wantErr: ErrURLHostInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeURL(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error: got %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected URL: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
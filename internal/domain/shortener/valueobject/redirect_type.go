@@ -0,0 +1,50 @@
+package valueobject
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultRedirectType задаёт тип редиректа по умолчанию для развёртывания.
+const DefaultRedirectType = http.StatusTemporaryRedirect
+
+// permanentRedirectMaxAge задаёт время, в течение которого браузеры и CDN могут кэшировать постоянный редирект.
+const permanentRedirectMaxAge = 24 * time.Hour
+
+var ErrRedirectTypeInvalid = errors.New("redirect_type must be one of 301, 302, 307, 308")
+
+// RedirectType задаёт HTTP-статус, которым отвечает редирект по короткой ссылке.
+// Нулевое значение означает тип редиректа по умолчанию для развёртывания.
+type RedirectType int
+
+// NewRedirectType создает тип редиректа из кода статуса. Ноль означает тип по умолчанию.
+func NewRedirectType(code int) (RedirectType, error) {
+	switch code {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return RedirectType(code), nil
+	}
+
+	return 0, fmt.Errorf("%w: %d", ErrRedirectTypeInvalid, code)
+}
+
+// StatusCode возвращает код статуса редиректа.
+func (rt RedirectType) StatusCode() int {
+	return int(rt)
+}
+
+// IsPermanent сообщает, является ли редирект постоянным.
+func (rt RedirectType) IsPermanent() bool {
+	return rt == http.StatusMovedPermanently || rt == http.StatusPermanentRedirect
+}
+
+// CacheControl возвращает значение заголовка Cache-Control для редиректа: постоянные редиректы
+// разрешено кэшировать браузерам и CDN, временные не кэшируются вовсе.
+func (rt RedirectType) CacheControl() string {
+	if rt.IsPermanent() {
+		return fmt.Sprintf("public, max-age=%d", int(permanentRedirectMaxAge.Seconds()))
+	}
+
+	return "no-store"
+}
@@ -1,26 +1,85 @@
 package valueobject
 
 import (
+	"errors"
 	"fmt"
-	"math/rand"
+	"strings"
 )
 
 const (
 	shortURLLength = 5
 	letterBytes    = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	aliasBytes     = letterBytes + "0123456789-_"
+	aliasMinLength = 3
+	aliasMaxLength = 32
 )
 
+var (
+	ErrAliasInvalid  = fmt.Errorf("alias must be %d-%d characters long and contain only latin letters, digits, '-' or '_'", aliasMinLength, aliasMaxLength)
+	ErrAliasReserved = errors.New("alias is reserved")
+)
+
+// reservedAliases содержит ключи, совпадающие с путями сервиса, которые нельзя занять пользовательским алиасом.
+var reservedAliases = map[string]struct{}{
+	"ping": {},
+	"api":  {},
+}
+
 type ShortURL struct {
 	baseURL  BaseURL
 	shortKey string
 }
 
-// NewShortURL создает новый объект ShortURL с заданной базовой URL и сгенерированным коротким ключом.
-func NewShortURL(baseURL BaseURL) ShortURL {
+// NewShortURL создает новый объект ShortURL с заданной базовой URL и коротким ключом, полученным от генератора
+// на попытке attempt.
+func NewShortURL(baseURL BaseURL, generator KeyGenerator, originalURL string, attempt int) (ShortURL, error) {
+	shortKey, err := generator.Generate(originalURL, attempt)
+	if err != nil {
+		return ShortURL{}, err
+	}
+
 	return ShortURL{
 		baseURL:  baseURL,
-		shortKey: generateShortKey(),
+		shortKey: shortKey,
+	}, nil
+}
+
+// NewShortURLFromAlias создает новый объект ShortURL с пользовательским алиасом в качестве короткого ключа.
+// Возвращает ошибку, если алиас не соответствует политике именования или зарезервирован.
+func NewShortURLFromAlias(baseURL BaseURL, alias string) (ShortURL, error) {
+	if err := ValidateAlias(alias); err != nil {
+		return ShortURL{}, err
+	}
+
+	return ShortURL{
+		baseURL:  baseURL,
+		shortKey: alias,
+	}, nil
+}
+
+// ValidateAlias проверяет длину и набор символов алиаса, а также отсутствие его в списке зарезервированных слов.
+func ValidateAlias(alias string) error {
+	if len(alias) < aliasMinLength || len(alias) > aliasMaxLength {
+		return ErrAliasInvalid
+	}
+
+	for _, r := range alias {
+		if !strings.ContainsRune(aliasBytes, r) {
+			return ErrAliasInvalid
+		}
 	}
+
+	if isReserved(alias) {
+		return ErrAliasReserved
+	}
+
+	return nil
+}
+
+// isReserved сообщает, совпадает ли ключ без учёта регистра с зарезервированным словом.
+func isReserved(key string) bool {
+	_, reserved := reservedAliases[strings.ToLower(key)]
+	return reserved
 }
 
 // ToString возвращает строку в формате: url/shortKey.
@@ -32,15 +91,3 @@ func (su ShortURL) ToString() string {
 func (su ShortURL) ShortKey() string {
 	return su.shortKey
 }
-
-// generateShortKey генерирует короткий ключ для сокращенной ссылки.
-// Использует криптографически стойкий генератор случайных чисел.
-func generateShortKey() string {
-	b := make([]byte, shortURLLength)
-
-	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
-	}
-
-	return string(b)
-}
@@ -0,0 +1,72 @@
+package valueobject
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	tagMaxLength  = 32
+	maxTagsPerURL = 20
+)
+
+var (
+	ErrTagInvalid  = fmt.Errorf("tag must be 1-%d characters long and contain only letters, digits, '-' or '_'", tagMaxLength)
+	ErrTooManyTags = fmt.Errorf("a link can have at most %d tags", maxTagsPerURL)
+)
+
+// NormalizeTags приводит теги к нижнему регистру, удаляет пробелы по краям и дубликаты
+// и возвращает их в отсортированном виде. Возвращает ошибку, если тег не соответствует политике именования.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{}, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if err := validateTag(tag); err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxTagsPerURL {
+		return nil, ErrTooManyTags
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// MergeTags объединяет текущие и добавляемые теги без дубликатов, соблюдая ограничение на их количество.
+func MergeTags(current, added []string) ([]string, error) {
+	return NormalizeTags(append(append([]string(nil), current...), added...))
+}
+
+// validateTag проверяет длину и набор символов тега.
+func validateTag(tag string) error {
+	if tag == "" || utf8.RuneCountInString(tag) > tagMaxLength {
+		return ErrTagInvalid
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return ErrTagInvalid
+		}
+	}
+
+	return nil
+}
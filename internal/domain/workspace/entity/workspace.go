@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Роли участников рабочего пространства. Каждая следующая роль включает права предыдущей:
+// наблюдатель видит ссылки и их статистику, редактор создаёт, меняет и удаляет ссылки,
+// владелец управляет участниками.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// roleRanks упорядочивает роли участников по возрастанию прав.
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Workspace — рабочее пространство, ссылки которого принадлежат команде, а не создавшему их пользователю.
+type Workspace struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Member — участник рабочего пространства.
+type Member struct {
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Email       string    `json:"email"` // Email пользователя на момент добавления, по которому участника приглашали
+	Role        string    `json:"role"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// Membership — рабочее пространство вместе с ролью в нём пользователя.
+type Membership struct {
+	Workspace
+	Role string `json:"role"`
+}
+
+func NewWorkspace(name string) *Workspace {
+	id := uuid.New()
+
+	return &Workspace{
+		ID:        id,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func NewMember(workspaceID uuid.UUID, userID, email, role string) *Member {
+	return &Member{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Email:       email,
+		Role:        role,
+		AddedAt:     time.Now().UTC(),
+	}
+}
+
+// IsValidRole сообщает, что role — одна из ролей участника рабочего пространства.
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAllows сообщает, что участнику с ролью role доступно действие, требующее роли required.
+func RoleAllows(role, required string) bool {
+	return IsValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+// IsOwner сообщает, что участник управляет рабочим пространством.
+func (m Member) IsOwner() bool {
+	return m.Role == RoleOwner
+}
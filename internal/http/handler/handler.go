@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"embed"
 	"errors"
+	"html/template"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/dto"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/shortener"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
 )
 
@@ -18,14 +25,49 @@ const (
 	RequestBodyIsEmpty    = "request body is empty"
 	BadRequest            = "bad request"
 	URLFieldIsEmpty       = "the url field cannot be empty"
+	InvalidTTL            = "the ttl parameter must be an integer number of seconds"
+	InvalidExpiresAt      = "the expires_at parameter must be in RFC 3339 format"
+	InvalidLimit          = "the limit parameter must be a positive integer"
+	InvalidSort           = "the sort parameter must be one of created_at, -created_at, original_url, -original_url"
+	InvalidRedirectType   = "the redirect_type parameter must be an HTTP status code"
+	InvalidPassQuery      = "the pass_query parameter must be a boolean"
+	Conflict              = "conflict"
+	ServiceUnavailable    = "service unavailable"
+	TooManyRequests       = "too many requests"
+	PayloadTooLarge       = "payload too large"
+	UserIDMissing         = "user ID is missing in request context"
 )
 
+// maxBatchBodySize — максимальный размер тела пакетного запроса на создание ссылок в байтах.
+const maxBatchBodySize = 1 << 20
+
 var (
-	ErrURLIsEmpty       = errors.New(URLFieldIsEmpty)
-	ErrReadAll          = errors.New(FailedReadRequestBody)
-	ErrRequestBodyEmpty = errors.New(RequestBodyIsEmpty)
+	ErrURLIsEmpty          = errors.New(URLFieldIsEmpty)
+	ErrReadAll             = errors.New(FailedReadRequestBody)
+	ErrRequestBodyEmpty    = errors.New(RequestBodyIsEmpty)
+	ErrInvalidTTL          = errors.New(InvalidTTL)
+	ErrInvalidExpiresAt    = errors.New(InvalidExpiresAt)
+	ErrInvalidLimit        = errors.New(InvalidLimit)
+	ErrInvalidSort         = errors.New(InvalidSort)
+	ErrInvalidRedirectType = errors.New(InvalidRedirectType)
+	ErrInvalidPassQuery    = errors.New(InvalidPassQuery)
+	ErrUserIDMissing       = errors.New(UserIDMissing)
 )
 
+// previewTemplate — шаблон страницы предпросмотра ссылки.
+//
+//go:embed templates/preview.html
+var previewFS embed.FS
+
+var previewTemplate = template.Must(template.ParseFS(previewFS, "templates/preview.html"))
+
+// previewPage содержит данные для страницы предпросмотра ссылки.
+type previewPage struct {
+	Title       string
+	Destination string
+	CreatedAt   time.Time
+}
+
 // Handler управляет HTTP-запросами, связанными с сокращением URL-адресов.
 type Handler struct {
 	shortenerService shortener.Shortener
@@ -41,30 +83,87 @@ func New(ss *shortener.Shortener, deleteChannel chan dto.DeleteTask) Handler {
 }
 
 // Get обрабатывает GET-запрос для получения оригинального URL по короткому ключу.
-// Возвращает 302 Redirect с заголовком Location.
+// Ключ ищется среди ссылок домена из заголовка Host. Отвечает редиректом с кодом статуса, заданным для ссылки,
+// и заголовком Cache-Control: постоянные редиректы кэшируются, временные — нет.
+// Параметры запроса и UTM-метки добавляются к адресу назначения, если это задано в настройках ссылки.
+// С параметром preview=1 вместо редиректа отдаётся страница предпросмотра.
 func (h Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if preview, _ := strconv.ParseBool(r.URL.Query().Get("preview")); preview {
+		h.Preview(w, r)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
-	url, err := h.shortenerService.GetShortURL(id)
+	domain := h.shortenerService.DomainFromHost(r.Host)
+	url, err := h.shortenerService.GetShortURL(domain, id)
 
 	if err != nil {
-		if errors.Is(err, storage.ErrURLNotFound) {
-			http.Error(w, "URL not found", http.StatusNotFound)
-			return
-		} else if errors.Is(err, storage.ErrURLDeleted) {
-			http.Error(w, "URL is deleted", http.StatusGone)
-			return
-		}
+		respondWithShortURLError(w, err)
+		return
+	}
+
+	h.shortenerService.RecordClick(domain, url.ShortKey, r.Referer(), r.UserAgent(), r.RemoteAddr)
+
+	redirectType := h.shortenerService.RedirectType(url)
+
+	w.Header().Set("Location", url.Destination(r.URL.Query()))
+	w.Header().Set("Cache-Control", redirectType.CacheControl())
+	w.WriteHeader(redirectType.StatusCode())
+}
+
+// Preview обрабатывает GET-запрос на страницу предпросмотра ссылки.
+// Показывает адрес назначения, дату создания и заголовок ссылки с кнопкой перехода.
+// Переход по ссылке при этом не засчитывается.
+func (h Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	domain := h.shortenerService.DomainFromHost(r.Host)
+	url, err := h.shortenerService.GetShortURL(domain, id)
+
+	if err != nil {
+		respondWithShortURLError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	query.Del("preview")
+
+	page := previewPage{
+		Title:       url.Title,
+		Destination: url.Destination(query),
+		CreatedAt:   url.CreatedAt,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := previewTemplate.Execute(w, page); err != nil {
+		slog.Error("failed to render preview page", slog.String("short_key", id), slog.Any("error", err))
+	}
+}
 
-		http.Error(w, err.Error(), http.StatusNotFound)
+// respondWithShortURLError отвечает текстовой ошибкой с кодом статуса, соответствующим ошибке получения ссылки.
+func respondWithShortURLError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrURLNotFound) {
+		http.Error(w, "URL not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, storage.ErrURLDeleted) {
+		http.Error(w, "URL is deleted", http.StatusGone)
+		return
+	} else if errors.Is(err, storage.ErrURLExpired) {
+		http.Error(w, "URL is expired", http.StatusGone)
+		return
+	} else if errors.Is(err, shortener.ErrURLBlocked) {
+		http.Error(w, "URL is blocked", http.StatusUnavailableForLegalReasons)
 		return
 	}
 
-	w.Header().Set("Location", url.OriginalURL)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+	http.Error(w, err.Error(), http.StatusNotFound)
 }
 
 // Post обрабатывает POST-запрос для создания короткого URL.
-// Ожидает URL в теле запроса и возвращает короткий URL или ошибку.
+// Ожидает URL в теле запроса и необязательные параметры alias, ttl, expires_at, domain, redirect_type,
+// pass_query, utm_source, utm_medium, utm_campaign и title в строке запроса,
+// возвращает короткий URL или ошибку.
 func (h Handler) Post(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	defer r.Body.Close()
@@ -79,7 +178,17 @@ func (h Handler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shortURL, err := h.shortenerService.CreateShortURL(r.Context(), string(body))
+	opts, err := shortenOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if opts.Domain == "" {
+		opts.Domain = h.shortenerService.DomainFromHost(r.Host)
+	}
+
+	shortURL, err := h.shortenerService.CreateShortURL(r.Context(), string(body), opts)
 
 	if err != nil {
 		if errors.Is(err, storage.ErrURLAlreadyExist) {
@@ -87,15 +196,70 @@ func (h Handler) Post(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusConflict)
 			w.Write([]byte(shortURL))
 			return
+		} else if errors.Is(err, storage.ErrShortKeyTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if errors.Is(err, shortener.ErrKeyspaceExhausted) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if errors.Is(err, shortener.ErrQuotaExceeded) {
+			h.setQuotaHeaders(w, r)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
 		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.setQuotaHeaders(w, r)
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte(shortURL))
 }
 
+// shortenOptionsFromQuery извлекает параметры создания короткой ссылки из строки запроса.
+func shortenOptionsFromQuery(query url.Values) (dto.ShortenOptions, error) {
+	opts := dto.ShortenOptions{Alias: query.Get("alias"), Domain: query.Get("domain"), Title: query.Get("title")}
+
+	if v := query.Get("ttl"); v != "" {
+		ttl, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return dto.ShortenOptions{}, ErrInvalidTTL
+		}
+		opts.TTL = time.Duration(ttl) * time.Second
+	}
+
+	if v := query.Get("expires_at"); v != "" {
+		expiresAt, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return dto.ShortenOptions{}, ErrInvalidExpiresAt
+		}
+		opts.ExpiresAt = &expiresAt
+	}
+
+	if v := query.Get("redirect_type"); v != "" {
+		redirectType, err := strconv.Atoi(v)
+		if err != nil {
+			return dto.ShortenOptions{}, ErrInvalidRedirectType
+		}
+		opts.RedirectType = redirectType
+	}
+
+	if v := query.Get("pass_query"); v != "" {
+		passQuery, err := strconv.ParseBool(v)
+		if err != nil {
+			return dto.ShortenOptions{}, ErrInvalidPassQuery
+		}
+		opts.PassQuery = passQuery
+	}
+
+	utm := &entity.UTM{Source: query.Get("utm_source"), Medium: query.Get("utm_medium"), Campaign: query.Get("utm_campaign")}
+	if !utm.IsEmpty() {
+		opts.UTM = utm
+	}
+
+	return opts, nil
+}
+
 // Ping обрабатывает запрос для проверки состояния сервиса.
 // Возвращает 200 OK если состояние сервиса в порядке, иначе 500 Internal Server Error.
 func (h Handler) Ping(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+var ErrShortKeysEmpty = errors.New("at least one short key must be set")
+
+// AdminBatchResponse представляет результат административного изменения ссылок.
+type AdminBatchResponse struct {
+	Affected int64 `json:"affected"`
+}
+
+// AdminListURLs обрабатывает GET-запрос администратора на получение ссылок всех пользователей.
+// Поддерживает те же параметры, что и список ссылок пользователя: filter ищет подстроку в адресе назначения,
+// tag, deleted, sort, limit и cursor. У каждой ссылки указан её владелец.
+func (h Handler) AdminListURLs(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
+
+	urls, nextCursor, err := h.shortenerService.FindAllShortURL(opts)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+			return
+		}
+
+		slog.Error("Error fetching URLs of all users", slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	setNextPageHeaders(w, r, nextCursor)
+	respondWithJSON(w, http.StatusOK, urls)
+}
+
+// AdminUserURLs обрабатывает GET-запрос администратора на получение ссылок пользователя по идентификатору.
+// Поддерживает те же параметры, что и список ссылок самого пользователя.
+func (h Handler) AdminUserURLs(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	opts, err := listOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
+
+	urls, nextCursor, err := h.shortenerService.GetAllShortURL(entity.Owner{UserID: userID}, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserListURL):
+			respondWithJSON(w, http.StatusOK, []*entity.URLItem{})
+		case errors.Is(err, storage.ErrInvalidCursor):
+			respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		default:
+			slog.Error("Error fetching URLs for user", slog.String("userID", userID), slog.String("error", err.Error()))
+			respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		}
+		return
+	}
+
+	for _, url := range urls {
+		url.UserID = userID
+	}
+
+	setNextPageHeaders(w, r, nextCursor)
+	respondWithJSON(w, http.StatusOK, urls)
+}
+
+// AdminDelete обрабатывает DELETE-запрос администратора на удаление ссылок любых пользователей.
+// Ожидает JSON-массив коротких ключей ссылок домена из параметра domain;
+// ссылки помечаются как удалённые сразу, а не в фоне.
+func (h Handler) AdminDelete(w http.ResponseWriter, r *http.Request) {
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
+
+	shortKeys, ok := decodeShortKeys(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.shortenerService.ForceDeleteShortURL(domain, shortKeys)
+	if err != nil {
+		slog.Error("Failed to force delete URLs", slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, AdminBatchResponse{Affected: count})
+}
+
+// AdminRestore обрабатывает POST-запрос администратора на восстановление удалённых ссылок любых пользователей.
+// Ожидает JSON-массив коротких ключей ссылок домена из параметра domain.
+func (h Handler) AdminRestore(w http.ResponseWriter, r *http.Request) {
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
+
+	shortKeys, ok := decodeShortKeys(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.shortenerService.ForceRestoreShortURL(domain, shortKeys)
+	if err != nil {
+		slog.Error("Failed to force restore URLs", slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, AdminBatchResponse{Affected: count})
+}
+
+// decodeShortKeys читает из тела запроса непустой JSON-массив коротких ключей.
+// При ошибке отвечает клиенту и возвращает false.
+func decodeShortKeys(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var shortKeys []string
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return nil, false
+	}
+
+	if err := json.Unmarshal(body, &shortKeys); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return nil, false
+	}
+
+	if len(shortKeys) == 0 {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrShortKeysEmpty.Error())
+		return nil, false
+	}
+
+	return shortKeys, true
+}
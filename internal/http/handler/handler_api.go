@@ -6,9 +6,17 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/dto"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/shortener"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
@@ -23,7 +31,47 @@ type ErrorResponse struct {
 
 // Request представляет запрос на создание короткого URL.
 type Request struct {
-	URL string `json:"url"`
+	URL          string      `json:"url"`
+	Alias        string      `json:"alias,omitempty"`
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`
+	TTL          int64       `json:"ttl,omitempty"` // Время жизни ссылки в секундах
+	Tags         []string    `json:"tags,omitempty"`
+	Domain       string      `json:"domain,omitempty"`
+	RedirectType int         `json:"redirect_type,omitempty"` // Код статуса редиректа: 301, 302, 307 или 308
+	PassQuery    bool        `json:"pass_query,omitempty"`
+	UTM          *entity.UTM `json:"utm,omitempty"`
+	Title        string      `json:"title,omitempty"` // Заголовок для страницы предпросмотра
+}
+
+// UpdateRequest представляет запрос на изменение адреса назначения и параметров редиректа короткой ссылки.
+type UpdateRequest struct {
+	URL          string      `json:"url,omitempty"`
+	RedirectType *int        `json:"redirect_type,omitempty"`
+	PassQuery    *bool       `json:"pass_query,omitempty"`
+	UTM          *entity.UTM `json:"utm,omitempty"` // Заменяет UTM-метки целиком; пустой объект удаляет их
+	Title        *string     `json:"title,omitempty"`
+}
+
+// TagsRequest представляет запрос на добавление или удаление тегов ссылки.
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagsResponse представляет итоговый набор тегов ссылки.
+type TagsResponse struct {
+	ShortKey string   `json:"short_key"`
+	Tags     []string `json:"tags"`
+}
+
+// BatchItemErrorResponse описывает ошибку проверки элемента пакетного запроса.
+type BatchItemErrorResponse struct {
+	CorrelationID string `json:"correlation_id"`
+	Error         string `json:"error"`
+}
+
+// RestoreResponse представляет количество восстановленных ссылок.
+type RestoreResponse struct {
+	Restored int64 `json:"restored"`
 }
 
 // Response представляет успешный ответ с результатом.
@@ -32,7 +80,9 @@ type Response struct {
 }
 
 // PostAPI обрабатывает POST-запрос для создания короткого URL.
-// Ожидает JSON с полем URL и возвращает короткий URL или ошибку.
+// Ожидает JSON с полем URL и необязательными полями alias, expires_at, ttl, tags, domain, redirect_type, pass_query, utm и title,
+// возвращает короткий URL или ошибку.
+// Если domain не задан, ссылка создаётся на домене из заголовка Host.
 func (h Handler) PostAPI(w http.ResponseWriter, r *http.Request) {
 	var request Request
 
@@ -52,28 +102,63 @@ func (h Handler) PostAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shortURL, err := h.shortenerService.CreateShortURL(r.Context(), request.URL)
+	opts := dto.ShortenOptions{
+		Alias:        request.Alias,
+		ExpiresAt:    request.ExpiresAt,
+		TTL:          time.Duration(request.TTL) * time.Second,
+		Tags:         request.Tags,
+		Domain:       request.Domain,
+		RedirectType: request.RedirectType,
+		PassQuery:    request.PassQuery,
+		UTM:          request.UTM,
+		Title:        request.Title,
+	}
+
+	if opts.Domain == "" {
+		opts.Domain = h.shortenerService.DomainFromHost(r.Host)
+	}
+
+	shortURL, err := h.shortenerService.CreateShortURL(r.Context(), request.URL, opts)
 
 	if err != nil {
 		if errors.Is(err, storage.ErrURLAlreadyExist) {
 			respondWithError(w, http.StatusConflict, BadRequest, shortURL)
 			return
+		} else if errors.Is(err, storage.ErrShortKeyTaken) {
+			respondWithError(w, http.StatusConflict, Conflict, err.Error())
+			return
+		} else if errors.Is(err, shortener.ErrKeyspaceExhausted) {
+			respondWithError(w, http.StatusServiceUnavailable, ServiceUnavailable, err.Error())
+			return
+		} else if errors.Is(err, shortener.ErrQuotaExceeded) {
+			h.setQuotaHeaders(w, r)
+			respondWithError(w, http.StatusTooManyRequests, TooManyRequests, err.Error())
+			return
 		}
 		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
 		return
 	}
 
+	h.setQuotaHeaders(w, r)
 	respondWithJSON(w, http.StatusCreated, Response{Result: shortURL})
 }
 
 // PostBatch обрабатывает POST-запрос для создания нескольких коротких URL.
-// Ожидает массив JSON объектов с полем URL и возвращает массив созданных URL или ошибку.
+// Ожидает массив JSON объектов с полем URL и необязательным полем domain (по умолчанию — домен из заголовка Host)
+// и возвращает массив созданных URL или ошибку. Если элементы пачки некорректны, в detail перечисляются
+// идентификаторы корреляции всех таких элементов и причины отказа.
 func (h Handler) PostBatch(w http.ResponseWriter, r *http.Request) {
 	var requestBatch []*entity.URLItem
 
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodySize)
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, PayloadTooLarge, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
 		return
 	}
@@ -83,44 +168,346 @@ func (h Handler) PostBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hostDomain := h.shortenerService.DomainFromHost(r.Host)
+	for _, item := range requestBatch {
+		if item.Domain == "" {
+			item.Domain = hostDomain
+		}
+	}
+
 	urls, err := h.shortenerService.CreateListShortURL(r.Context(), requestBatch)
 
 	if err != nil {
-		if errors.Is(err, storage.ErrURLAlreadyExist) {
+		var batchErr *shortener.BatchError
+		if errors.As(err, &batchErr) {
+			respondWithError(w, http.StatusBadRequest, BadRequest, batchItemErrors(batchErr))
+			return
+		} else if errors.Is(err, storage.ErrURLAlreadyExist) {
 			respondWithError(w, http.StatusConflict, BadRequest, urls)
 			return
+		} else if errors.Is(err, shortener.ErrKeyspaceExhausted) {
+			respondWithError(w, http.StatusServiceUnavailable, ServiceUnavailable, err.Error())
+			return
+		} else if errors.Is(err, shortener.ErrBatchTooLarge) {
+			h.setQuotaHeaders(w, r)
+			respondWithError(w, http.StatusRequestEntityTooLarge, PayloadTooLarge, err.Error())
+			return
+		} else if errors.Is(err, shortener.ErrQuotaExceeded) {
+			h.setQuotaHeaders(w, r)
+			respondWithError(w, http.StatusTooManyRequests, TooManyRequests, err.Error())
+			return
 		}
 		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
 		return
 	}
 
+	h.setQuotaHeaders(w, r)
 	respondWithJSON(w, http.StatusCreated, urls)
 }
 
+// batchItemErrors формирует описание ошибок каждого некорректного элемента пакетного запроса.
+func batchItemErrors(batchErr *shortener.BatchError) []BatchItemErrorResponse {
+	items := make([]BatchItemErrorResponse, 0, len(batchErr.Items))
+	for _, item := range batchErr.Items {
+		items = append(items, BatchItemErrorResponse{CorrelationID: item.ID, Error: item.Err.Error()})
+	}
+
+	return items
+}
+
+// setQuotaHeaders добавляет к ответу лимиты и остаток квот пользователя и его IP-адреса на создание ссылок.
+// Заголовки передаются только для заданных квот. Если одна из суточных квот исчерпана,
+// заголовок Retry-After сообщает, через сколько секунд она обнулится.
+func (h Handler) setQuotaHeaders(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDContextKey).(string)
+	ip, _ := r.Context().Value(middleware.ClientIPContextKey).(string)
+
+	usage, err := h.shortenerService.QuotaUsage(userID, ip)
+	if err != nil {
+		slog.Error("failed to get quota usage", slog.String("userID", userID), slog.Any("error", err))
+		return
+	}
+
+	if usage.MaxActiveLinks > 0 {
+		w.Header().Set("X-Quota-Links-Limit", strconv.Itoa(usage.MaxActiveLinks))
+		w.Header().Set("X-Quota-Links-Remaining", strconv.Itoa(max(usage.MaxActiveLinks-usage.ActiveLinks, 0)))
+	}
+
+	exhausted := false
+
+	if usage.MaxDailyLinks > 0 {
+		remaining := max(usage.MaxDailyLinks-usage.DailyLinks, 0)
+		w.Header().Set("X-Quota-Daily-Limit", strconv.Itoa(usage.MaxDailyLinks))
+		w.Header().Set("X-Quota-Daily-Remaining", strconv.Itoa(remaining))
+		exhausted = exhausted || remaining == 0
+	}
+
+	if usage.MaxIPDailyLinks > 0 {
+		remaining := max(usage.MaxIPDailyLinks-usage.IPDailyLinks, 0)
+		w.Header().Set("X-Quota-IP-Daily-Limit", strconv.Itoa(usage.MaxIPDailyLinks))
+		w.Header().Set("X-Quota-IP-Daily-Remaining", strconv.Itoa(remaining))
+		exhausted = exhausted || remaining == 0
+	}
+
+	if usage.MaxDailyLinks > 0 || usage.MaxIPDailyLinks > 0 {
+		w.Header().Set("X-Quota-Daily-Reset", strconv.FormatInt(usage.DailyResetAt.Unix(), 10))
+
+		if exhausted {
+			retryAfter := int(math.Ceil(time.Until(usage.DailyResetAt).Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		}
+	}
+
+	if usage.MaxBatchSize > 0 {
+		w.Header().Set("X-Quota-Batch-Limit", strconv.Itoa(usage.MaxBatchSize))
+	}
+}
+
+// GetAll обрабатывает GET-запрос для получения ссылок пользователя.
+// Поддерживает параметры limit, cursor, sort (created_at, original_url, с префиксом "-" для убывания),
+// filter (подстрока оригинального URL), tag (тег ссылки) и deleted=true для просмотра удалённых ссылок.
+// Курсор следующей страницы передаётся в заголовках Link и X-Next-Cursor.
 func (h Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(middleware.UserIDContextKey).(string)
+	owner := linkOwner(r)
+
+	slog.Info("Fetching URLs for owner", slog.String("owner", owner.String()))
 
-	slog.Info("Fetching URLs for user", slog.String("userID", userID))
+	opts, err := listOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
 
-	urls, err := h.shortenerService.GetAllShortURL(userID)
+	urls, nextCursor, err := h.shortenerService.GetAllShortURL(owner, opts)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserListURL) {
-			slog.Info("No URLs found for user", slog.String("userID", userID))
+			slog.Info("No URLs found for owner", slog.String("owner", owner.String()))
 			respondWithError(w, http.StatusNoContent, "", "")
 			return
+		} else if errors.Is(err, storage.ErrInvalidCursor) {
+			respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+			return
 		}
 
-		slog.Error("Error fetching URLs for user", slog.String("userID", userID), slog.String("error", err.Error()))
+		slog.Error("Error fetching URLs for owner", slog.String("owner", owner.String()), slog.String("error", err.Error()))
 		respondWithError(w, http.StatusBadRequest, "", err.Error())
 		return
 	}
 
-	slog.Info("Successfully fetched URLs for user", slog.String("userID", userID))
+	setNextPageHeaders(w, r, nextCursor)
+
+	slog.Info("Successfully fetched URLs for owner", slog.String("owner", owner.String()))
 	respondWithJSON(w, http.StatusOK, urls)
 }
 
+// setNextPageHeaders устанавливает ссылку на следующую страницу и её курсор, если страница не последняя.
+func setNextPageHeaders(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode()))
+	w.Header().Set("X-Next-Cursor", nextCursor)
+}
+
+// linkOwner возвращает владельца ссылок запроса: рабочее пространство из маршрута /api/workspaces/{workspace},
+// если запрос к нему прошёл проверку членства, иначе — самого пользователя.
+func linkOwner(r *http.Request) entity.Owner {
+	userID, _ := r.Context().Value(middleware.UserIDContextKey).(string)
+	workspaceID, _ := r.Context().Value(middleware.WorkspaceIDContextKey).(string)
+	return entity.Owner{UserID: userID, WorkspaceID: workspaceID}
+}
+
+// linkDomain возвращает домен ссылок запроса из параметра domain строки запроса.
+// Без параметра используется домен из заголовка Host, как при создании ссылки.
+// При неизвестном домене в параметре отвечает клиенту и возвращает false.
+func (h Handler) linkDomain(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.URL.Query().Get("domain")
+	if name == "" {
+		return h.shortenerService.DomainFromHost(r.Host), true
+	}
+
+	domain, err := h.shortenerService.ResolveDomain(name)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return "", false
+	}
+	return domain, true
+}
+
+// listOptionsFromQuery извлекает параметры выборки ссылок из строки запроса.
+func listOptionsFromQuery(query url.Values) (entity.ListOptions, error) {
+	opts := entity.ListOptions{
+		Cursor:   query.Get("cursor"),
+		Contains: query.Get("filter"),
+		Tag:      strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Deleted:  query.Get("deleted") == "true",
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return entity.ListOptions{}, ErrInvalidLimit
+		}
+		opts.Limit = limit
+	}
+
+	sortBy := query.Get("sort")
+	if strings.HasPrefix(sortBy, "-") {
+		opts.Desc = true
+		sortBy = sortBy[1:]
+	}
+
+	switch sortBy {
+	case "", entity.SortByCreatedAt:
+		opts.SortBy = entity.SortByCreatedAt
+	case entity.SortByOriginalURL:
+		opts.SortBy = entity.SortByOriginalURL
+	default:
+		return entity.ListOptions{}, ErrInvalidSort
+	}
+
+	return opts, nil
+}
+
+// Update обрабатывает PATCH-запрос для изменения ссылки пользователя.
+// Ожидает JSON с любым набором полей url, redirect_type, pass_query, utm и title и возвращает обновлённую ссылку или ошибку.
+// Домен ссылки задаётся параметром domain, по умолчанию — домен по умолчанию.
+func (h Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var request UpdateRequest
+
+	owner := linkOwner(r)
+	shortKey := chi.URLParam(r, "key")
+
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	update := entity.URLUpdate{
+		OriginalURL:  request.URL,
+		RedirectType: request.RedirectType,
+		PassQuery:    request.PassQuery,
+		UTM:          request.UTM,
+		Title:        request.Title,
+	}
+
+	url, err := h.shortenerService.UpdateShortURL(domain, shortKey, owner, update)
+	if err != nil {
+		if errors.Is(err, storage.ErrURLNotFound) {
+			respondWithError(w, http.StatusNotFound, "URL not found", shortKey)
+			return
+		} else if errors.Is(err, storage.ErrURLAlreadyExist) {
+			respondWithError(w, http.StatusConflict, Conflict, err.Error())
+			return
+		}
+
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, url)
+}
+
+// Stats обрабатывает GET-запрос для получения статистики переходов по ссылке пользователя.
+// Возвращает общее количество переходов и количество переходов по дням.
+func (h Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	owner := linkOwner(r)
+	shortKey := chi.URLParam(r, "key")
+
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.shortenerService.GetClickStats(domain, shortKey, owner)
+	if err != nil {
+		if errors.Is(err, storage.ErrURLNotFound) {
+			respondWithError(w, http.StatusNotFound, "URL not found", shortKey)
+			return
+		}
+
+		slog.Error("Error fetching click stats", slog.String("shortKey", shortKey), slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, stats)
+}
+
+// AddTags обрабатывает POST-запрос для добавления тегов к ссылке пользователя.
+// Ожидает JSON с полем tags и возвращает итоговый набор тегов.
+func (h Handler) AddTags(w http.ResponseWriter, r *http.Request) {
+	h.changeTags(w, r, h.shortenerService.AddTags)
+}
+
+// RemoveTags обрабатывает DELETE-запрос для удаления тегов у ссылки пользователя.
+// Ожидает JSON с полем tags и возвращает итоговый набор тегов.
+func (h Handler) RemoveTags(w http.ResponseWriter, r *http.Request) {
+	h.changeTags(w, r, h.shortenerService.RemoveTags)
+}
+
+// changeTags разбирает запрос на изменение тегов, применяет к ссылке функцию change и отправляет итоговый набор тегов.
+func (h Handler) changeTags(w http.ResponseWriter, r *http.Request, change func(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error)) {
+	var request TagsRequest
+
+	owner := linkOwner(r)
+	shortKey := chi.URLParam(r, "key")
+
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	tags, err := change(domain, shortKey, owner, request.Tags)
+	if err != nil {
+		if errors.Is(err, storage.ErrURLNotFound) {
+			respondWithError(w, http.StatusNotFound, "URL not found", shortKey)
+			return
+		}
+
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	respondWithJSON(w, http.StatusOK, TagsResponse{ShortKey: shortKey, Tags: tags})
+}
+
 func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(middleware.UserIDContextKey).(string)
+	owner := linkOwner(r)
+
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -136,16 +523,69 @@ func (h Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	task := dto.DeleteTask{
+		Domain:    domain,
 		ShortKeys: shortKeys,
-		UserID:    userID,
+		Owner:     owner,
 	}
 
 	h.deleteChannel <- task
 
-	slog.Info("Successfully deleted URLs for user", slog.String("userID", userID))
+	slog.Info("Successfully deleted URLs for owner", slog.String("owner", owner.String()))
 	respondWithJSON(w, http.StatusAccepted, nil)
 }
 
+// Restore обрабатывает POST-запрос для восстановления удалённых ссылок пользователя.
+// Ожидает JSON-массив коротких ключей ссылок домена из параметра domain и возвращает количество восстановленных ссылок.
+// Ссылки с истёкшим сроком жизни не восстанавливаются; если не восстановлено ни одной ссылки, отвечает 404.
+func (h Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	owner := linkOwner(r)
+
+	domain, ok := h.linkDomain(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+	var shortKeys []string
+
+	if err := json.Unmarshal(body, &shortKeys); err != nil {
+		http.Error(w, "Failed to parse JSON", http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.shortenerService.RestoreShortURL(domain, shortKeys, owner)
+	if err != nil {
+		if errors.Is(err, shortener.ErrNothingRestored) {
+			respondWithError(w, http.StatusNotFound, "URL not found", err.Error())
+			return
+		}
+
+		slog.Error("Failed to restore URLs", slog.String("owner", owner.String()), slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, RestoreResponse{Restored: count})
+}
+
+// BlockedURLs обрабатывает GET-запрос администратора для получения сохранённых ссылок,
+// адреса назначения которых запрещены текущим списком запрета, вместе со сработавшими правилами.
+func (h Handler) BlockedURLs(w http.ResponseWriter, r *http.Request) {
+	urls, err := h.shortenerService.BlockedURLs()
+	if err != nil {
+		slog.Error("Failed to find blocked URLs", slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, urls)
+}
+
 // respondWithError отправляет ответ об ошибке в формате JSON.
 func respondWithError(w http.ResponseWriter, code int, errorMessage string, detail interface{}) {
 	respondWithJSON(w, code, ErrorResponse{Code: code, Error: errorMessage, Detail: detail})
@@ -0,0 +1,514 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/auth"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/shortener"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+const (
+	Unauthorized      = "unauthorized"
+	Forbidden         = "forbidden"
+	FailedIssueToken  = "failed to issue auth token"
+	CredentialsNeeded = "the email and password fields cannot be empty"
+)
+
+var (
+	ErrCredentialsEmpty    = errors.New(CredentialsNeeded)
+	ErrRefreshTokenMissing = errors.New("refresh token is missing in both request body and refresh_token cookie")
+	ErrDisableYourself     = errors.New("you cannot disable your own account")
+)
+
+// AuthHandler управляет HTTP-запросами регистрации и входа пользователей.
+type AuthHandler struct {
+	authService      *auth.Auth
+	shortenerService *shortener.Shortener
+}
+
+// NewAuth создает новый экземпляр AuthHandler с заданными сервисами учётных записей и сокращения URL.
+func NewAuth(as *auth.Auth, ss *shortener.Shortener) AuthHandler {
+	return AuthHandler{authService: as, shortenerService: ss}
+}
+
+// CredentialsRequest представляет запрос на регистрацию или вход пользователя.
+type CredentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Username string `json:"username,omitempty"` // Используется только при регистрации
+}
+
+// AuthResponse представляет пользователя, для которого начата сессия.
+type AuthResponse struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token"`
+	// RefreshToken обменивается на новую пару токенов в /api/auth/refresh, когда истекает Token.
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn — время жизни Token в секундах.
+	ExpiresIn int64 `json:"expires_in"`
+	// ClaimedLinks — количество анонимных ссылок, перешедших к пользователю при входе.
+	ClaimedLinks int64 `json:"claimed_links,omitempty"`
+}
+
+// RefreshRequest представляет запрос на обновление токенов.
+// Если refresh_token не передан, используется кука refresh_token.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse представляет новую пару токенов сессии.
+type TokenResponse struct {
+	UserID       string `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// LogoutAllResponse представляет результат завершения всех сессий пользователя.
+type LogoutAllResponse struct {
+	RevokedSessions int64 `json:"revoked_sessions"`
+}
+
+// RoleRequest представляет запрос администратора на назначение роли пользователю.
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// UserResponse представляет учётную запись пользователя для администратора, без хеша пароля.
+type UserResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Username   string     `json:"username,omitempty"`
+	Role       string     `json:"role"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// APIKeyRequest представляет запрос на выпуск API-ключа.
+type APIKeyRequest struct {
+	Name   string   `json:"name,omitempty"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse представляет API-ключ пользователя. Сам ключ возвращается только при выпуске.
+type APIKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Key       string     `json:"key,omitempty"`
+}
+
+// APIKeys возвращает проверку API-ключей для APIKeyMiddleware.
+func (h AuthHandler) APIKeys() middleware.APIKeyAuthenticator {
+	return h.authService
+}
+
+// Sessions возвращает управление сессиями для AuthMiddleware и AuthCheckMiddleware.
+func (h AuthHandler) Sessions() middleware.Sessions {
+	return h.authService
+}
+
+// JWKS обрабатывает GET-запрос на получение открытых ключей проверки токенов в формате JWKS,
+// чтобы другие сервисы могли проверять выпущенные токены.
+func (h AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, middleware.JWKS())
+}
+
+// Register обрабатывает POST-запрос на регистрацию пользователя.
+// Ожидает JSON с полями email, password и необязательным username,
+// создаёт учётную запись и выпускает токен для её постоянного идентификатора.
+// Ссылки, созданные анонимно с текущим токеном, переходят к новой учётной записи.
+func (h AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.authService.Register(request.Email, request.Password, request.Username)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserAlreadyExists) {
+			respondWithError(w, http.StatusConflict, Conflict, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
+
+	h.respondWithSession(w, http.StatusCreated, user, h.claimAnonymousLinks(r, user))
+}
+
+// Login обрабатывает POST-запрос на вход пользователя.
+// Ожидает JSON с полями email и password и выпускает токен для идентификатора пользователя,
+// поэтому ссылки доступны с любого браузера, в котором выполнен вход.
+// Ссылки, созданные анонимно с текущим токеном, переходят к пользователю.
+func (h AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.authService.Login(request.Email, request.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			respondWithError(w, http.StatusUnauthorized, Unauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			respondWithError(w, http.StatusForbidden, Forbidden, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	h.respondWithSession(w, http.StatusOK, user, h.claimAnonymousLinks(r, user))
+}
+
+// Refresh обрабатывает POST-запрос на обновление токенов.
+// Принимает refresh-токен из JSON-поля refresh_token или куки refresh_token и выпускает новую пару токенов;
+// предъявленный refresh-токен после этого недействителен. Повторное предъявление уже обменянного токена
+// завершает сессию.
+func (h AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request RefreshRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+			return
+		}
+	}
+
+	if request.RefreshToken == "" {
+		if cookie, err := r.Cookie(middleware.RefreshTokenCookie); err == nil {
+			request.RefreshToken = cookie.Value
+		}
+	}
+
+	if request.RefreshToken == "" {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrRefreshTokenMissing.Error())
+		return
+	}
+
+	session, tokens, err := middleware.RefreshSession(w, h.authService, request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrSessionInvalid) {
+			middleware.ClearSessionTokens(w)
+			respondWithError(w, http.StatusUnauthorized, Unauthorized, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, TokenResponse{
+		UserID:       session.UserID,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL.Seconds()),
+	})
+}
+
+// Logout обрабатывает POST-запрос на завершение текущей сессии.
+// Токен доступа и refresh-токен сессии перестают приниматься, куки с ними удаляются.
+func (h AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// У запросов с API-ключом идентификатора сессии нет: удаляются только куки.
+	if sessionID, _ := r.Context().Value(middleware.SessionIDContextKey).(string); sessionID != "" {
+		if err := h.authService.EndSession(sessionID); err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+			respondWithError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+
+	middleware.ClearSessionTokens(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll обрабатывает POST-запрос на завершение всех сессий пользователя на всех устройствах,
+// например если токен мог быть украден.
+func (h AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDContextKey).(string)
+
+	count, err := h.authService.EndUserSessions(userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	slog.Info("All sessions revoked", slog.String("userID", userID), slog.Int64("count", count))
+
+	middleware.ClearSessionTokens(w)
+	respondWithJSON(w, http.StatusOK, LogoutAllResponse{RevokedSessions: count})
+}
+
+// CreateAPIKey обрабатывает POST-запрос на выпуск API-ключа пользователя.
+// Ожидает JSON с необязательным полем name и списком scopes из links:read, links:write и links:delete.
+// Ключ возвращается в ответе только один раз, сохраняется лишь его хеш.
+func (h AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request APIKeyRequest
+
+	userID := r.Context().Value(middleware.UserIDContextKey).(string)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	key, rawKey, err := h.authService.CreateAPIKey(userID, request.Name, request.Scopes)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+		return
+	}
+
+	response := toAPIKeyResponse(key)
+	response.Key = rawKey
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// ListAPIKeys обрабатывает GET-запрос на получение API-ключей пользователя, включая отозванные.
+func (h AuthHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDContextKey).(string)
+
+	keys, err := h.authService.ListAPIKeys(userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, toAPIKeyResponse(key))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// RevokeAPIKey обрабатывает DELETE-запрос на отзыв API-ключа пользователя по идентификатору.
+func (h AuthHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDContextKey).(string)
+	id := chi.URLParam(r, "id")
+
+	if err := h.authService.RevokeAPIKey(userID, id); err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			respondWithError(w, http.StatusNotFound, "API key not found", id)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableUser обрабатывает POST-запрос администратора на отключение учётной записи.
+// Отключённый пользователь не может войти, его сессии завершаются, а API-ключи перестают приниматься.
+// Ссылки пользователя продолжают работать; при необходимости их удаляют отдельно.
+func (h AuthHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	if adminID, _ := r.Context().Value(middleware.UserIDContextKey).(string); adminID == userID {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrDisableYourself.Error())
+		return
+	}
+
+	h.setUserDisabled(w, userID, true)
+}
+
+// EnableUser обрабатывает POST-запрос администратора на включение ранее отключённой учётной записи.
+func (h AuthHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, chi.URLParam(r, "id"), false)
+}
+
+// SetUserRole обрабатывает POST-запрос администратора на назначение роли пользователю.
+// Первого администратора назначают запросом с заголовком X-Admin-Token либо входом через провайдера
+// OpenID Connect, подтвердившего email из ADMIN_EMAILS.
+func (h AuthHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var request RoleRequest
+
+	userID := chi.URLParam(r, "id")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	user, err := h.authService.SetUserRole(userID, request.Role)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			respondWithError(w, http.StatusNotFound, "user not found", userID)
+			return
+		} else if errors.Is(err, auth.ErrRoleInvalid) {
+			respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toUserResponse(user))
+}
+
+// setUserDisabled меняет признак отключения учётной записи и отвечает её данными.
+func (h AuthHandler) setUserDisabled(w http.ResponseWriter, userID string, disabled bool) {
+	user, err := h.authService.SetUserDisabled(userID, disabled)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			respondWithError(w, http.StatusNotFound, "user not found", userID)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toUserResponse(user))
+}
+
+// toUserResponse преобразует учётную запись в ответ без хеша пароля.
+func toUserResponse(user *entity.User) UserResponse {
+	return UserResponse{
+		ID:         user.ID.String(),
+		Email:      user.Email,
+		Username:   user.Username,
+		Role:       user.UserRole(),
+		CreatedAt:  user.CreatedAt,
+		DisabledAt: user.DisabledAt,
+	}
+}
+
+// toAPIKeyResponse преобразует API-ключ в ответ без хеша ключа.
+func toAPIKeyResponse(key *entity.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:        key.ID.String(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+// claimAnonymousLinks передаёт пользователю ссылки анонимного идентификатора из токенов запроса
+// и возвращает их количество. Ссылки другой учётной записи не передаются, даже если запрос выполнен с её токеном.
+// Ошибка передачи не мешает входу и только записывается в лог.
+func (h AuthHandler) claimAnonymousLinks(r *http.Request, user *entity.User) int64 {
+	anonymousID, ok := h.requestUserID(r)
+	if !ok || anonymousID == user.ID.String() {
+		return 0
+	}
+
+	registered, err := h.authService.IsRegistered(anonymousID)
+	if err != nil {
+		slog.Error("Failed to check anonymous user", slog.String("userID", anonymousID), slog.String("error", err.Error()))
+		return 0
+	}
+
+	if registered {
+		return 0
+	}
+
+	count, err := h.shortenerService.ClaimLinks(anonymousID, user.ID.String())
+	if err != nil {
+		slog.Error("Failed to claim anonymous URLs", slog.String("userID", user.ID.String()), slog.String("error", err.Error()))
+		return 0
+	}
+
+	return count
+}
+
+// requestUserID возвращает идентификатор пользователя из токена доступа запроса,
+// а если токен истёк — из refresh-токена в куке, не обменивая его. Токены отозванных сессий не учитываются.
+func (h AuthHandler) requestUserID(r *http.Request) (string, bool) {
+	if userID, err := middleware.UserIDFromRequest(r, h.authService); err == nil {
+		return userID, true
+	}
+
+	cookie, err := r.Cookie(middleware.RefreshTokenCookie)
+	if err != nil {
+		return "", false
+	}
+
+	session, err := h.authService.LookupSession(cookie.Value)
+	if err != nil {
+		return "", false
+	}
+
+	return session.UserID, true
+}
+
+// decodeCredentials читает учётные данные из тела запроса.
+// При ошибке отвечает клиенту и возвращает false.
+func decodeCredentials(w http.ResponseWriter, r *http.Request) (CredentialsRequest, bool) {
+	var request CredentialsRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return request, false
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return request, false
+	}
+
+	if request.Email == "" || request.Password == "" {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrCredentialsEmpty.Error())
+		return request, false
+	}
+
+	return request, true
+}
+
+// respondWithSession начинает сессию пользователя и отвечает его данными и токенами сессии.
+func (h AuthHandler) respondWithSession(w http.ResponseWriter, statusCode int, user *entity.User, claimedLinks int64) {
+	_, tokens, err := middleware.StartSession(w, h.authService, user.ID.String())
+	if err != nil {
+		slog.Error("Failed to generate auth token", slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, FailedIssueToken, nil)
+		return
+	}
+
+	respondWithJSON(w, statusCode, AuthResponse{
+		UserID:       user.ID.String(),
+		Email:        user.Email,
+		Username:     user.Username,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL.Seconds()),
+		ClaimedLinks: claimedLinks,
+	})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/auth"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+const (
+	// OIDCFlowCookie хранит state, nonce и код проверки PKCE начатого входа через провайдера до возврата от него.
+	OIDCFlowCookie = "oidc_flow"
+
+	// oidcFlowTTL ограничивает время, за которое пользователь должен войти на странице провайдера.
+	oidcFlowTTL = 10 * time.Minute
+
+	// oidcCookiePath ограничивает отправку куки входа через провайдера его обработчиками.
+	oidcCookiePath = "/api/auth/oidc/"
+)
+
+var (
+	ErrOIDCFlowMissing  = errors.New("OIDC login was not started or has expired")
+	ErrOIDCStateInvalid = errors.New("state does not match the OIDC login request")
+	ErrOIDCCodeMissing  = errors.New("authorization code is missing")
+)
+
+// OIDCLogin обрабатывает GET-запрос на вход через провайдера OpenID Connect.
+// Сохраняет state, nonce и код проверки PKCE в куке и перенаправляет на страницу входа провайдера.
+func (h AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	flow, err := h.authService.StartOIDC()
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCDisabled) {
+			respondWithError(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	// Провайдер возвращает пользователя переходом с другого сайта, поэтому кука не может быть SameSite=Strict.
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCFlowCookie,
+		Value:    strings.Join([]string{flow.State, flow.Nonce, flow.CodeVerifier}, "."),
+		Path:     oidcCookiePath,
+		MaxAge:   int(oidcFlowTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil,
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, flow.URL, http.StatusFound)
+}
+
+// OIDCCallback обрабатывает GET-запрос возврата от провайдера OpenID Connect.
+// Сверяет state с начатым входом, обменивает код авторизации на ID-токен и начинает сессию пользователя,
+// которому выдан токен, так же как при входе по паролю. При первом входе учётная запись создаётся
+// или привязывается к существующей с тем же подтверждённым провайдером email.
+func (h AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	cookie, err := r.Cookie(OIDCFlowCookie)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrOIDCFlowMissing.Error())
+		return
+	}
+
+	// Кука одноразовая: повторный возврат от провайдера с тем же state не принимается.
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCFlowCookie,
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	flow := strings.Split(cookie.Value, ".")
+	if len(flow) != 3 {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrOIDCFlowMissing.Error())
+		return
+	}
+	state, nonce, codeVerifier := flow[0], flow[1], flow[2]
+
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrOIDCStateInvalid.Error())
+		return
+	}
+
+	if providerError := query.Get("error"); providerError != "" {
+		respondWithError(w, http.StatusUnauthorized, Unauthorized, map[string]string{
+			"error":             providerError,
+			"error_description": query.Get("error_description"),
+		})
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		respondWithError(w, http.StatusBadRequest, BadRequest, ErrOIDCCodeMissing.Error())
+		return
+	}
+
+	user, err := h.authService.FinishOIDC(r.Context(), code, codeVerifier, nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCDisabled):
+			respondWithError(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, auth.ErrOIDCLoginFailed), errors.Is(err, auth.ErrOIDCEmailMissing):
+			slog.Warn("OIDC login rejected", slog.String("error", err.Error()))
+			respondWithError(w, http.StatusUnauthorized, Unauthorized, err.Error())
+		case errors.Is(err, auth.ErrUserDisabled):
+			respondWithError(w, http.StatusForbidden, Forbidden, err.Error())
+		case errors.Is(err, auth.ErrOIDCEmailTaken), errors.Is(err, storage.ErrUserAlreadyExists):
+			respondWithError(w, http.StatusConflict, Conflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "", err.Error())
+		}
+		return
+	}
+
+	// Анонимные ссылки при входе через провайдера не передаются: куки анонимной сессии SameSite=Strict
+	// не отправляются при возврате от провайдера с другого сайта.
+	h.respondWithSession(w, http.StatusOK, user, 0)
+}
@@ -10,29 +10,54 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/viper"
 
 	"github.com/Kenny201/go-yandex-shortener.git/cmd/shortener/config"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/auth"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/dto"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/app/shortener"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/workspace"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/oidc"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/mocks"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/testutil/oidctest"
 )
 
 // setupTestEnvironment инициализирует окружение для теста.
 func setupTestEnvironment(t *testing.T) (*mocks.MockRepository, *gomock.Controller, *shortener.Shortener) {
+	return setupQuotaEnvironment(t, shortener.Quota{})
+}
 
+// setupQuotaEnvironment инициализирует окружение для теста с заданными квотами пользователей.
+func setupQuotaEnvironment(t *testing.T, quota shortener.Quota) (*mocks.MockRepository, *gomock.Controller, *shortener.Shortener) {
 	args := initArgs(t)
 
 	ctrl := gomock.NewController(t)
 	mockRepository := mocks.NewMockRepository(ctrl)
 
-	shortenerService := shortener.New(mockRepository, args.BaseURL)
+	keyGenerator, err := valueobject.NewRandomKeyGenerator(args.KeyLength, args.KeyAlphabet)
+	if err != nil {
+		t.Fatalf("failed to create key generator: %v", err)
+	}
+
+	domains, err := valueobject.NewDomains(args.BaseURL, []string{"brand.io"})
+	if err != nil {
+		t.Fatalf("failed to create domains: %v", err)
+	}
+
+	blocklist, err := valueobject.ParseBlocklist(strings.NewReader("blocked.example\n*.phish.example"))
+	if err != nil {
+		t.Fatalf("failed to create blocklist: %v", err)
+	}
+
+	shortenerService := shortener.New(mockRepository, domains, keyGenerator, valueobject.DefaultRedirectType, blocklist, quota)
 	return mockRepository, ctrl, shortenerService
 }
 
@@ -97,30 +122,111 @@ func TestGetHandler(t *testing.T) {
 	tests := []struct {
 		name               string
 		id                 string
+		host               string
+		wantDomain         string
+		redirectType       int
+		originalURL        string
+		query              string
+		passQuery          bool
+		utm                *entity.UTM
+		mockReturnError    error
 		wantLocationHeader string
+		wantCacheControl   string
 		wantStatusCode     int
 	}{
 		{
 			name:               "redirect_for_existing_short_url_yandex",
 			id:                 "some-short-url",
 			wantLocationHeader: "https://yandex.ru",
+			wantCacheControl:   "no-store",
 			wantStatusCode:     http.StatusTemporaryRedirect,
 		},
+		{
+			name:               "permanent_redirect_is_cacheable",
+			id:                 "permanent-key",
+			redirectType:       http.StatusMovedPermanently,
+			wantLocationHeader: "https://yandex.ru",
+			wantCacheControl:   "public, max-age=86400",
+			wantStatusCode:     http.StatusMovedPermanently,
+		},
+		{
+			name:               "campaign_redirect_is_not_cacheable",
+			id:                 "campaign-key",
+			redirectType:       http.StatusFound,
+			wantLocationHeader: "https://yandex.ru",
+			wantCacheControl:   "no-store",
+			wantStatusCode:     http.StatusFound,
+		},
 		{
 			name:               "redirect_for_existing_short_url_practicum",
 			id:                 "some-short-url-practicum",
 			wantLocationHeader: "https://practicum.yandex.ru",
 			wantStatusCode:     http.StatusTemporaryRedirect,
 		},
+		{
+			name:               "query_dropped_without_pass_through",
+			id:                 "plain-key",
+			query:              "?utm_source=newsletter",
+			wantLocationHeader: "https://yandex.ru",
+			wantStatusCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:               "query_passed_without_clobbering_original",
+			id:                 "pass-key",
+			originalURL:        "https://yandex.ru/?a=1",
+			query:              "?utm_source=newsletter&a=2",
+			passQuery:          true,
+			wantLocationHeader: "https://yandex.ru/?a=1&utm_source=newsletter",
+			wantStatusCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:               "utm_defaults_appended",
+			id:                 "utm-key",
+			originalURL:        "https://yandex.ru/?utm_source=site",
+			utm:                &entity.UTM{Source: "mail", Campaign: "spring"},
+			wantLocationHeader: "https://yandex.ru/?utm_source=site&utm_campaign=spring",
+			wantStatusCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:               "incoming_query_wins_over_utm_defaults",
+			id:                 "pass-utm-key",
+			originalURL:        "https://yandex.ru/path#top",
+			query:              "?utm_medium=social",
+			passQuery:          true,
+			utm:                &entity.UTM{Medium: "email", Campaign: "spring"},
+			wantLocationHeader: "https://yandex.ru/path?utm_campaign=spring&utm_medium=social#top",
+			wantStatusCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:               "redirect_for_vanity_domain",
+			id:                 "brand-key",
+			host:               "brand.io",
+			wantDomain:         "brand.io",
+			wantLocationHeader: "https://brand.ru",
+			wantStatusCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:           "blocked_destination",
+			id:             "blocked-key",
+			originalURL:    "https://login.phish.example/",
+			wantStatusCode: http.StatusUnavailableForLegalReasons,
+		},
 		{
 			name:           "id_not_found",
 			id:             "nonexistent-id",
 			wantStatusCode: http.StatusNotFound,
 		},
 		{
-			name:           "id_deleted",
-			id:             "deleted-id",
-			wantStatusCode: http.StatusGone,
+			name:            "id_deleted",
+			id:              "deleted-id",
+			mockReturnError: storage.ErrURLDeleted,
+			wantStatusCode:  http.StatusGone,
+		},
+		{
+			name:            "id_expired",
+			id:              "expired-id",
+			mockReturnError: storage.ErrURLExpired,
+			wantStatusCode:  http.StatusGone,
 		},
 	}
 
@@ -129,20 +235,31 @@ func TestGetHandler(t *testing.T) {
 			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
 			defer ctrl.Finish()
 
-			if tt.wantStatusCode == http.StatusGone {
-				mockRepository.EXPECT().Get(tt.id).Return(nil, storage.ErrURLDeleted)
+			if tt.mockReturnError != nil {
+				mockRepository.EXPECT().Get(tt.wantDomain, tt.id).Return(nil, tt.mockReturnError)
 			} else if tt.wantStatusCode != http.StatusNotFound {
-				mockRepository.EXPECT().Get(tt.id).Return(&entity.URL{
-					ID:          "some-id",
-					ShortKey:    tt.id,
-					OriginalURL: tt.wantLocationHeader,
+				originalURL := tt.originalURL
+				if originalURL == "" {
+					originalURL = tt.wantLocationHeader
+				}
+				mockRepository.EXPECT().Get(tt.wantDomain, tt.id).Return(&entity.URL{
+					ID:           "some-id",
+					ShortKey:     tt.id,
+					Domain:       tt.wantDomain,
+					OriginalURL:  originalURL,
+					RedirectType: tt.redirectType,
+					PassQuery:    tt.passQuery,
+					UTM:          tt.utm,
 				}, nil)
 			} else {
-				mockRepository.EXPECT().Get(tt.id).Return(nil, fmt.Errorf("not found"))
+				mockRepository.EXPECT().Get(tt.wantDomain, tt.id).Return(nil, fmt.Errorf("not found"))
 			}
 
 			// Полный URL с коротким ключом
-			rw, req := sendRequest(http.MethodGet, fmt.Sprintf("%s/%s", args.BaseURL, tt.id), nil)
+			rw, req := sendRequest(http.MethodGet, fmt.Sprintf("%s/%s%s", args.BaseURL, tt.id, tt.query), nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
 			req = withURLParam(req, "id", tt.id)
 			New(shortenerService, nil).Get(rw, req)
 
@@ -153,10 +270,104 @@ func TestGetHandler(t *testing.T) {
 				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
 			}
 
-			if response.StatusCode == http.StatusTemporaryRedirect && response.Header.Get("Location") != tt.wantLocationHeader {
+			if tt.wantLocationHeader == "" {
+				return
+			}
+
+			if response.Header.Get("Location") != tt.wantLocationHeader {
 				t.Errorf("response Location header does not match: got %v, want %v",
 					response.Header.Get("Location"), tt.wantLocationHeader)
 			}
+
+			if tt.wantCacheControl != "" && response.Header.Get("Cache-Control") != tt.wantCacheControl {
+				t.Errorf("response Cache-Control header does not match: got %v, want %v",
+					response.Header.Get("Cache-Control"), tt.wantCacheControl)
+			}
+		})
+	}
+}
+
+// TestPreviewHandler тестирует страницу предпросмотра ссылки.
+func TestPreviewHandler(t *testing.T) {
+	args := initArgs(t)
+
+	tests := []struct {
+		name            string
+		id              string
+		title           string
+		mockReturnError error
+		wantContains    []string
+		wantStatusCode  int
+	}{
+		{
+			name:           "preview_with_title",
+			id:             "some-short-url",
+			title:          "Яндекс <главная>",
+			wantContains:   []string{"Яндекс &lt;главная&gt;", `href="https://yandex.ru/?from=preview"`, "01.02.2024"},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "preview_without_title",
+			id:             "untitled",
+			wantContains:   []string{"Предпросмотр ссылки", "https://yandex.ru/?from=preview"},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:            "preview_deleted",
+			id:              "deleted-id",
+			mockReturnError: storage.ErrURLDeleted,
+			wantStatusCode:  http.StatusGone,
+		},
+		{
+			name:            "preview_not_found",
+			id:              "nonexistent-id",
+			mockReturnError: storage.ErrURLNotFound,
+			wantStatusCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			if tt.mockReturnError != nil {
+				mockRepository.EXPECT().Get("", tt.id).Return(nil, tt.mockReturnError)
+			} else {
+				mockRepository.EXPECT().Get("", tt.id).Return(&entity.URL{
+					ID:          "some-id",
+					ShortKey:    tt.id,
+					OriginalURL: "https://yandex.ru/?from=preview",
+					CreatedAt:   time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC),
+					Title:       tt.title,
+				}, nil)
+			}
+
+			rw, req := sendRequest(http.MethodGet, fmt.Sprintf("%s/%s?preview=1", args.BaseURL, tt.id), nil)
+			req = withURLParam(req, "id", tt.id)
+			New(shortenerService, nil).Get(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if response.Header.Get("Location") != "" {
+				t.Errorf("preview must not redirect: got Location %v", response.Header.Get("Location"))
+			}
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %v", err)
+			}
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("response body does not contain %q", want)
+				}
+			}
 		})
 	}
 }
@@ -189,6 +400,12 @@ func TestPostAPIHandler(t *testing.T) {
 			wantStatusCode:          http.StatusBadRequest,
 			wantResponseContentType: "application/json",
 		},
+		{
+			name:                    "post_json_request_for_blocked_destination",
+			body:                    `{"url": "https://Blocked.example/login"}`,
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+		},
 		{
 			name:                    "post_request_when_body_isn't_json_type",
 			body:                    "https://practicum.yandex.ru",
@@ -223,6 +440,225 @@ func TestPostAPIHandler(t *testing.T) {
 	}
 }
 
+// TestPostAPIHandlerWithAlias тестирует создание короткого URL с пользовательским алиасом и сроком жизни.
+func TestPostAPIHandlerWithAlias(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		expectCreateCalled bool
+		mockReturnError    error
+		wantStatusCode     int
+		wantShortKey       string
+	}{
+		{
+			name:               "post_with_valid_alias",
+			body:               `{"url": "https://yandex.ru", "alias": "q3-report"}`,
+			expectCreateCalled: true,
+			wantStatusCode:     http.StatusCreated,
+			wantShortKey:       "q3-report",
+		},
+		{
+			name:               "post_with_taken_alias",
+			body:               `{"url": "https://yandex.ru", "alias": "q3-report"}`,
+			expectCreateCalled: true,
+			mockReturnError:    storage.ErrShortKeyTaken,
+			wantStatusCode:     http.StatusConflict,
+		},
+		{
+			name:           "post_with_reserved_alias",
+			body:           `{"url": "https://yandex.ru", "alias": "API"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "post_with_invalid_alias_charset",
+			body:           `{"url": "https://yandex.ru", "alias": "q3 report!"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "post_with_too_short_alias",
+			body:           `{"url": "https://yandex.ru", "alias": "q3"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "post_with_ttl",
+			body:               `{"url": "https://yandex.ru", "alias": "q3-report", "ttl": 3600}`,
+			expectCreateCalled: true,
+			wantStatusCode:     http.StatusCreated,
+			wantShortKey:       "q3-report",
+		},
+		{
+			name:           "post_with_expires_at_in_past",
+			body:           `{"url": "https://yandex.ru", "expires_at": "2001-01-01T00:00:00Z"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "post_with_both_ttl_and_expires_at",
+			body:           `{"url": "https://yandex.ru", "ttl": 60, "expires_at": "2999-01-01T00:00:00Z"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			if tt.expectCreateCalled {
+				mockRepository.EXPECT().Create(gomock.Any()).Return(nil, tt.mockReturnError)
+			}
+
+			rw, req := sendRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body))
+			New(shortenerService, nil).PostAPI(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantShortKey != "" {
+				var result Response
+				if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+
+				if !strings.HasSuffix(result.Result, "/"+tt.wantShortKey) {
+					t.Errorf("unexpected short url: got %v, want key %v", result.Result, tt.wantShortKey)
+				}
+			}
+		})
+	}
+}
+
+// TestPostAPIHandlerDuplicateURLWithAlias проверяет, что повторное сокращение адреса с другим алиасом,
+// в том числе занятым другой ссылкой, возвращает 409 и существующую короткую ссылку, не создавая новую.
+func TestPostAPIHandlerDuplicateURLWithAlias(t *testing.T) {
+	args := initArgs(t)
+
+	keyGenerator, err := valueobject.NewRandomKeyGenerator(args.KeyLength, args.KeyAlphabet)
+	if err != nil {
+		t.Fatalf("failed to create key generator: %v", err)
+	}
+
+	domains, err := valueobject.NewDomains(args.BaseURL, nil)
+	if err != nil {
+		t.Fatalf("failed to create domains: %v", err)
+	}
+
+	repository := storage.NewShortenerMemory()
+	shortenerService := shortener.New(repository, domains, keyGenerator, valueobject.DefaultRedirectType, nil, shortener.Quota{})
+	handler := New(shortenerService, nil)
+
+	for _, body := range []string{`{"url": "https://yandex.ru", "alias": "first"}`, `{"url": "https://ya.ru", "alias": "other"}`} {
+		rw, req := sendRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		handler.PostAPI(rw, req)
+
+		if rw.Code != http.StatusCreated {
+			t.Fatalf("expected status: got %v, want %v", rw.Code, http.StatusCreated)
+		}
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "different_alias", body: `{"url": "https://yandex.ru", "alias": "second"}`},
+		{name: "alias_of_another_link", body: `{"url": "https://yandex.ru", "alias": "other"}`},
+		{name: "without_alias", body: `{"url": "https://yandex.ru"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw, req := sendRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body))
+			handler.PostAPI(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != http.StatusConflict {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, http.StatusConflict)
+			}
+
+			var result ErrorResponse
+			if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+
+			if shortURL, _ := result.Detail.(string); !strings.HasSuffix(shortURL, "/first") {
+				t.Errorf("expected existing short url: got %v", result.Detail)
+			}
+		})
+	}
+
+	if url, err := repository.Get("", "second"); err == nil {
+		t.Errorf("expected no link to be created with the new alias: got %+v", url)
+	}
+}
+
+// TestPostAPIHandlerKeyCollision тестирует повторную генерацию короткого ключа при коллизии.
+func TestPostAPIHandlerKeyCollision(t *testing.T) {
+	tests := []struct {
+		name           string
+		collisions     int
+		wantStatusCode int
+	}{
+		{
+			name:           "post_without_collision",
+			collisions:     0,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "post_after_two_collisions",
+			collisions:     2,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "post_when_keyspace_exhausted",
+			collisions:     5,
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			var usedKeys []string
+			recordKey := func(url *entity.URL) { usedKeys = append(usedKeys, url.ShortKey) }
+
+			if tt.collisions > 0 {
+				mockRepository.EXPECT().Create(gomock.Any()).Do(recordKey).Return(nil, storage.ErrShortKeyTaken).Times(tt.collisions)
+			}
+			if tt.wantStatusCode == http.StatusCreated {
+				mockRepository.EXPECT().Create(gomock.Any()).Do(recordKey).Return(nil, nil)
+			}
+
+			rw, req := sendRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://yandex.ru"}`))
+			New(shortenerService, nil).PostAPI(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode == http.StatusCreated {
+				var result Response
+				if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+
+				if want := usedKeys[len(usedKeys)-1]; !strings.HasSuffix(result.Result, "/"+want) {
+					t.Errorf("unexpected short url: got %v, want key %v", result.Result, want)
+				}
+			}
+		})
+	}
+}
+
 // TestPingHandler тестирует обработчик проверки состояния сервиса.
 func TestPingHandler(t *testing.T) {
 	args := initArgs(t)
@@ -266,11 +702,12 @@ func TestPostBatchHandler(t *testing.T) {
 		mockReturnError         error
 		wantStatusCode          int
 		wantResponseContentType string
+		wantInvalidIDs          []string
 		expectCreateListCalled  bool
 	}{
 		{
 			name: "post_batch_with_valid_urls",
-			body: `[{"url": "https://yandex.ru"}, {"url": "https://practicum.yandex.ru"}]`,
+			body: `[{"correlation_id": "1", "original_url": "https://yandex.ru"}, {"correlation_id": "2", "original_url": "https://practicum.yandex.ru"}]`,
 			mockReturnValue: []*entity.URLItem{
 				{ID: "1", ShortURL: "some-short-url-1"},
 				{ID: "2", ShortURL: "some-short-url-2"},
@@ -291,7 +728,7 @@ func TestPostBatchHandler(t *testing.T) {
 		},
 		{
 			name: "post_batch_with_conflicting_urls",
-			body: `[{"url": "https://yandex.ru"}]`,
+			body: `[{"correlation_id": "1", "original_url": "https://yandex.ru"}]`,
 			mockReturnValue: []*entity.URLItem{
 				{ID: "1", ShortURL: "some-short-url-1"},
 			},
@@ -300,6 +737,28 @@ func TestPostBatchHandler(t *testing.T) {
 			wantResponseContentType: "application/json",
 			expectCreateListCalled:  true,
 		},
+		{
+			name:                    "post_batch_with_invalid_url",
+			body:                    `[{"correlation_id": "1", "original_url": "https://yandex.ru"}, {"correlation_id": "2", "original_url": "javascript:alert(1)"}]`,
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+			wantInvalidIDs:          []string{"2"},
+		},
+		{
+			name:                    "post_batch_with_duplicate_canonical_urls",
+			body:                    `[{"correlation_id": "1", "original_url": "HTTP://Yandex.ru:80"}, {"correlation_id": "2", "original_url": "http://yandex.ru/"}]`,
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+			wantInvalidIDs:          []string{"2"},
+		},
+		{
+			name: "post_batch_reports_every_invalid_item",
+			body: `[{"correlation_id": "1", "original_url": "hello"}, {"correlation_id": "2", "original_url": "https://yandex.ru"},` +
+				` {"correlation_id": "3", "original_url": "https://ya.ru", "ttl": -1}, {"correlation_id": "4", "original_url": "https://blocked.example"}]`,
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+			wantInvalidIDs:          []string{"1", "3", "4"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -326,34 +785,331 @@ func TestPostBatchHandler(t *testing.T) {
 			if contentType := response.Header.Get("Content-Type"); contentType != tt.wantResponseContentType {
 				t.Errorf("response content type header does not match: got %v, want %v", contentType, tt.wantResponseContentType)
 			}
+
+			if tt.wantInvalidIDs == nil {
+				return
+			}
+
+			var body struct {
+				Detail []BatchItemErrorResponse `json:"detail"`
+			}
+			if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			var ids []string
+			for _, item := range body.Detail {
+				if item.Error == "" {
+					t.Errorf("expected error message for correlation_id %s", item.CorrelationID)
+				}
+				ids = append(ids, item.CorrelationID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.wantInvalidIDs) {
+				t.Errorf("unexpected invalid items: got %v, want %v", ids, tt.wantInvalidIDs)
+			}
 		})
 	}
 }
 
-// TestGetAllHandler тестирует обработчик получения всех сокращенных URL пользователя.
-func TestGetAllHandler(t *testing.T) {
+// TestQuotaHandlers проверяет, что превышение квот отклоняется до обращения к хранилищу
+// и ответ содержит заголовки с лимитами и остатком квот.
+func TestQuotaHandlers(t *testing.T) {
 	tests := []struct {
-		name                    string
-		userID                  string
-		mockReturnValue         []*entity.URLItem
-		mockReturnError         error
-		wantStatusCode          int
-		wantResponseContentType string
+		name           string
+		path           string
+		body           string
+		usage          entity.URLUsage
+		wantStatusCode int
+		wantHeaders    map[string]string
+		wantRetryAfter bool
+		countCalls     int
 	}{
 		{
-			name:   "get_all_with_valid_userID",
-			userID: "user123",
-			mockReturnValue: []*entity.URLItem{
-				{ID: "1", ShortURL: "https://short.url/1", OriginalURL: "https://original.url/1"},
-				{ID: "2", ShortURL: "https://short.url/2", OriginalURL: "https://original.url/2"},
-			},
-			mockReturnError:         nil,
-			wantStatusCode:          http.StatusOK,
-			wantResponseContentType: "application/json",
+			name:           "active_links_quota_exceeded",
+			path:           "/api/shorten",
+			body:           `{"url":"https://yandex.ru"}`,
+			usage:          entity.URLUsage{Active: 3, Created: 1},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantHeaders:    map[string]string{"X-Quota-Links-Limit": "3", "X-Quota-Links-Remaining": "0", "X-Quota-Daily-Remaining": "4"},
+			countCalls:     2,
 		},
 		{
-			name:                    "get_all_with_no_urls",
-			userID:                  "user123",
+			name:           "daily_quota_exceeded",
+			path:           "/api/shorten",
+			body:           `{"url":"https://yandex.ru"}`,
+			usage:          entity.URLUsage{Active: 1, Created: 5},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantHeaders:    map[string]string{"X-Quota-Links-Remaining": "2", "X-Quota-Daily-Limit": "5", "X-Quota-Daily-Remaining": "0"},
+			wantRetryAfter: true,
+			countCalls:     2,
+		},
+		{
+			name:           "batch_exceeds_active_links_quota",
+			path:           "/api/shorten/batch",
+			body:           `[{"correlation_id":"1","original_url":"https://a.ru"},{"correlation_id":"2","original_url":"https://b.ru"}]`,
+			usage:          entity.URLUsage{Active: 2},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantHeaders:    map[string]string{"X-Quota-Links-Remaining": "1", "X-Quota-Batch-Limit": "2"},
+			countCalls:     2,
+		},
+		{
+			name:           "batch_too_large",
+			path:           "/api/shorten/batch",
+			body:           `[{"correlation_id":"1","original_url":"https://a.ru"},{"correlation_id":"2","original_url":"https://b.ru"},{"correlation_id":"3","original_url":"https://c.ru"}]`,
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+			wantHeaders:    map[string]string{"X-Quota-Batch-Limit": "2", "X-Quota-Links-Remaining": "3"},
+			countCalls:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupQuotaEnvironment(t, shortener.Quota{MaxActiveLinks: 3, MaxDailyLinks: 5, MaxBatchSize: 2})
+			defer ctrl.Finish()
+
+			// Ссылки пользователя подсчитываются при проверке квоты и при формировании заголовков ответа;
+			// размер пачки проверяется без обращения к хранилищу.
+			mockRepository.EXPECT().CountUsage("user123", "", gomock.Any()).Return(tt.usage, nil).Times(tt.countCalls)
+			mockRepository.EXPECT().Create(gomock.Any()).Times(0)
+			mockRepository.EXPECT().CreateList(gomock.Any(), gomock.Any()).Times(0)
+
+			rw, req := sendRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, "user123"))
+
+			handler := New(shortenerService, nil)
+			if strings.HasSuffix(tt.path, "/batch") {
+				handler.PostBatch(rw, req)
+			} else {
+				handler.PostAPI(rw, req)
+			}
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			for header, want := range tt.wantHeaders {
+				if got := response.Header.Get(header); got != want {
+					t.Errorf("unexpected %s header: got %q, want %q", header, got, want)
+				}
+			}
+
+			if retryAfter := response.Header.Get("Retry-After"); (retryAfter != "") != tt.wantRetryAfter {
+				t.Errorf("unexpected Retry-After header: %q", retryAfter)
+			}
+		})
+	}
+}
+
+// TestIPQuotaHandlers проверяет, что квота IP-адреса ограничивает создание ссылок
+// запросами с разными идентификаторами пользователя, а заголовки квот передаются и в успешных ответах.
+// Ссылки подсчитываются по данным хранилища, поэтому новый экземпляр сервиса видит уже созданные ссылки.
+func TestIPQuotaHandlers(t *testing.T) {
+	args := initArgs(t)
+
+	keyGenerator, err := valueobject.NewRandomKeyGenerator(args.KeyLength, args.KeyAlphabet)
+	if err != nil {
+		t.Fatalf("failed to create key generator: %v", err)
+	}
+
+	domains, err := valueobject.NewDomains(args.BaseURL, nil)
+	if err != nil {
+		t.Fatalf("failed to create domains: %v", err)
+	}
+
+	repository := storage.NewShortenerMemory()
+	newHandler := func() Handler {
+		return New(shortener.New(repository, domains, keyGenerator, valueobject.DefaultRedirectType, nil, shortener.Quota{MaxIPDailyLinks: 2}), nil)
+	}
+
+	tests := []struct {
+		name           string
+		userID         string
+		ip             string
+		wantStatusCode int
+		wantRemaining  string
+		wantRetryAfter bool
+	}{
+		{name: "first_anonymous_request", userID: "anon-1", ip: "203.0.113.7", wantStatusCode: http.StatusCreated, wantRemaining: "1"},
+		{name: "second_anonymous_request", userID: "anon-2", ip: "203.0.113.7", wantStatusCode: http.StatusCreated, wantRemaining: "0", wantRetryAfter: true},
+		{name: "third_anonymous_request", userID: "anon-3", ip: "203.0.113.7", wantStatusCode: http.StatusTooManyRequests, wantRemaining: "0", wantRetryAfter: true},
+		{name: "request_from_other_ip", userID: "anon-4", ip: "198.51.100.1", wantStatusCode: http.StatusCreated, wantRemaining: "1"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Обработчик создаётся заново для каждого запроса, как после перезапуска сервиса.
+			handler := newHandler()
+
+			body := fmt.Sprintf(`{"url":"https://yandex.ru/%d"}`, i)
+			rw, req := sendRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+			ctx := context.WithValue(req.Context(), middleware.UserIDContextKey, tt.userID)
+			req = req.WithContext(context.WithValue(ctx, middleware.ClientIPContextKey, tt.ip))
+
+			handler.PostAPI(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if got := response.Header.Get("X-Quota-IP-Daily-Remaining"); got != tt.wantRemaining {
+				t.Errorf("unexpected X-Quota-IP-Daily-Remaining header: got %q, want %q", got, tt.wantRemaining)
+			}
+
+			if retryAfter := response.Header.Get("Retry-After"); (retryAfter != "") != tt.wantRetryAfter {
+				t.Errorf("unexpected Retry-After header: %q", retryAfter)
+			}
+		})
+	}
+}
+
+// TestPostBatchBodyTooLarge проверяет, что тело пакетного запроса больше допустимого размера отклоняется до разбора.
+func TestPostBatchBodyTooLarge(t *testing.T) {
+	_, ctrl, shortenerService := setupQuotaEnvironment(t, shortener.Quota{})
+	defer ctrl.Finish()
+
+	body := `[{"correlation_id":"1","original_url":"https://a.ru/` + strings.Repeat("a", maxBatchBodySize) + `"}]`
+	rw, req := sendRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+
+	New(shortenerService, nil).PostBatch(rw, req)
+
+	response := rw.Result()
+	defer responseClose(t, response)
+
+	if response.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status: got %v, want %v", response.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+}
+
+// TestGetAllHandler тестирует обработчик получения всех сокращенных URL пользователя.
+func TestGetAllHandler(t *testing.T) {
+	defaultOptions := entity.ListOptions{Limit: 100, SortBy: entity.SortByCreatedAt}
+
+	tests := []struct {
+		name                    string
+		userID                  string
+		workspaceID             string
+		query                   string
+		expectGetAllCalled      bool
+		wantOptions             entity.ListOptions
+		mockReturnValue         []*entity.URLItem
+		mockNextCursor          string
+		mockReturnError         error
+		wantStatusCode          int
+		wantResponseContentType string
+		wantNextCursor          string
+	}{
+		{
+			name:               "get_all_workspace_links",
+			userID:             "user123",
+			workspaceID:        "8f1c2a9e-3b4d-4c5e-9f6a-7b8c9d0e1f2a",
+			expectGetAllCalled: true,
+			wantOptions:        defaultOptions,
+			mockReturnValue: []*entity.URLItem{
+				{ID: "1", ShortURL: "https://short.url/1", OriginalURL: "https://original.url/1", UserID: "user456", WorkspaceID: "8f1c2a9e-3b4d-4c5e-9f6a-7b8c9d0e1f2a"},
+			},
+			wantStatusCode:          http.StatusOK,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:               "get_all_deleted_with_valid_userID",
+			userID:             "user123",
+			query:              "?deleted=true",
+			expectGetAllCalled: true,
+			wantOptions:        entity.ListOptions{Limit: 100, SortBy: entity.SortByCreatedAt, Deleted: true},
+			mockReturnValue: []*entity.URLItem{
+				{ID: "1", ShortURL: "https://short.url/1", OriginalURL: "https://original.url/1"},
+			},
+			mockReturnError:         nil,
+			wantStatusCode:          http.StatusOK,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:               "get_all_with_valid_userID",
+			userID:             "user123",
+			expectGetAllCalled: true,
+			wantOptions:        defaultOptions,
+			mockReturnValue: []*entity.URLItem{
+				{ID: "1", ShortURL: "https://short.url/1", OriginalURL: "https://original.url/1"},
+				{ID: "2", ShortURL: "https://short.url/2", OriginalURL: "https://original.url/2"},
+			},
+			mockReturnError:         nil,
+			wantStatusCode:          http.StatusOK,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:               "get_all_first_page_with_filter_and_sort",
+			userID:             "user123",
+			query:              "?limit=1&sort=-original_url&filter=original",
+			expectGetAllCalled: true,
+			wantOptions:        entity.ListOptions{Limit: 1, SortBy: entity.SortByOriginalURL, Desc: true, Contains: "original"},
+			mockReturnValue: []*entity.URLItem{
+				{ID: "2", ShortURL: "https://short.url/2", OriginalURL: "https://original.url/2"},
+			},
+			mockNextCursor:          "next-page",
+			wantStatusCode:          http.StatusOK,
+			wantResponseContentType: "application/json",
+			wantNextCursor:          "next-page",
+		},
+		{
+			name:               "get_all_filtered_by_tag",
+			userID:             "user123",
+			query:              "?tag=Promo",
+			expectGetAllCalled: true,
+			wantOptions:        entity.ListOptions{Limit: 100, SortBy: entity.SortByCreatedAt, Tag: "promo"},
+			mockReturnValue: []*entity.URLItem{
+				{ID: "1", ShortURL: "https://short.url/1", OriginalURL: "https://original.url/1", Tags: []string{"promo"}},
+			},
+			wantStatusCode:          http.StatusOK,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:               "get_all_with_limit_above_maximum",
+			userID:             "user123",
+			query:              "?limit=100000&cursor=abc",
+			expectGetAllCalled: true,
+			wantOptions:        entity.ListOptions{Limit: 1000, SortBy: entity.SortByCreatedAt, Cursor: "abc"},
+			mockReturnValue: []*entity.URLItem{
+				{ID: "1", ShortURL: "https://short.url/1", OriginalURL: "https://original.url/1"},
+			},
+			wantStatusCode:          http.StatusOK,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:                    "get_all_with_invalid_cursor",
+			userID:                  "user123",
+			query:                   "?cursor=broken",
+			expectGetAllCalled:      true,
+			wantOptions:             entity.ListOptions{Limit: 100, SortBy: entity.SortByCreatedAt, Cursor: "broken"},
+			mockReturnError:         storage.ErrInvalidCursor,
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:                    "get_all_with_invalid_sort",
+			userID:                  "user123",
+			query:                   "?sort=short_key",
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:                    "get_all_with_invalid_limit",
+			userID:                  "user123",
+			query:                   "?limit=-5",
+			wantStatusCode:          http.StatusBadRequest,
+			wantResponseContentType: "application/json",
+		},
+		{
+			name:                    "get_all_with_no_urls",
+			userID:                  "user123",
+			expectGetAllCalled:      true,
+			wantOptions:             defaultOptions,
 			mockReturnValue:         []*entity.URLItem{},
 			mockReturnError:         storage.ErrUserListURL,
 			wantStatusCode:          http.StatusNoContent,
@@ -362,6 +1118,8 @@ func TestGetAllHandler(t *testing.T) {
 		{
 			name:                    "get_all_with_service_error",
 			userID:                  "user123",
+			expectGetAllCalled:      true,
+			wantOptions:             defaultOptions,
 			mockReturnValue:         nil,
 			mockReturnError:         fmt.Errorf("service error"),
 			wantStatusCode:          http.StatusBadRequest,
@@ -375,12 +1133,15 @@ func TestGetAllHandler(t *testing.T) {
 			defer ctrl.Finish()
 
 			// Настройка ожидания вызова метода GetAllShortURL в зависимости от условий теста.
-			if tt.userID != "" {
-				mockRepository.EXPECT().GetAll(tt.userID).Return(tt.mockReturnValue, tt.mockReturnError)
+			if tt.expectGetAllCalled {
+				mockRepository.EXPECT().GetAll(entity.Owner{UserID: tt.userID, WorkspaceID: tt.workspaceID}, tt.wantOptions).Return(tt.mockReturnValue, tt.mockNextCursor, tt.mockReturnError)
 			}
 
-			rw, req := sendRequest(http.MethodGet, "/api/user/urls", nil)
+			rw, req := sendRequest(http.MethodGet, "/api/user/urls"+tt.query, nil)
 			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, tt.userID))
+			if tt.workspaceID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), middleware.WorkspaceIDContextKey, tt.workspaceID))
+			}
 
 			New(shortenerService, nil).GetAll(rw, req)
 
@@ -395,6 +1156,14 @@ func TestGetAllHandler(t *testing.T) {
 				t.Errorf("response content type header does not match: got %v, want %v", response.Header.Get("Content-Type"), tt.wantResponseContentType)
 			}
 
+			if got := response.Header.Get("X-Next-Cursor"); got != tt.wantNextCursor {
+				t.Errorf("next cursor header does not match: got %v, want %v", got, tt.wantNextCursor)
+			}
+
+			if tt.wantNextCursor != "" && !strings.Contains(response.Header.Get("Link"), "cursor="+tt.wantNextCursor) {
+				t.Errorf("link header does not contain next cursor: got %v", response.Header.Get("Link"))
+			}
+
 			if tt.wantStatusCode == http.StatusOK {
 				body, err := io.ReadAll(response.Body)
 				if err != nil {
@@ -414,79 +1183,1520 @@ func TestGetAllHandler(t *testing.T) {
 	}
 }
 
-// TestDeleteHandler тестирует обработчик удаления короткого URL.
-func TestHandler_Delete(t *testing.T) {
+// TestUpdateHandler тестирует обработчик изменения адреса назначения и типа редиректа ссылки.
+func TestUpdateHandler(t *testing.T) {
+	permanent := http.StatusPermanentRedirect
+
 	tests := []struct {
-		name           string
-		body           string
-		userID         string
-		expectedStatus int
-		expectTask     *dto.DeleteTask
-		expectError    bool
+		name               string
+		body               string
+		expectUpdateCalled bool
+		wantUpdate         entity.URLUpdate
+		mockReturnError    error
+		wantStatusCode     int
 	}{
 		{
-			name:           "valid_request",
-			body:           `["short-key-1", "short-key-2"]`,
-			userID:         "user123",
-			expectedStatus: http.StatusAccepted,
-			expectTask: &dto.DeleteTask{
-				ShortKeys: []string{"short-key-1", "short-key-2"},
-				UserID:    "user123",
-			},
-			expectError: false,
+			name:               "update_owned_url",
+			body:               `{"url": "https://practicum.yandex.ru"}`,
+			expectUpdateCalled: true,
+			wantStatusCode:     http.StatusOK,
 		},
 		{
-			name:           "invalid_json",
-			body:           `invalid-json`,
-			userID:         "user123",
-			expectedStatus: http.StatusBadRequest,
-			expectTask:     nil,
-			expectError:    true,
+			name:               "update_redirect_type_only",
+			body:               `{"redirect_type": 308}`,
+			expectUpdateCalled: true,
+			wantUpdate:         entity.URLUpdate{RedirectType: &permanent},
+			wantStatusCode:     http.StatusOK,
 		},
 		{
-			name:           "empty_body",
-			body:           ``,
-			userID:         "user123",
-			expectedStatus: http.StatusBadRequest,
-			expectTask:     nil,
-			expectError:    true,
+			name:           "update_with_invalid_redirect_type",
+			body:           `{"redirect_type": 200}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update_foreign_or_missing_url",
+			body:               `{"url": "https://practicum.yandex.ru"}`,
+			expectUpdateCalled: true,
+			mockReturnError:    storage.ErrURLNotFound,
+			wantStatusCode:     http.StatusNotFound,
+		},
+		{
+			name:               "update_to_already_shortened_url",
+			body:               `{"url": "https://practicum.yandex.ru"}`,
+			expectUpdateCalled: true,
+			mockReturnError:    storage.ErrURLAlreadyExist,
+			wantStatusCode:     http.StatusConflict,
+		},
+		{
+			name:           "update_with_empty_url",
+			body:           `{"url": ""}`,
+			wantStatusCode: http.StatusBadRequest,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			deleteChannel := make(chan dto.DeleteTask, 1)
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
 
-			h := Handler{deleteChannel: deleteChannel}
+			if tt.expectUpdateCalled {
+				var url *entity.URL
+				if tt.mockReturnError == nil {
+					url = &entity.URL{ShortKey: "abcde", OriginalURL: "https://practicum.yandex.ru"}
+				}
+				update := tt.wantUpdate
+				if update.IsEmpty() {
+					update.OriginalURL = "https://practicum.yandex.ru/"
+				}
+				mockRepository.EXPECT().Update("", "abcde", entity.Owner{UserID: "user123"}, update).Return(url, tt.mockReturnError)
+			}
 
-			body := strings.NewReader(tt.body)
+			rw, req := sendRequest(http.MethodPatch, "/api/user/urls/abcde", strings.NewReader(tt.body))
+			req = withURLParam(req, "key", "abcde")
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, "user123"))
 
-			rw, req := sendRequest(http.MethodDelete, "/api/shorten", body)
-			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, tt.userID))
+			New(shortenerService, nil).Update(rw, req)
 
-			h.Delete(rw, req)
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+// TestAddTagsHandler тестирует обработчик добавления тегов к ссылке.
+func TestAddTagsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantTagsArg      []string
+		mockReturnTags   []string
+		mockReturnError  error
+		wantStatusCode   int
+		wantResponseTags []string
+	}{
+		{
+			name:             "add_normalized_tags",
+			body:             `{"tags": ["Promo", " summer ", "promo"]}`,
+			wantTagsArg:      []string{"promo", "summer"},
+			mockReturnTags:   []string{"news", "promo", "summer"},
+			wantStatusCode:   http.StatusOK,
+			wantResponseTags: []string{"news", "promo", "summer"},
+		},
+		{
+			name:            "add_tags_to_foreign_url",
+			body:            `{"tags": ["promo"]}`,
+			wantTagsArg:     []string{"promo"},
+			mockReturnError: storage.ErrURLNotFound,
+			wantStatusCode:  http.StatusNotFound,
+		},
+		{
+			name:           "add_invalid_tag",
+			body:           `{"tags": ["bad tag!"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "add_empty_tags",
+			body:           `{"tags": []}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			if tt.wantTagsArg != nil {
+				mockRepository.EXPECT().AddTags("", "abcde", entity.Owner{UserID: "user123"}, tt.wantTagsArg).Return(tt.mockReturnTags, tt.mockReturnError)
+			}
+
+			rw, req := sendRequest(http.MethodPost, "/api/user/urls/abcde/tags", strings.NewReader(tt.body))
+			req = withURLParam(req, "key", "abcde")
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, "user123"))
+
+			New(shortenerService, nil).AddTags(rw, req)
 
 			response := rw.Result()
 			defer responseClose(t, response)
 
-			if response.StatusCode != tt.expectedStatus {
-				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.expectedStatus)
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
 			}
 
-			if !tt.expectError {
-				select {
-				case task := <-deleteChannel:
-					if !reflect.DeepEqual(task, *tt.expectTask) {
-						t.Errorf("expected task: got %v, want %v", task, *tt.expectTask)
-					}
-				default:
-					t.Errorf("expected task to be sent to channel, but it was not")
+			if tt.wantResponseTags != nil {
+				var resp TagsResponse
+				if err := json.NewDecoder(response.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+
+				if !reflect.DeepEqual(resp.Tags, tt.wantResponseTags) {
+					t.Errorf("expected tags: got %v, want %v", resp.Tags, tt.wantResponseTags)
 				}
 			}
 		})
 	}
 }
 
+// TestStatsHandler тестирует обработчик получения статистики переходов по ссылке.
+func TestStatsHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		shortKey        string
+		query           string
+		host            string
+		wantDomain      string
+		mockReturnValue *entity.ClickStats
+		mockReturnError error
+		wantStatusCode  int
+	}{
+		{
+			name:     "stats_for_owned_url",
+			shortKey: "abcde",
+			mockReturnValue: &entity.ClickStats{
+				ShortKey: "abcde",
+				Total:    3,
+				Daily: []entity.DailyClicks{
+					{Date: "2024-09-01", Count: 1},
+					{Date: "2024-09-02", Count: 2},
+				},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:            "stats_for_foreign_or_missing_url",
+			shortKey:        "zzzzz",
+			mockReturnError: storage.ErrURLNotFound,
+			wantStatusCode:  http.StatusNotFound,
+		},
+		{
+			name:       "stats_for_url_on_extra_domain",
+			shortKey:   "abcde",
+			query:      "?domain=Brand.io",
+			wantDomain: "brand.io",
+			mockReturnValue: &entity.ClickStats{
+				ShortKey: "abcde",
+				Total:    1,
+				Daily:    []entity.DailyClicks{{Date: "2024-09-01", Count: 1}},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:       "stats_for_url_on_request_host",
+			shortKey:   "abcde",
+			host:       "brand.io",
+			wantDomain: "brand.io",
+			mockReturnValue: &entity.ClickStats{
+				ShortKey: "abcde",
+				Total:    1,
+				Daily:    []entity.DailyClicks{{Date: "2024-09-01", Count: 1}},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "stats_for_unknown_domain",
+			shortKey:       "abcde",
+			query:          "?domain=unknown.io",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			if tt.wantStatusCode != http.StatusBadRequest {
+				mockRepository.EXPECT().GetClickStats(tt.wantDomain, tt.shortKey, entity.Owner{UserID: "user123"}).Return(tt.mockReturnValue, tt.mockReturnError)
+			}
+
+			rw, req := sendRequest(http.MethodGet, fmt.Sprintf("/api/user/urls/%s/stats%s", tt.shortKey, tt.query), nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			req = withURLParam(req, "key", tt.shortKey)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, "user123"))
+
+			New(shortenerService, nil).Stats(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode == http.StatusOK {
+				var stats entity.ClickStats
+				if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+
+				if !reflect.DeepEqual(&stats, tt.mockReturnValue) {
+					t.Errorf("unexpected stats: got %+v, want %+v", stats, *tt.mockReturnValue)
+				}
+			}
+		})
+	}
+}
+
+// TestBlockedURLsHandler тестирует обработчик получения ссылок, запрещённых списком запрета.
+func TestBlockedURLsHandler(t *testing.T) {
+	args := initArgs(t)
+
+	mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+	defer ctrl.Finish()
+
+	stored := []*entity.URL{
+		{ShortKey: "abcde", OriginalURL: "https://yandex.ru/", UserID: "user1"},
+		{ShortKey: "fghij", OriginalURL: "https://login.phish.example/", UserID: "user2"},
+		{ShortKey: "klmno", OriginalURL: "https://blocked.example/", Domain: "brand.io"},
+	}
+
+	mockRepository.EXPECT().FindActive(gomock.Any()).DoAndReturn(func(match func(string) bool) ([]*entity.URL, error) {
+		var found []*entity.URL
+		for _, url := range stored {
+			if match(url.OriginalURL) {
+				found = append(found, url)
+			}
+		}
+		return found, nil
+	})
+
+	rw, req := sendRequest(http.MethodGet, "/api/admin/blocklist/matches", nil)
+	New(shortenerService, nil).BlockedURLs(rw, req)
+
+	response := rw.Result()
+	defer responseClose(t, response)
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status: got %v, want %v", response.StatusCode, http.StatusOK)
+	}
+
+	var got []dto.BlockedURL
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	domains, err := valueobject.NewDomains(args.BaseURL, []string{"brand.io"})
+	if err != nil {
+		t.Fatalf("failed to create domains: %v", err)
+	}
+
+	want := []dto.BlockedURL{
+		{ShortURL: domains.ShortURL("", "fghij"), ShortKey: "fghij", OriginalURL: "https://login.phish.example/", UserID: "user2", Rule: "*.phish.example"},
+		{ShortURL: domains.ShortURL("brand.io", "klmno"), ShortKey: "klmno", Domain: "brand.io", OriginalURL: "https://blocked.example/", Rule: "blocked.example"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected blocked URLs: got %+v, want %+v", got, want)
+	}
+}
+
+// TestAdminHandlers тестирует поиск ссылок всех пользователей и их принудительное удаление.
+func TestAdminHandlers(t *testing.T) {
+	mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+	defer ctrl.Finish()
+
+	handler := New(shortenerService, nil)
+
+	mockRepository.EXPECT().
+		FindAll(entity.ListOptions{Limit: 100, SortBy: entity.SortByCreatedAt, Contains: "phish"}).
+		Return([]*entity.URLItem{{ShortKey: "fghij", OriginalURL: "https://login.phish.example/", UserID: "user2"}}, "", nil)
+
+	rw, req := sendRequest(http.MethodGet, "/api/admin/urls?filter=phish", nil)
+	handler.AdminListURLs(rw, req)
+
+	response := rw.Result()
+	defer responseClose(t, response)
+
+	var got []entity.URLItem
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := []entity.URLItem{
+		{ShortURL: "http://localhost/fghij", ShortKey: "fghij", OriginalURL: "https://login.phish.example/", UserID: "user2"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected URLs: got %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+		wantAffected   int64
+	}{
+		{
+			name:           "delete_links_of_any_user",
+			body:           `["fghij","abcde"]`,
+			wantStatusCode: http.StatusOK,
+			wantAffected:   2,
+		},
+		{
+			name:           "empty_keys",
+			body:           `[]`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantStatusCode == http.StatusOK {
+				mockRepository.EXPECT().ForceMarkAsDeleted("", []string{"fghij", "abcde"}).Return(tt.wantAffected, nil)
+			}
+
+			rw, req := sendRequest(http.MethodDelete, "/api/admin/urls", strings.NewReader(tt.body))
+			handler.AdminDelete(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			var got AdminBatchResponse
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Affected != tt.wantAffected {
+				t.Errorf("unexpected affected count: got %v, want %v", got.Affected, tt.wantAffected)
+			}
+		})
+	}
+}
+
+// TestDeleteHandler тестирует обработчик удаления короткого URL.
+func TestHandler_Delete(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		query          string
+		host           string
+		userID         string
+		expectedStatus int
+		expectTask     *dto.DeleteTask
+		expectError    bool
+	}{
+		{
+			name:           "valid_request",
+			body:           `["short-key-1", "short-key-2"]`,
+			userID:         "user123",
+			expectedStatus: http.StatusAccepted,
+			expectTask: &dto.DeleteTask{
+				ShortKeys: []string{"short-key-1", "short-key-2"},
+				Owner:     entity.Owner{UserID: "user123"},
+			},
+			expectError: false,
+		},
+		{
+			name:           "valid_request_on_extra_domain",
+			body:           `["short-key-1"]`,
+			query:          "?domain=brand.io",
+			userID:         "user123",
+			expectedStatus: http.StatusAccepted,
+			expectTask: &dto.DeleteTask{
+				Domain:    "brand.io",
+				ShortKeys: []string{"short-key-1"},
+				Owner:     entity.Owner{UserID: "user123"},
+			},
+			expectError: false,
+		},
+		{
+			name:           "valid_request_on_request_host",
+			body:           `["short-key-1"]`,
+			host:           "brand.io",
+			userID:         "user123",
+			expectedStatus: http.StatusAccepted,
+			expectTask: &dto.DeleteTask{
+				Domain:    "brand.io",
+				ShortKeys: []string{"short-key-1"},
+				Owner:     entity.Owner{UserID: "user123"},
+			},
+			expectError: false,
+		},
+		{
+			name:           "unknown_domain",
+			body:           `["short-key-1"]`,
+			query:          "?domain=unknown.io",
+			userID:         "user123",
+			expectedStatus: http.StatusBadRequest,
+			expectTask:     nil,
+			expectError:    true,
+		},
+		{
+			name:           "invalid_json",
+			body:           `invalid-json`,
+			userID:         "user123",
+			expectedStatus: http.StatusBadRequest,
+			expectTask:     nil,
+			expectError:    true,
+		},
+		{
+			name:           "empty_body",
+			body:           ``,
+			userID:         "user123",
+			expectedStatus: http.StatusBadRequest,
+			expectTask:     nil,
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			deleteChannel := make(chan dto.DeleteTask, 1)
+
+			h := New(shortenerService, deleteChannel)
+
+			body := strings.NewReader(tt.body)
+
+			rw, req := sendRequest(http.MethodDelete, "/api/shorten"+tt.query, body)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, tt.userID))
+
+			h.Delete(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.expectedStatus)
+			}
+
+			if !tt.expectError {
+				select {
+				case task := <-deleteChannel:
+					if !reflect.DeepEqual(task, *tt.expectTask) {
+						t.Errorf("expected task: got %v, want %v", task, *tt.expectTask)
+					}
+				default:
+					t.Errorf("expected task to be sent to channel, but it was not")
+				}
+			}
+		})
+	}
+}
+
+// TestRestoreHandler тестирует обработчик восстановления удалённых ссылок.
+func TestRestoreHandler(t *testing.T) {
+	tests := []struct {
+		name                string
+		body                string
+		expectRestoreCalled bool
+		mockReturnCount     int64
+		mockReturnError     error
+		wantStatusCode      int
+		wantRestored        int64
+	}{
+		{
+			name:                "restore_valid_keys",
+			body:                `["short-key-1", "short-key-2"]`,
+			expectRestoreCalled: true,
+			mockReturnCount:     2,
+			wantStatusCode:      http.StatusOK,
+			wantRestored:        2,
+		},
+		{
+			name:                "restore_nothing_restored",
+			body:                `["short-key-1"]`,
+			expectRestoreCalled: true,
+			wantStatusCode:      http.StatusNotFound,
+		},
+		{
+			name:                "restore_with_storage_error",
+			body:                `["short-key-1"]`,
+			expectRestoreCalled: true,
+			mockReturnError:     fmt.Errorf("storage error"),
+			wantStatusCode:      http.StatusInternalServerError,
+		},
+		{
+			name:           "restore_invalid_json",
+			body:           `invalid-json`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+			defer ctrl.Finish()
+
+			if tt.expectRestoreCalled {
+				var shortKeys []string
+				if err := json.Unmarshal([]byte(tt.body), &shortKeys); err != nil {
+					t.Fatalf("invalid test body: %v", err)
+				}
+				mockRepository.EXPECT().Restore("", shortKeys, entity.Owner{UserID: "user123"}).Return(tt.mockReturnCount, tt.mockReturnError)
+			}
+
+			rw, req := sendRequest(http.MethodPost, "/api/user/urls/restore", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, "user123"))
+
+			New(shortenerService, nil).Restore(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode == http.StatusOK {
+				var got RestoreResponse
+				if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+
+				if got.Restored != tt.wantRestored {
+					t.Errorf("unexpected restored count: got %v, want %v", got.Restored, tt.wantRestored)
+				}
+			}
+		})
+	}
+}
+
+// setupAuthHandler создает обработчик учётных записей с одним зарегистрированным пользователем
+// и возвращает его идентификатор.
+func setupAuthHandler(t *testing.T) (AuthHandler, *mocks.MockRepository, *gomock.Controller, string) {
+	t.Helper()
+
+	mockRepository, ctrl, shortenerService := setupTestEnvironment(t)
+
+	authService := auth.New(storage.NewUserMemory(), storage.NewAPIKeyMemory(), storage.NewSessionMemory(), nil, nil)
+	user, err := authService.Register("alice@example.com", "secret123", "alice")
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+
+	return NewAuth(authService, shortenerService), mockRepository, ctrl, user.ID.String()
+}
+
+// TestRegisterHandler тестирует обработчик регистрации пользователя.
+func TestRegisterHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+	}{
+		{
+			name:           "register_new_user",
+			body:           `{"email":"Bob@Example.com","password":"password1","username":"bob"}`,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "register_taken_email",
+			body:           `{"email":"ALICE@example.com","password":"password1"}`,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "register_invalid_email",
+			body:           `{"email":"bob","password":"password1"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "register_short_password",
+			body:           `{"email":"bob@example.com","password":"short"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "register_empty_password",
+			body:           `{"email":"bob@example.com"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authHandler, _, ctrl, _ := setupAuthHandler(t)
+			defer ctrl.Finish()
+
+			rw, req := sendRequest(http.MethodPost, "/api/auth/register", strings.NewReader(tt.body))
+			authHandler.Register(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode != http.StatusCreated {
+				return
+			}
+
+			var got AuthResponse
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Email != "bob@example.com" || got.UserID == "" || got.Token == "" {
+				t.Errorf("unexpected response: %+v", got)
+			}
+
+			if response.Header.Get("Authorization") != "Bearer "+got.Token {
+				t.Errorf("response Authorization header does not match issued token")
+			}
+		})
+	}
+}
+
+// TestLoginHandler тестирует обработчик входа пользователя.
+func TestLoginHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantStatusCode int
+	}{
+		{
+			name:           "login_valid_credentials",
+			body:           `{"email":"Alice@Example.com","password":"secret123"}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "login_wrong_password",
+			body:           `{"email":"alice@example.com","password":"secret1234"}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "login_unknown_email",
+			body:           `{"email":"bob@example.com","password":"secret123"}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "login_invalid_json",
+			body:           `invalid-json`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authHandler, _, ctrl, _ := setupAuthHandler(t)
+			defer ctrl.Finish()
+
+			rw, req := sendRequest(http.MethodPost, "/api/auth/login", strings.NewReader(tt.body))
+			authHandler.Login(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			hasToken := response.Header.Get("Authorization") != ""
+			if hasToken != (tt.wantStatusCode == http.StatusOK) {
+				t.Errorf("unexpected Authorization header presence: got %v", hasToken)
+			}
+		})
+	}
+}
+
+// TestLoginHandlerClaimsAnonymousLinks тестирует передачу анонимных ссылок пользователю при входе.
+func TestLoginHandlerClaimsAnonymousLinks(t *testing.T) {
+	tests := []struct {
+		name             string
+		tokenUserID      string
+		registeredToken  bool
+		invalidToken     bool
+		wantClaimedLinks int64
+	}{
+		{
+			name:             "claim_anonymous_links",
+			tokenUserID:      "6b0f4bd3-8a4c-4e8a-9f0e-5a5c1f3f8f11",
+			wantClaimedLinks: 2,
+		},
+		{
+			name:            "skip_links_of_registered_user",
+			registeredToken: true,
+		},
+		{
+			name:         "skip_invalid_token",
+			invalidToken: true,
+		},
+		{
+			name: "skip_without_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authHandler, mockRepository, ctrl, aliceID := setupAuthHandler(t)
+			defer ctrl.Finish()
+
+			body := `{"email":"alice@example.com","password":"secret123"}`
+			rw, req := sendRequest(http.MethodPost, "/api/auth/login", strings.NewReader(body))
+
+			tokenUserID := tt.tokenUserID
+			if tt.registeredToken {
+				bob, err := authHandler.authService.Register("bob@example.com", "password1", "")
+				if err != nil {
+					t.Fatalf("failed to register user: %v", err)
+				}
+				tokenUserID = bob.ID.String()
+			}
+
+			if tokenUserID != "" {
+				_, tokens, err := middleware.StartSession(httptest.NewRecorder(), authHandler.Sessions(), tokenUserID)
+				if err != nil {
+					t.Fatalf("failed to issue token: %v", err)
+				}
+				req.AddCookie(&http.Cookie{Name: "auth_token", Value: tokens.AccessToken})
+			} else if tt.invalidToken {
+				req.AddCookie(&http.Cookie{Name: "auth_token", Value: "invalid-token"})
+			}
+
+			if tt.wantClaimedLinks > 0 {
+				mockRepository.EXPECT().TransferOwnership(tokenUserID, aliceID).Return(tt.wantClaimedLinks, nil)
+			}
+
+			authHandler.Login(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, http.StatusOK)
+			}
+
+			var got AuthResponse
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.ClaimedLinks != tt.wantClaimedLinks {
+				t.Errorf("claimed links do not match: got %v, want %v", got.ClaimedLinks, tt.wantClaimedLinks)
+			}
+		})
+	}
+}
+
+// loginSession выполняет вход пользователя alice и возвращает выданные токены.
+func loginSession(t *testing.T, authHandler AuthHandler) AuthResponse {
+	t.Helper()
+
+	body := `{"email":"alice@example.com","password":"secret123"}`
+	rw, req := sendRequest(http.MethodPost, "/api/auth/login", strings.NewReader(body))
+	authHandler.Login(rw, req)
+
+	response := rw.Result()
+	defer responseClose(t, response)
+
+	var got AuthResponse
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if got.Token == "" || got.RefreshToken == "" {
+		t.Fatalf("expected access and refresh tokens: got %+v", got)
+	}
+
+	return got
+}
+
+// refreshStatus обменивает refresh-токен и возвращает код ответа и новые токены.
+func refreshStatus(t *testing.T, authHandler AuthHandler, refreshToken string) (int, TokenResponse) {
+	t.Helper()
+
+	body := fmt.Sprintf(`{"refresh_token":%q}`, refreshToken)
+	rw, req := sendRequest(http.MethodPost, "/api/auth/refresh", strings.NewReader(body))
+	authHandler.Refresh(rw, req)
+
+	response := rw.Result()
+	defer responseClose(t, response)
+
+	var got TokenResponse
+	if response.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+
+	return response.StatusCode, got
+}
+
+// TestRefreshHandler проверяет ротацию refresh-токена и завершение сессии при повторном предъявлении старого токена.
+func TestRefreshHandler(t *testing.T) {
+	authHandler, _, ctrl, aliceID := setupAuthHandler(t)
+	defer ctrl.Finish()
+
+	session := loginSession(t, authHandler)
+
+	code, refreshed := refreshStatus(t, authHandler, session.RefreshToken)
+	if code != http.StatusOK {
+		t.Fatalf("expected status: got %v, want %v", code, http.StatusOK)
+	}
+
+	if refreshed.UserID != aliceID || refreshed.RefreshToken == session.RefreshToken {
+		t.Fatalf("expected rotated refresh token for %v: got %+v", aliceID, refreshed)
+	}
+
+	if code, _ := refreshStatus(t, authHandler, session.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("expected reused refresh token to be rejected: got %v, want %v", code, http.StatusUnauthorized)
+	}
+
+	if code, _ := refreshStatus(t, authHandler, refreshed.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("expected session to be revoked after reuse: got %v, want %v", code, http.StatusUnauthorized)
+	}
+
+	if code, _ := refreshStatus(t, authHandler, "invalid"); code != http.StatusUnauthorized {
+		t.Errorf("expected invalid refresh token to be rejected: got %v, want %v", code, http.StatusUnauthorized)
+	}
+}
+
+// TestLogoutHandler проверяет, что после выхода токены сессии отклоняются AuthCheckMiddleware.
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		logoutAll       bool
+		wantOtherActive bool
+	}{
+		{
+			name:            "logout_current_session",
+			wantOtherActive: true,
+		},
+		{
+			name:      "logout_all_sessions",
+			logoutAll: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authHandler, _, ctrl, _ := setupAuthHandler(t)
+			defer ctrl.Finish()
+
+			current := loginSession(t, authHandler)
+			other := loginSession(t, authHandler)
+
+			checkStatus := func(token string, handler http.HandlerFunc) int {
+				rw, req := sendRequest(http.MethodPost, "/api/auth/logout", nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				middleware.AuthCheckMiddleware(authHandler.Sessions())(handler).ServeHTTP(rw, req)
+				return rw.Code
+			}
+
+			logout := authHandler.Logout
+			wantStatusCode := http.StatusNoContent
+			if tt.logoutAll {
+				logout = authHandler.LogoutAll
+				wantStatusCode = http.StatusOK
+			}
+
+			if code := checkStatus(current.Token, logout); code != wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", code, wantStatusCode)
+			}
+
+			ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+			if code := checkStatus(current.Token, ok); code != http.StatusUnauthorized {
+				t.Errorf("expected token of ended session to be rejected: got %v", code)
+			}
+
+			if code, _ := refreshStatus(t, authHandler, current.RefreshToken); code != http.StatusUnauthorized {
+				t.Errorf("expected refresh token of ended session to be rejected: got %v", code)
+			}
+
+			if active := checkStatus(other.Token, ok) == http.StatusOK; active != tt.wantOtherActive {
+				t.Errorf("unexpected state of other session: active=%v, want %v", active, tt.wantOtherActive)
+			}
+		})
+	}
+}
+
+// TestDisableUserHandler проверяет, что отключённый пользователь не может войти,
+// а его сессии завершаются, и что включение учётной записи снова разрешает вход.
+func TestDisableUserHandler(t *testing.T) {
+	authHandler, _, ctrl, aliceID := setupAuthHandler(t)
+	defer ctrl.Finish()
+
+	session := loginSession(t, authHandler)
+
+	setDisabled := func(handler http.HandlerFunc, userID string) int {
+		rw, req := sendRequest(http.MethodPost, "/api/admin/users/"+userID+"/disable", nil)
+		handler(rw, withURLParam(req, "id", userID))
+		return rw.Code
+	}
+
+	login := func() int {
+		body := `{"email":"alice@example.com","password":"secret123"}`
+		rw, req := sendRequest(http.MethodPost, "/api/auth/login", strings.NewReader(body))
+		authHandler.Login(rw, req)
+		return rw.Code
+	}
+
+	if code := setDisabled(authHandler.DisableUser, aliceID); code != http.StatusOK {
+		t.Fatalf("expected status: got %v, want %v", code, http.StatusOK)
+	}
+
+	if code := login(); code != http.StatusForbidden {
+		t.Errorf("expected disabled user to be denied login: got %v, want %v", code, http.StatusForbidden)
+	}
+
+	if code, _ := refreshStatus(t, authHandler, session.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("expected sessions of disabled user to be revoked: got %v, want %v", code, http.StatusUnauthorized)
+	}
+
+	if code := setDisabled(authHandler.DisableUser, "6b0f4bd3-8a4c-4e8a-9f0e-5a5c1f3f8f11"); code != http.StatusNotFound {
+		t.Errorf("expected unknown user to be reported: got %v, want %v", code, http.StatusNotFound)
+	}
+
+	if code := setDisabled(authHandler.EnableUser, aliceID); code != http.StatusOK {
+		t.Fatalf("expected status: got %v, want %v", code, http.StatusOK)
+	}
+
+	if code := login(); code != http.StatusOK {
+		t.Errorf("expected enabled user to log in: got %v, want %v", code, http.StatusOK)
+	}
+}
+
+// TestAdminEmailsRole проверяет, что email из списка администраторов даёт роль администратора
+// только при входе через провайдера, подтвердившего email, а не при регистрации и входе по паролю.
+func TestAdminEmailsRole(t *testing.T) {
+	provider := oidctest.NewProvider("shortener", "client-secret", oidctest.User{})
+	defer provider.Close()
+
+	idp, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:       provider.Issuer(),
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  "http://localhost/api/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("failed to configure OIDC provider: %v", err)
+	}
+
+	_, ctrl, shortenerService := setupTestEnvironment(t)
+	defer ctrl.Finish()
+
+	adminEmails := []string{"root@example.com", "ops@example.com"}
+	authService := auth.New(storage.NewUserMemory(), storage.NewAPIKeyMemory(), storage.NewSessionMemory(), adminEmails, idp)
+	authHandler := NewAuth(authService, shortenerService)
+
+	user, err := authService.Register("Root@Example.com", "secret123", "")
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+
+	if _, err := authService.Login("root@example.com", "secret123"); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+
+	if role, _ := authService.UserRole(user.ID.String()); role != "user" {
+		t.Errorf("expected self-registered admin email not to grant admin role: got %v", role)
+	}
+
+	tests := []struct {
+		name     string
+		user     oidctest.User
+		wantRole string
+	}{
+		{
+			name:     "unverified_admin_email",
+			user:     oidctest.User{Subject: "sub-1", Email: "ops@example.com"},
+			wantRole: "user",
+		},
+		{
+			name:     "verified_admin_email",
+			user:     oidctest.User{Subject: "sub-1", Email: "ops@example.com", EmailVerified: true},
+			wantRole: "admin",
+		},
+		{
+			name:     "verified_regular_email",
+			user:     oidctest.User{Subject: "sub-2", Email: "dave@example.com", EmailVerified: true},
+			wantRole: "user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.SetUser(tt.user)
+
+			rw := httptest.NewRecorder()
+			authHandler.OIDCCallback(rw, oidcCallbackRequest(t, authHandler))
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			var got AuthResponse
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if role, _ := authService.UserRole(got.UserID); role != tt.wantRole {
+				t.Errorf("unexpected role: got %v, want %v", role, tt.wantRole)
+			}
+		})
+	}
+
+	rw, req := sendRequest(http.MethodPost, "/api/admin/users/"+user.ID.String()+"/role", strings.NewReader(`{"role":"admin"}`))
+	authHandler.SetUserRole(rw, withURLParam(req, "id", user.ID.String()))
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected status: got %v, want %v", rw.Code, http.StatusOK)
+	}
+
+	if role, _ := authService.UserRole(user.ID.String()); role != "admin" {
+		t.Errorf("expected admin to grant admin role explicitly: got %v", role)
+	}
+
+	rw, req = sendRequest(http.MethodPost, "/api/admin/users/"+user.ID.String()+"/role", strings.NewReader(`{"role":"root"}`))
+	authHandler.SetUserRole(rw, withURLParam(req, "id", user.ID.String()))
+
+	if rw.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown role to be rejected: got %v, want %v", rw.Code, http.StatusBadRequest)
+	}
+}
+
+// TestOIDCHandlers проверяет вход через тестовый провайдер OpenID Connect: первый вход создаёт учётную запись,
+// повторный находит её по идентификатору у провайдера, а существующая учётная запись с тем же email
+// привязывается только при подтверждённом провайдером email.
+func TestOIDCHandlers(t *testing.T) {
+	provider := oidctest.NewProvider("shortener", "client-secret", oidctest.User{})
+	defer provider.Close()
+
+	idp, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:       provider.Issuer(),
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  "http://localhost/api/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("failed to configure OIDC provider: %v", err)
+	}
+
+	_, ctrl, shortenerService := setupTestEnvironment(t)
+	defer ctrl.Finish()
+
+	authService := auth.New(storage.NewUserMemory(), storage.NewAPIKeyMemory(), storage.NewSessionMemory(), nil, idp)
+	alice, err := authService.Register("alice@example.com", "secret123", "alice")
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+
+	authHandler := NewAuth(authService, shortenerService)
+
+	// Идентификаторы учётных записей по email: повторный вход и привязка должны возвращать ту же учётную запись.
+	userIDs := map[string]string{"alice@example.com": alice.ID.String()}
+
+	tests := []struct {
+		name           string
+		user           oidctest.User
+		tamper         func(req *http.Request)
+		wantStatusCode int
+	}{
+		{
+			name:           "first_login_creates_user",
+			user:           oidctest.User{Subject: "sub-1", Email: "Carol@Example.com", EmailVerified: true, Name: "Carol"},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "repeated_login_finds_user_by_subject",
+			user:           oidctest.User{Subject: "sub-1", Email: "carol.new@example.com", EmailVerified: true},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "unverified_email_of_existing_account",
+			user:           oidctest.User{Subject: "sub-2", Email: "alice@example.com"},
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "verified_email_links_existing_account",
+			user:           oidctest.User{Subject: "sub-2", Email: "alice@example.com", EmailVerified: true},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "state_mismatch",
+			user: oidctest.User{Subject: "sub-3", Email: "dave@example.com", EmailVerified: true},
+			tamper: func(req *http.Request) {
+				query := req.URL.Query()
+				query.Set("state", "forged")
+				req.URL.RawQuery = query.Encode()
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "wrong_code_verifier",
+			user: oidctest.User{Subject: "sub-3", Email: "dave@example.com", EmailVerified: true},
+			tamper: func(req *http.Request) {
+				cookie, _ := req.Cookie(OIDCFlowCookie)
+				req.Header.Del("Cookie")
+				req.AddCookie(&http.Cookie{Name: OIDCFlowCookie, Value: cookie.Value[:strings.LastIndex(cookie.Value, ".")] + ".forged"})
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.SetUser(tt.user)
+
+			req := oidcCallbackRequest(t, authHandler)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			rw := httptest.NewRecorder()
+			authHandler.OIDCCallback(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+
+			var got AuthResponse
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Token == "" || got.RefreshToken == "" {
+				t.Fatalf("expected access and refresh tokens: got %+v", got)
+			}
+
+			if userID, ok := userIDs[got.Email]; ok && userID != got.UserID {
+				t.Errorf("unexpected user of %s: got %v, want %v", got.Email, got.UserID, userID)
+			}
+			userIDs[got.Email] = got.UserID
+		})
+	}
+
+	if userIDs["carol@example.com"] == "" {
+		t.Errorf("expected email to be normalized for the created user: got %v", userIDs)
+	}
+
+	disabledHandler, _, ctrl, _ := setupAuthHandler(t)
+	defer ctrl.Finish()
+
+	rw, req := sendRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	disabledHandler.OIDCLogin(rw, req)
+
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("expected OIDC login to be unavailable without a provider: got %v, want %v", rw.Code, http.StatusNotFound)
+	}
+}
+
+// oidcCallbackRequest начинает вход через провайдера, проходит его страницу входа
+// и возвращает запрос возврата от провайдера с кукой начатого входа.
+func oidcCallbackRequest(t *testing.T, authHandler AuthHandler) *http.Request {
+	t.Helper()
+
+	rw, req := sendRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	authHandler.OIDCLogin(rw, req)
+
+	if rw.Code != http.StatusFound {
+		t.Fatalf("expected redirect to the provider: got %v", rw.Code)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	response, err := client.Get(rw.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to open provider login page: %v", err)
+	}
+	defer responseClose(t, response)
+
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect back from the provider: got %v", response.StatusCode)
+	}
+
+	_, callback := sendRequest(http.MethodGet, response.Header.Get("Location"), nil)
+	for _, cookie := range rw.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+
+	return callback
+}
+
+// TestCreateAPIKeyHandler тестирует выпуск API-ключа и проверку выпущенного ключа.
+func TestCreateAPIKeyHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantScopes     []string
+		wantStatusCode int
+	}{
+		{
+			name:           "create_key_with_scopes",
+			body:           `{"name":"ci","scopes":["links:write","links:read"]}`,
+			wantScopes:     []string{"links:read", "links:write"},
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "create_key_without_scopes",
+			body:           `{"name":"ci"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "create_key_with_unknown_scope",
+			body:           `{"scopes":["links:admin"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authHandler, _, ctrl, aliceID := setupAuthHandler(t)
+			defer ctrl.Finish()
+
+			rw, req := sendRequest(http.MethodPost, "/api/user/api-keys", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, aliceID))
+			authHandler.CreateAPIKey(rw, req)
+
+			response := rw.Result()
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode != http.StatusCreated {
+				return
+			}
+
+			var got APIKeyResponse
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Scopes, tt.wantScopes) || !strings.HasPrefix(got.Key, got.Prefix) {
+				t.Errorf("unexpected API key: %+v", got)
+			}
+
+			userID, scopes, err := authHandler.APIKeys().AuthenticateAPIKey(got.Key)
+			if err != nil || userID != aliceID || !reflect.DeepEqual(scopes, tt.wantScopes) {
+				t.Errorf("issued key is not accepted: got %v, %v, %v", userID, scopes, err)
+			}
+		})
+	}
+}
+
+// TestWorkspaceHandlers проверяет создание рабочего пространства, управление участниками и их ролями,
+// а также запрет оставить пространство без владельца.
+func TestWorkspaceHandlers(t *testing.T) {
+	authService := auth.New(storage.NewUserMemory(), storage.NewAPIKeyMemory(), storage.NewSessionMemory(), nil, nil)
+
+	alice, err := authService.Register("alice@example.com", "secret123", "alice")
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+	bob, err := authService.Register("bob@example.com", "secret123", "bob")
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+	aliceID, bobID := alice.ID.String(), bob.ID.String()
+
+	workspaceHandler := NewWorkspace(workspace.New(storage.NewWorkspaceMemory(), authService))
+
+	call := func(handler http.HandlerFunc, method, userID, workspaceID, memberID, body string) *http.Response {
+		rw, req := sendRequest(method, "/api/workspaces", strings.NewReader(body))
+
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("workspace", workspaceID)
+		chiCtx.URLParams.Add("user", memberID)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)
+
+		// Пустой userID означает запрос без идентификатора пользователя в контексте.
+		if userID != "" {
+			ctx = context.WithValue(ctx, middleware.UserIDContextKey, userID)
+		}
+
+		handler(rw, req.WithContext(ctx))
+		return rw.Result()
+	}
+
+	response := call(workspaceHandler.Create, http.MethodPost, aliceID, "", "", `{"name":"Marketing"}`)
+	defer responseClose(t, response)
+
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status: got %v, want %v", response.StatusCode, http.StatusCreated)
+	}
+
+	var created struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if created.Name != "Marketing" || created.Role != "owner" {
+		t.Fatalf("unexpected workspace: %+v", created)
+	}
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		method         string
+		userID         string
+		memberID       string
+		body           string
+		wantStatusCode int
+	}{
+		{
+			name:           "create_without_user_id",
+			handler:        workspaceHandler.Create,
+			method:         http.MethodPost,
+			body:           `{"name":"Sales"}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "list_without_user_id",
+			handler:        workspaceHandler.List,
+			method:         http.MethodGet,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "remove_member_without_user_id",
+			handler:        workspaceHandler.RemoveMember,
+			method:         http.MethodDelete,
+			memberID:       aliceID,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "anonymous_user_cannot_create_workspace",
+			handler:        workspaceHandler.Create,
+			method:         http.MethodPost,
+			userID:         "anonymous",
+			body:           `{"name":"Sales"}`,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "empty_name",
+			handler:        workspaceHandler.Create,
+			method:         http.MethodPost,
+			userID:         aliceID,
+			body:           `{"name":"  "}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "add_member_with_unknown_role",
+			handler:        workspaceHandler.AddMember,
+			method:         http.MethodPost,
+			userID:         aliceID,
+			body:           `{"email":"bob@example.com","role":"admin"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "add_unregistered_user",
+			handler:        workspaceHandler.AddMember,
+			method:         http.MethodPost,
+			userID:         aliceID,
+			body:           `{"email":"carol@example.com","role":"viewer"}`,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "add_member",
+			handler:        workspaceHandler.AddMember,
+			method:         http.MethodPost,
+			userID:         aliceID,
+			body:           `{"email":"Bob@Example.com","role":"viewer"}`,
+			wantStatusCode: http.StatusCreated,
+		},
+		{
+			name:           "add_member_twice",
+			handler:        workspaceHandler.AddMember,
+			method:         http.MethodPost,
+			userID:         aliceID,
+			body:           `{"email":"bob@example.com","role":"editor"}`,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "demote_last_owner",
+			handler:        workspaceHandler.UpdateMember,
+			method:         http.MethodPatch,
+			userID:         aliceID,
+			memberID:       aliceID,
+			body:           `{"role":"editor"}`,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "promote_member",
+			handler:        workspaceHandler.UpdateMember,
+			method:         http.MethodPatch,
+			userID:         aliceID,
+			memberID:       bobID,
+			body:           `{"role":"editor"}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "member_cannot_remove_owner",
+			handler:        workspaceHandler.RemoveMember,
+			method:         http.MethodDelete,
+			userID:         bobID,
+			memberID:       aliceID,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "last_owner_cannot_leave",
+			handler:        workspaceHandler.RemoveMember,
+			method:         http.MethodDelete,
+			userID:         aliceID,
+			memberID:       aliceID,
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "member_leaves",
+			handler:        workspaceHandler.RemoveMember,
+			method:         http.MethodDelete,
+			userID:         bobID,
+			memberID:       bobID,
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "remove_former_member",
+			handler:        workspaceHandler.RemoveMember,
+			method:         http.MethodDelete,
+			userID:         aliceID,
+			memberID:       bobID,
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := call(tt.handler, tt.method, tt.userID, created.ID, tt.memberID, tt.body)
+			defer responseClose(t, response)
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+
+	members := workspaceHandler.Members()
+
+	if role, err := members.MemberRole(created.ID, aliceID); err != nil || role != "owner" {
+		t.Errorf("unexpected owner role: got %q, %v", role, err)
+	}
+
+	if role, err := members.MemberRole(created.ID, bobID); err != nil || role != "" {
+		t.Errorf("expected former member to lose access: got %q, %v", role, err)
+	}
+}
+
 func initArgs(t *testing.T) *config.Args {
 	t.Helper()
 	err := config.LoadConfig("../../../")
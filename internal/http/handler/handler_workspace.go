@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/auth"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/workspace"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+// WorkspaceHandler управляет HTTP-запросами к рабочим пространствам и их участникам.
+// Ссылки рабочего пространства обслуживает Handler: владельца ссылок он определяет по маршруту запроса.
+type WorkspaceHandler struct {
+	workspaceService *workspace.Workspaces
+}
+
+// NewWorkspace создает новый экземпляр WorkspaceHandler с заданным сервисом рабочих пространств.
+func NewWorkspace(ws *workspace.Workspaces) WorkspaceHandler {
+	return WorkspaceHandler{workspaceService: ws}
+}
+
+// WorkspaceRequest представляет запрос на создание рабочего пространства.
+type WorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// MemberRequest представляет запрос на добавление участника или изменение его роли.
+// Email используется только при добавлении.
+type MemberRequest struct {
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role"`
+}
+
+// Members возвращает проверку членства для WorkspaceMiddleware.
+func (h WorkspaceHandler) Members() middleware.WorkspaceMembers {
+	return h.workspaceService
+}
+
+// Create обрабатывает POST-запрос на создание рабочего пространства.
+// Пользователь, создавший пространство, становится его владельцем.
+func (h WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var request WorkspaceRequest
+
+	userID, ok := requestUserID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	membership, err := h.workspaceService.Create(userID, request.Name)
+	if err != nil {
+		respondWithWorkspaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, membership)
+}
+
+// List обрабатывает GET-запрос на получение рабочих пространств пользователя с его ролью в каждом.
+func (h WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUserID(w, r)
+	if !ok {
+		return
+	}
+
+	memberships, err := h.workspaceService.List(userID)
+	if err != nil {
+		respondWithWorkspaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, memberships)
+}
+
+// ListMembers обрабатывает GET-запрос на получение участников рабочего пространства.
+func (h WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.workspaceService.Members(chi.URLParam(r, "workspace"))
+	if err != nil {
+		respondWithWorkspaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, members)
+}
+
+// AddMember обрабатывает POST-запрос владельца на добавление в рабочее пространство
+// зарегистрированного пользователя по email с заданной ролью.
+func (h WorkspaceHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var request MemberRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	member, err := h.workspaceService.AddMember(chi.URLParam(r, "workspace"), request.Email, request.Role)
+	if err != nil {
+		respondWithWorkspaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, member)
+}
+
+// UpdateMember обрабатывает PATCH-запрос владельца на изменение роли участника рабочего пространства.
+func (h WorkspaceHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	var request MemberRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedReadRequestBody, err.Error())
+		return
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, FailedUnmarshall, err.Error())
+		return
+	}
+
+	member, err := h.workspaceService.ChangeRole(chi.URLParam(r, "workspace"), chi.URLParam(r, "user"), request.Role)
+	if err != nil {
+		respondWithWorkspaceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, member)
+}
+
+// RemoveMember обрабатывает DELETE-запрос на исключение участника из рабочего пространства.
+// Владелец может исключить любого участника, остальные участники — только покинуть пространство сами.
+func (h WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := requestUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(chi.URLParam(r, "workspace"), actorID, chi.URLParam(r, "user")); err != nil {
+		respondWithWorkspaceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestUserID возвращает идентификатор пользователя из контекста запроса.
+// Если идентификатора нет, отправляет ответ 401 и возвращает false.
+func requestUserID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, _ := r.Context().Value(middleware.UserIDContextKey).(string)
+	if userID == "" {
+		respondWithError(w, http.StatusUnauthorized, Unauthorized, ErrUserIDMissing.Error())
+		return "", false
+	}
+
+	return userID, true
+}
+
+// respondWithWorkspaceError отправляет ответ с кодом статуса, соответствующим ошибке сервиса рабочих пространств.
+func respondWithWorkspaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, workspace.ErrNameEmpty), errors.Is(err, workspace.ErrNameTooLong),
+		errors.Is(err, workspace.ErrRoleInvalid), errors.Is(err, auth.ErrEmailInvalid):
+		respondWithError(w, http.StatusBadRequest, BadRequest, err.Error())
+	case errors.Is(err, workspace.ErrAccountRequired), errors.Is(err, workspace.ErrOwnerRoleRequired):
+		respondWithError(w, http.StatusForbidden, Forbidden, err.Error())
+	case errors.Is(err, workspace.ErrUserNotRegistered), errors.Is(err, storage.ErrMemberNotFound),
+		errors.Is(err, storage.ErrWorkspaceNotFound):
+		respondWithError(w, http.StatusNotFound, "not found", err.Error())
+	case errors.Is(err, storage.ErrLastOwner), errors.Is(err, storage.ErrMemberExists):
+		respondWithError(w, http.StatusConflict, Conflict, err.Error())
+	default:
+		slog.Error("Workspace request failed", slog.String("error", err.Error()))
+		respondWithError(w, http.StatusInternalServerError, "", err.Error())
+	}
+}
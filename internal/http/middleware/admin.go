@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/spf13/viper"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// AdminTokenHeader содержит заголовок, в котором передаётся токен администратора.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminMiddleware пропускает запрос дальше, только если он выполнен администратором:
+// с токеном доступа пользователя с ролью admin или с заголовком X-Admin-Token, совпадающим с ADMIN_TOKEN из конфигурации.
+// Если ADMIN_TOKEN не задан, доступ по заголовку X-Admin-Token закрыт. Запросы с API-ключом отклоняются.
+func AdminMiddleware(sessions Sessions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := r.Header.Get(AdminTokenHeader); token != "" {
+				adminToken := viper.GetString("ADMIN_TOKEN")
+				if adminToken == "" {
+					http.Error(w, "admin API is disabled", http.StatusForbidden)
+					return
+				}
+
+				if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+					slog.Warn("Invalid admin token", slog.String("remoteAddr", r.RemoteAddr))
+					http.Error(w, "invalid admin token", http.StatusUnauthorized)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, ok := userIDFromContext(r.Context()); ok {
+				http.Error(w, "admin API is not available with an API key", http.StatusForbidden)
+				return
+			}
+
+			claims, err := authenticate(w, r, sessions)
+			if err != nil {
+				if errors.Is(err, ErrSessionCheck) {
+					slog.Error("Failed to check session", slog.String("error", err.Error()))
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if claims.Role != entity.RoleAdmin {
+				slog.Warn("Admin API access denied", slog.String("userID", claims.UserID))
+				http.Error(w, "admin role required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(claims.withContext(r.Context())))
+		})
+	}
+}
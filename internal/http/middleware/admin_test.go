@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// TestAdminMiddleware проверяет доступ к административным методам по роли из токена и по токену администратора.
+func TestAdminMiddleware(t *testing.T) {
+	SetKeyset(NewKeyset("test-secret"))
+	t.Cleanup(func() { SetKeyset(nil) })
+
+	viper.Set("ADMIN_TOKEN", "static-admin-token")
+	t.Cleanup(func() { viper.Set("ADMIN_TOKEN", "") })
+
+	session := entity.NewSession("owner", "", time.Now().Add(time.Hour))
+	sessions := &fakeSessions{active: map[string]bool{session.ID.String(): true}}
+
+	signToken := func(role string) string {
+		claims := jwt.MapClaims{"user_id": "owner", "sid": session.ID.String(), "exp": time.Now().Add(time.Minute).Unix()}
+		if role != "" {
+			claims["role"] = role
+		}
+
+		token, err := currentKeyset().Sign(claims)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		apiKey         bool
+		wantStatusCode int
+	}{
+		{
+			name:           "admin_role",
+			headers:        map[string]string{"Authorization": "Bearer " + signToken(entity.RoleAdmin)},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "user_role",
+			headers:        map[string]string{"Authorization": "Bearer " + signToken(entity.RoleUser)},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "token_without_role",
+			headers:        map[string]string{"Authorization": "Bearer " + signToken("")},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "without_token",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "static_admin_token",
+			headers:        map[string]string{AdminTokenHeader: "static-admin-token"},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "invalid_static_admin_token",
+			headers:        map[string]string{AdminTokenHeader: "guess"},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "api_key",
+			apiKey:         true,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AdminMiddleware(sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/urls", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if tt.apiKey {
+				ctx := context.WithValue(req.Context(), UserIDContextKey, "owner")
+				req = req.WithContext(context.WithValue(ctx, ScopesContextKey, []string{entity.ScopeLinksRead}))
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			if rw.Code != tt.wantStatusCode {
+				t.Errorf("expected status: got %v, want %v", rw.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// APIKeyHeader содержит заголовок, в котором машинные клиенты передают API-ключ.
+	APIKeyHeader = "X-API-Key"
+
+	// apiKeyAuthScheme — схема заголовка Authorization для передачи API-ключа.
+	apiKeyAuthScheme = "ApiKey "
+
+	// ScopesContextKey хранит области доступа API-ключа, которым аутентифицирован запрос.
+	ScopesContextKey contextUserIDKey = "scopes"
+)
+
+// APIKeyAuthenticator проверяет API-ключ и возвращает идентификатор владельца и области доступа ключа.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(rawKey string) (string, []string, error)
+}
+
+// APIKeyMiddleware аутентифицирует запрос по API-ключу из заголовка X-API-Key или Authorization: ApiKey.
+// Идентификатор владельца и области доступа ключа сохраняются в контексте запроса;
+// AuthMiddleware и AuthCheckMiddleware такой запрос не перепроверяют. Запросы без API-ключа передаются дальше без изменений.
+func APIKeyMiddleware(keys APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rawKey := apiKeyFromRequest(r)
+			if rawKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, scopes, err := keys.AuthenticateAPIKey(rawKey)
+			if err != nil {
+				slog.Warn("Invalid API key", slog.String("remoteAddr", r.RemoteAddr))
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDContextKey, userID)
+			ctx = context.WithValue(ctx, ScopesContextKey, scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ScopeMiddleware пропускает запрос, аутентифицированный API-ключом, только если ключу разрешена область доступа scope.
+// Запросам с токеном пользователя доступны все области.
+func ScopeMiddleware(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := r.Context().Value(ScopesContextKey).([]string)
+			if ok && !slices.Contains(scopes, scope) {
+				http.Error(w, "API key lacks the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionOnlyMiddleware отклоняет запросы, аутентифицированные API-ключом.
+// Используется для методов, которые должны быть доступны только самому пользователю, например управления ключами.
+func SessionOnlyMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value(ScopesContextKey).([]string); ok {
+				http.Error(w, "this method is not available with an API key", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiKeyFromRequest извлекает API-ключ из заголовка X-API-Key или Authorization со схемой ApiKey.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, apiKeyAuthScheme) {
+		return strings.TrimSpace(strings.TrimPrefix(authHeader, apiKeyAuthScheme))
+	}
+
+	return ""
+}
+
+// userIDFromContext возвращает идентификатор пользователя, если запрос уже аутентифицирован.
+func userIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(UserIDContextKey).(string)
+	return userID, ok && userID != ""
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAPIKeys принимает единственный ключ с заданными областями доступа.
+type fakeAPIKeys struct {
+	key    string
+	scopes []string
+}
+
+func (f fakeAPIKeys) AuthenticateAPIKey(rawKey string) (string, []string, error) {
+	if rawKey != f.key {
+		return "", nil, errors.New("API key is invalid or revoked")
+	}
+	return "owner", f.scopes, nil
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	keys := fakeAPIKeys{key: "sk_valid", scopes: []string{"links:read"}}
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		scope          string
+		sessionOnly    bool
+		wantStatusCode int
+		wantUserID     string
+	}{
+		{
+			name:           "x_api_key_header",
+			headers:        map[string]string{APIKeyHeader: "sk_valid"},
+			scope:          "links:read",
+			wantStatusCode: http.StatusOK,
+			wantUserID:     "owner",
+		},
+		{
+			name:           "authorization_api_key_scheme",
+			headers:        map[string]string{"Authorization": "ApiKey sk_valid"},
+			scope:          "links:read",
+			wantStatusCode: http.StatusOK,
+			wantUserID:     "owner",
+		},
+		{
+			name:           "missing_scope",
+			headers:        map[string]string{APIKeyHeader: "sk_valid"},
+			scope:          "links:delete",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "invalid_key",
+			headers:        map[string]string{APIKeyHeader: "sk_invalid"},
+			scope:          "links:read",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "session_only_method",
+			headers:        map[string]string{APIKeyHeader: "sk_valid"},
+			sessionOnly:    true,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "request_without_key_has_all_scopes",
+			scope:          "links:delete",
+			sessionOnly:    true,
+			wantStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = userIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			if tt.sessionOnly {
+				handler = SessionOnlyMiddleware()(handler)
+			}
+			if tt.scope != "" {
+				handler = ScopeMiddleware(tt.scope)(handler)
+			}
+			handler = APIKeyMiddleware(keys)(handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Errorf("expected status %d, got %d", tt.wantStatusCode, rec.Code)
+			}
+
+			if gotUserID != tt.wantUserID {
+				t.Errorf("expected user ID %q, got %q", tt.wantUserID, gotUserID)
+			}
+		})
+	}
+}
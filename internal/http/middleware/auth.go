@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -10,13 +11,23 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/spf13/viper"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
 )
 
 type contextUserIDKey string
 
 const (
 	UserIDContextKey contextUserIDKey = "user_id"
+
+	// SessionIDContextKey хранит идентификатор сессии, в которой выпущен токен доступа запроса.
+	SessionIDContextKey contextUserIDKey = "session_id"
+
+	// RoleContextKey хранит роль пользователя из токена доступа запроса.
+	RoleContextKey contextUserIDKey = "role"
+
+	// AuthTokenCookie — кука с токеном доступа.
+	AuthTokenCookie = "auth_token"
 )
 
 var (
@@ -25,118 +36,230 @@ var (
 	ErrInvalidToken         = errors.New("invalid token")
 	ErrUserIDMissingInToken = errors.New("userID missing in token claims")
 	ErrInvalidTokenClaims   = errors.New("invalid token claims")
+	ErrSessionRevoked       = errors.New("session is revoked or expired")
+	ErrSessionCheck         = errors.New("failed to check session")
 )
 
-// AuthMiddleware создает новый токен и устанавливает его в заголовок Authorization и в куку, если токен отсутствует или недействителен.
-func AuthMiddleware() func(http.Handler) http.Handler {
+// AuthMiddleware аутентифицирует запрос по токену доступа или refresh-токену из куки.
+// Если ни один из них не действителен, создаёт анонимного пользователя, начинает для него сессию
+// и устанавливает токены в заголовок Authorization и в куки.
+func AuthMiddleware(sessions Sessions) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			jwtSecret := viper.GetString("JWT_SECRET")
-			userID, err := validateAuthTokenFromRequest(r, jwtSecret)
+			if _, ok := userIDFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := authenticate(w, r, sessions)
+
+			if errors.Is(err, ErrSessionCheck) {
+				slog.Error("Failed to check session", slog.String("error", err.Error()))
+				http.Error(w, `{"error":"Failed to check session"}`, http.StatusInternalServerError)
+				return
+			}
 
 			if err != nil {
-				userID = uuid.New().String()
-				token, err := generateAuthToken(userID, jwtSecret)
+				userID := uuid.New().String()
+				session, tokens, err := StartSession(w, sessions, userID)
 				if err != nil {
 					slog.Error("Failed to generate auth token", slog.String("error", err.Error()))
 					http.Error(w, `{"error":"Failed to generate auth token"}`, http.StatusInternalServerError)
 					return
 				}
 
-				http.SetCookie(w, &http.Cookie{
-					Name:     "auth_token",
-					Value:    token,
-					Path:     "/",
-					HttpOnly: true,
-					SameSite: http.SameSiteStrictMode,
-					Secure:   false,
-				})
-
-				w.Header().Set("Authorization", "Bearer "+token)
-				slog.Info("Generated new auth token for POST request", slog.String("userID", userID))
+				claims = authClaims{UserID: userID, SessionID: session.ID.String(), Role: tokens.Role}
+				slog.Info("Started anonymous session", slog.String("userID", userID), slog.String("sessionID", claims.SessionID))
 			} else {
-				slog.Info("Valid token found", slog.String("userID", userID))
+				slog.Info("Valid token found", slog.String("userID", claims.UserID))
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDContextKey, userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(claims.withContext(r.Context())))
 		})
 	}
 }
 
-// AuthCheckMiddleware проверяет наличие и валидность токена в заголовке Authorization или куке и передает запрос дальше.
-func AuthCheckMiddleware() func(http.Handler) http.Handler {
+// SetAuthToken выпускает токен доступа для userID с ролью role в сессии sessionID
+// и устанавливает его в заголовок Authorization и в куку auth_token. Возвращает выпущенный токен.
+func SetAuthToken(w http.ResponseWriter, userID, sessionID, role string) (string, error) {
+	token, err := generateAuthToken(userID, sessionID, role)
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     AuthTokenCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   false,
+	})
+
+	w.Header().Set("Authorization", "Bearer "+token)
+	return token, nil
+}
+
+// AuthCheckMiddleware проверяет токен доступа в заголовке Authorization или куке и то, что его сессия не отозвана.
+// Если токен истёк, но в куке есть действительный refresh-токен, сессия продлевается и выпускаются новые токены.
+func AuthCheckMiddleware(sessions Sessions) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			jwtSecret := viper.GetString("JWT_SECRET")
+			if _, ok := userIDFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-			userID, err := validateAuthTokenFromRequest(r, jwtSecret)
+			claims, err := authenticate(w, r, sessions)
 			if err != nil {
+				if errors.Is(err, ErrSessionCheck) {
+					slog.Error("Failed to check session", slog.String("error", err.Error()))
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
 				slog.Warn("Missing or invalid token")
 				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDContextKey, userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(claims.withContext(r.Context())))
 		})
 	}
 }
 
+// UserIDFromRequest возвращает идентификатор пользователя из действительного токена в заголовке Authorization или куке,
+// если сессия токена не отозвана. Истёкший токен не продлевается.
+func UserIDFromRequest(r *http.Request, sessions Sessions) (string, error) {
+	claims, err := validateAuthTokenFromRequest(r)
+	if err != nil {
+		return "", err
+	}
+
+	if claims.SessionID == "" {
+		return "", ErrSessionRevoked
+	}
+
+	active, err := sessions.IsSessionActive(claims.SessionID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSessionCheck, err)
+	}
+
+	if !active {
+		return "", ErrSessionRevoked
+	}
+
+	return claims.UserID, nil
+}
+
+// authenticate возвращает пользователя и сессию запроса по токену доступа.
+// Если токен недействителен или его сессия отозвана, пробует продлить сессию refresh-токеном из куки
+// и устанавливает в ответ новые токены. Ошибка проверки сессии в хранилище оборачивается в ErrSessionCheck.
+func authenticate(w http.ResponseWriter, r *http.Request, sessions Sessions) (authClaims, error) {
+	claims, err := validateAuthTokenFromRequest(r)
+	// Токен без сессии нельзя отозвать, поэтому он не принимается: клиент продлевает сессию refresh-токеном.
+	if err == nil && claims.SessionID != "" {
+		active, err := sessions.IsSessionActive(claims.SessionID)
+		if err != nil {
+			return authClaims{}, fmt.Errorf("%w: %v", ErrSessionCheck, err)
+		}
+
+		if active {
+			return claims, nil
+		}
+	}
+
+	if err == nil {
+		err = ErrSessionRevoked
+	}
+
+	cookie, cookieErr := r.Cookie(RefreshTokenCookie)
+	if cookieErr != nil || cookie.Value == "" {
+		return authClaims{}, err
+	}
+
+	session, tokens, err := RefreshSession(w, sessions, cookie.Value)
+	if err != nil {
+		slog.Warn("Failed to refresh session", slog.String("error", err.Error()))
+		ClearSessionTokens(w)
+		return authClaims{}, err
+	}
+
+	slog.Info("Session refreshed", slog.String("userID", session.UserID))
+	return authClaims{UserID: session.UserID, SessionID: session.ID.String(), Role: tokens.Role}, nil
+}
+
 // validateAuthTokenFromRequest извлекает и проверяет токен из заголовка Authorization или куки.
-func validateAuthTokenFromRequest(r *http.Request, secret string) (string, error) {
+func validateAuthTokenFromRequest(r *http.Request) (authClaims, error) {
 	authHeader := r.Header.Get("Authorization")
 	var tokenStr string
 
 	if authHeader != "" {
 		tokenStr = strings.TrimPrefix(authHeader, "Bearer ")
 	} else {
-		cookie, err := r.Cookie("auth_token")
+		cookie, err := r.Cookie(AuthTokenCookie)
 		if err == nil && cookie.Value != "" {
 			tokenStr = cookie.Value
 		} else {
-			return "", ErrMissingAuthToken
+			return authClaims{}, ErrMissingAuthToken
 		}
 	}
 
-	return validateAuthToken(tokenStr, secret)
+	return validateAuthToken(tokenStr)
 }
 
-// validateAuthToken проверяет валидность токена и извлекает userID.
-func validateAuthToken(tokenStr, secret string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrUnexpectedSignMethod
-		}
-		return []byte(secret), nil
-	})
+// authClaims содержит сведения о пользователе из токена доступа.
+type authClaims struct {
+	UserID    string
+	SessionID string
+	Role      string
+}
+
+// withContext сохраняет сведения о пользователе в контексте запроса.
+func (c authClaims) withContext(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, UserIDContextKey, c.UserID)
+	ctx = context.WithValue(ctx, SessionIDContextKey, c.SessionID)
+	return context.WithValue(ctx, RoleContextKey, c.Role)
+}
+
+// validateAuthToken проверяет валидность токена ключом из набора ключей и извлекает сведения о пользователе.
+// У токенов, выпущенных до появления ролей, роль считается обычной.
+func validateAuthToken(tokenStr string) (authClaims, error) {
+	token, err := jwt.Parse(tokenStr, currentKeyset().Keyfunc)
 
 	if err != nil || !token.Valid {
-		return "", ErrInvalidToken
+		return authClaims{}, ErrInvalidToken
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		userID, ok := claims["user_id"].(string)
 		if !ok || userID == "" {
-			return "", ErrUserIDMissingInToken
+			return authClaims{}, ErrUserIDMissingInToken
+		}
+
+		sessionID, _ := claims["sid"].(string)
+
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = entity.RoleUser
 		}
-		return userID, nil
+
+		return authClaims{UserID: userID, SessionID: sessionID, Role: role}, nil
 	}
 
-	return "", ErrInvalidTokenClaims
+	return authClaims{}, ErrInvalidTokenClaims
 }
 
-// generateAuthToken создает новый токен с userID.
-func generateAuthToken(userID, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// generateAuthToken создает новый токен доступа с userID, идентификатором сессии и ролью пользователя,
+// подписанный ключом подписи из набора ключей.
+func generateAuthToken(userID, sessionID, role string) (string, error) {
+	now := time.Now()
+
+	return currentKeyset().Sign(jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"sid":     sessionID,
+		"role":    role,
+		"iat":     now.Unix(),
+		"exp":     now.Add(AccessTokenTTL).Unix(),
 	})
-
-	signedToken, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", err
-	}
-
-	return signedToken, nil
 }
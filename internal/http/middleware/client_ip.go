@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// ClientIPContextKey хранит IP-адрес клиента, отправившего запрос.
+const ClientIPContextKey contextUserIDKey = "client_ip"
+
+// ClientIP сохраняет в контексте запроса IP-адрес клиента из адреса соединения без порта.
+func ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ClientIPContextKey, ip)))
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestClientIP проверяет, что в контекст запроса попадает IP-адрес клиента без порта.
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantIP     string
+	}{
+		{
+			name:       "ipv4_with_port",
+			remoteAddr: "203.0.113.7:54321",
+			wantIP:     "203.0.113.7",
+		},
+		{
+			name:       "ipv6_with_port",
+			remoteAddr: "[2001:db8::1]:54321",
+			wantIP:     "2001:db8::1",
+		},
+		{
+			name:       "without_port",
+			remoteAddr: "203.0.113.7",
+			wantIP:     "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIP interface{}
+			handler := ClientIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotIP = r.Context().Value(ClientIPContextKey)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotIP != tt.wantIP {
+				t.Errorf("expected client IP in context: got %v, want %v", gotIP, tt.wantIP)
+			}
+		})
+	}
+}
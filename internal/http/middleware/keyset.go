@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// SecretKeyID — идентификатор ключа HS256 из JWT_SECRET.
+// Токены без заголовка kid, выпущенные до появления набора ключей, проверяются этим ключом.
+const SecretKeyID = "secret"
+
+var (
+	ErrKeyNotFound    = errors.New("signing key not found")
+	ErrKeyCannotSign  = errors.New("key has no private part and can only verify tokens")
+	ErrKeyDuplicate   = errors.New("duplicate key id")
+	ErrKeyUnsupported = errors.New("unsupported key, expected an RSA or Ed25519 key in PEM format")
+	ErrNoSigningKey   = errors.New("no signing key configured, set JWT_SECRET or a PEM signing key")
+	ErrUnknownKeyID   = errors.New("unknown key id")
+)
+
+// keyset — набор ключей, которым подписываются и проверяются токены; устанавливается при запуске через SetKeyset.
+var keyset atomic.Pointer[Keyset]
+
+// jwtKey — ключ подписи или проверки токенов.
+type jwtKey struct {
+	method    jwt.SigningMethod
+	signKey   interface{} // nil, если ключ только проверяет подпись
+	verifyKey interface{}
+	public    bool // Публикуется ли ключ в JWKS; симметричные ключи не публикуются
+}
+
+// Keyset хранит ключи проверки токенов по идентификатору kid и ключ, которым подписываются новые токены.
+// Несколько ключей позволяют сменить ключ подписи, не отзывая уже выпущенные токены.
+type Keyset struct {
+	keys       map[string]jwtKey
+	signingKID string
+}
+
+// NewKeyset создает набор ключей с ключом HS256 из secret, который используется и для подписи.
+// Если secret пуст, набор создается без ключей.
+func NewKeyset(secret string) *Keyset {
+	ks := &Keyset{keys: make(map[string]jwtKey)}
+
+	if secret != "" {
+		ks.keys[SecretKeyID] = jwtKey{method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+		ks.signingKID = SecretKeyID
+	}
+
+	return ks
+}
+
+// LoadKeyset создает набор ключей из secret и PEM-файлов ключей RSA или Ed25519.
+// Идентификатором ключа служит имя файла без расширения. Ключ подписи задаётся signingKID;
+// если он пуст, токены подписываются ключом из secret.
+func LoadKeyset(secret string, files []string, signingKID string) (*Keyset, error) {
+	ks := NewKeyset(secret)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if err := ks.AddPEM(kid, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	if signingKID != "" {
+		if err := ks.SetSigningKey(signingKID); err != nil {
+			return nil, fmt.Errorf("%s: %w", signingKID, err)
+		}
+	}
+
+	if ks.signingKID == "" {
+		return nil, ErrNoSigningKey
+	}
+
+	return ks, nil
+}
+
+// AddPEM добавляет ключ RSA (RS256) или Ed25519 (EdDSA) в формате PEM.
+// Закрытый ключ может подписывать токены, открытый — только проверять.
+func (ks *Keyset) AddPEM(kid string, data []byte) error {
+	if _, ok := ks.keys[kid]; ok || kid == SecretKeyID {
+		return fmt.Errorf("%w: %s", ErrKeyDuplicate, kid)
+	}
+
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		ks.keys[kid] = jwtKey{method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey, public: true}
+		return nil
+	}
+
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		if private, ok := key.(ed25519.PrivateKey); ok {
+			ks.keys[kid] = jwtKey{method: jwt.SigningMethodEdDSA, signKey: private, verifyKey: private.Public(), public: true}
+			return nil
+		}
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		ks.keys[kid] = jwtKey{method: jwt.SigningMethodRS256, verifyKey: key, public: true}
+		return nil
+	}
+
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		if public, ok := key.(ed25519.PublicKey); ok {
+			ks.keys[kid] = jwtKey{method: jwt.SigningMethodEdDSA, verifyKey: public, public: true}
+			return nil
+		}
+	}
+
+	return ErrKeyUnsupported
+}
+
+// SetSigningKey назначает ключ, которым подписываются новые токены.
+func (ks *Keyset) SetSigningKey(kid string) error {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	if key.signKey == nil {
+		return ErrKeyCannotSign
+	}
+
+	ks.signingKID = kid
+	return nil
+}
+
+// Sign подписывает claims ключом подписи и указывает его идентификатор в заголовке kid.
+func (ks *Keyset) Sign(claims jwt.Claims) (string, error) {
+	key, ok := ks.keys[ks.signingKID]
+	if !ok {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = ks.signingKID
+
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc возвращает ключ проверки по заголовку kid токена.
+// Токены без kid проверяются ключом из JWT_SECRET. Алгоритм токена должен совпадать с алгоритмом ключа.
+func (ks *Keyset) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = SecretKeyID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, ErrUnexpectedSignMethod
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWK — открытый ключ в формате JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet — набор открытых ключей в формате JWKS.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи проверки токенов, отсортированные по идентификатору.
+// Симметричный ключ из JWT_SECRET не публикуется.
+func (ks *Keyset) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.keys))}
+
+	for kid, key := range ks.keys {
+		if !key.public {
+			continue
+		}
+
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// SetKeyset устанавливает набор ключей для выпуска и проверки токенов.
+func SetKeyset(ks *Keyset) {
+	keyset.Store(ks)
+}
+
+// JWKS возвращает открытые ключи текущего набора ключей.
+func JWKS() JWKSet {
+	return currentKeyset().JWKS()
+}
+
+// currentKeyset возвращает установленный набор ключей.
+// Пока набор не установлен, используется ключ из JWT_SECRET.
+func currentKeyset() *Keyset {
+	if ks := keyset.Load(); ks != nil {
+		return ks
+	}
+	return NewKeyset(viper.GetString("JWT_SECRET"))
+}
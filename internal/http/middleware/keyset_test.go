@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM сохраняет ключ в PEM-файл во временном каталоге и возвращает путь к файлу.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+// testKeyFiles создает закрытые ключи RSA и Ed25519 и открытый ключ Ed25519 в формате PEM.
+func testKeyFiles(t *testing.T) (rsaFile, edFile, edPublicFile string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	rsaDER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+
+	_, otherPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	otherPublicDER, _ := x509.MarshalPKIXPublicKey(otherPrivate.Public())
+
+	return writePEM(t, dir, "rsa-2024.pem", "PRIVATE KEY", rsaDER),
+		writePEM(t, dir, "ed-2025.pem", "PRIVATE KEY", edDER),
+		writePEM(t, dir, "partner.pem", "PUBLIC KEY", otherPublicDER)
+}
+
+func TestKeysetRotation(t *testing.T) {
+	rsaFile, edFile, edPublicFile := testKeyFiles(t)
+	claims := jwt.MapClaims{"user_id": "user", "exp": time.Now().Add(time.Hour).Unix()}
+
+	legacy, err := NewKeyset("secret").Sign(claims)
+	if err != nil {
+		t.Fatalf("failed to sign legacy token: %v", err)
+	}
+
+	oldKeys, err := LoadKeyset("secret", []string{rsaFile}, "rsa-2024")
+	if err != nil {
+		t.Fatalf("failed to load keyset: %v", err)
+	}
+
+	oldToken, err := oldKeys.Sign(claims)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// После смены ключа подписи старые токены продолжают проверяться.
+	newKeys, err := LoadKeyset("secret", []string{rsaFile, edFile, edPublicFile}, "ed-2025")
+	if err != nil {
+		t.Fatalf("failed to load keyset: %v", err)
+	}
+
+	newToken, err := newKeys.Sign(claims)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// Токен HS256 с kid ключа RSA не должен приниматься.
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	confused.Header["kid"] = "rsa-2024"
+	confusedToken, _ := confused.SignedString([]byte("secret"))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantKID string
+		wantErr bool
+	}{
+		{name: "legacy_token_without_kid", token: legacy, wantKID: SecretKeyID},
+		{name: "token_of_previous_signing_key", token: oldToken, wantKID: "rsa-2024"},
+		{name: "token_of_current_signing_key", token: newToken, wantKID: "ed-2025"},
+		{name: "algorithm_does_not_match_key", token: confusedToken, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.Parse(tt.token, newKeys.Keyfunc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected token to be rejected")
+				}
+				return
+			}
+
+			if err != nil || !token.Valid {
+				t.Fatalf("expected valid token, got %v", err)
+			}
+
+			if kid, _ := token.Header["kid"].(string); kid != tt.wantKID && !(kid == "" && tt.wantKID == SecretKeyID) {
+				t.Errorf("unexpected kid: got %q, want %q", kid, tt.wantKID)
+			}
+		})
+	}
+
+	if _, err := LoadKeyset("secret", []string{edPublicFile}, "partner"); !errors.Is(err, ErrKeyCannotSign) {
+		t.Errorf("expected public key to be rejected as signing key, got %v", err)
+	}
+
+	if _, err := LoadKeyset("", nil, ""); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("expected error without signing key, got %v", err)
+	}
+
+	jwks := newKeys.JWKS()
+	if len(jwks.Keys) != 3 {
+		t.Fatalf("expected 3 public keys in JWKS, got %d", len(jwks.Keys))
+	}
+
+	for _, key := range jwks.Keys {
+		if key.Kid == SecretKeyID {
+			t.Errorf("symmetric key must not be published")
+		}
+		if key.Kid == "rsa-2024" && (key.Kty != "RSA" || key.Alg != "RS256" || key.N == "" || key.E != "AQAB") {
+			t.Errorf("unexpected RSA key: %+v", key)
+		}
+		if key.Kid == "ed-2025" && (key.Kty != "OKP" || key.Crv != "Ed25519" || key.Alg != "EdDSA" || key.X == "") {
+			t.Errorf("unexpected Ed25519 key: %+v", key)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+const (
+	// AccessTokenTTL — время жизни токена доступа. Истёкший токен обменивается на новый по refresh-токену.
+	AccessTokenTTL = 15 * time.Minute
+
+	// RefreshTokenCookie — кука с refresh-токеном.
+	RefreshTokenCookie = "refresh_token"
+)
+
+// Sessions создает, продлевает и проверяет сессии пользователей.
+type Sessions interface {
+	// StartSession создает сессию пользователя и возвращает её вместе с refresh-токеном.
+	StartSession(userID string) (*entity.Session, string, error)
+	// RefreshSession обменивает refresh-токен на новый и возвращает продлённую сессию.
+	RefreshSession(refreshToken string) (*entity.Session, string, error)
+	// IsSessionActive сообщает, что сессия не отозвана и не истекла.
+	IsSessionActive(sessionID string) (bool, error)
+	// UserRole возвращает роль пользователя для токена доступа.
+	UserRole(userID string) (string, error)
+}
+
+// Tokens — токены, выданные клиенту для сессии.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	Role         string // Роль пользователя, записанная в токен доступа
+}
+
+// StartSession начинает сессию пользователя, выпускает для неё токен доступа и refresh-токен
+// и устанавливает их в ответ.
+func StartSession(w http.ResponseWriter, sessions Sessions, userID string) (*entity.Session, Tokens, error) {
+	session, refreshToken, err := sessions.StartSession(userID)
+	if err != nil {
+		return nil, Tokens{}, err
+	}
+
+	tokens, err := setSessionTokens(w, sessions, session, refreshToken)
+	if err != nil {
+		return nil, Tokens{}, err
+	}
+
+	return session, tokens, nil
+}
+
+// RefreshSession обменивает refresh-токен на новую пару токенов и устанавливает их в ответ.
+func RefreshSession(w http.ResponseWriter, sessions Sessions, refreshToken string) (*entity.Session, Tokens, error) {
+	session, refreshToken, err := sessions.RefreshSession(refreshToken)
+	if err != nil {
+		return nil, Tokens{}, err
+	}
+
+	tokens, err := setSessionTokens(w, sessions, session, refreshToken)
+	if err != nil {
+		return nil, Tokens{}, err
+	}
+
+	return session, tokens, nil
+}
+
+// ClearSessionTokens удаляет куки с токеном доступа и refresh-токеном.
+func ClearSessionTokens(w http.ResponseWriter) {
+	for _, name := range []string{AuthTokenCookie, RefreshTokenCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
+
+// setSessionTokens выпускает токен доступа сессии с текущей ролью пользователя
+// и устанавливает его вместе с refresh-токеном в ответ.
+// Кука refresh-токена живёт столько же, сколько сессия, чтобы браузер сохранял её между перезапусками.
+func setSessionTokens(w http.ResponseWriter, sessions Sessions, session *entity.Session, refreshToken string) (Tokens, error) {
+	role, err := sessions.UserRole(session.UserID)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	accessToken, err := SetAuthToken(w, session.UserID, session.ID.String(), role)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookie,
+		Value:    refreshToken,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   false,
+	})
+
+	return Tokens{AccessToken: accessToken, RefreshToken: refreshToken, Role: role}, nil
+}
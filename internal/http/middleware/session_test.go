@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// fakeSessions хранит состояние сессий и принимает единственный refresh-токен.
+type fakeSessions struct {
+	active       map[string]bool
+	refreshToken string
+	session      *entity.Session
+}
+
+func (f *fakeSessions) StartSession(userID string) (*entity.Session, string, error) {
+	return entity.NewSession(userID, "", time.Now().Add(time.Hour)), "new-refresh", nil
+}
+
+func (f *fakeSessions) RefreshSession(refreshToken string) (*entity.Session, string, error) {
+	if refreshToken != f.refreshToken {
+		return nil, "", errors.New("refresh token is invalid, expired or revoked")
+	}
+	return f.session, "rotated-refresh", nil
+}
+
+func (f *fakeSessions) IsSessionActive(sessionID string) (bool, error) {
+	return f.active[sessionID], nil
+}
+
+func (f *fakeSessions) UserRole(userID string) (string, error) {
+	return entity.RoleUser, nil
+}
+
+// TestAuthCheckMiddlewareSessions проверяет отклонение токенов отозванных сессий
+// и продление сессии по refresh-токену из куки.
+func TestAuthCheckMiddlewareSessions(t *testing.T) {
+	SetKeyset(NewKeyset("test-secret"))
+	t.Cleanup(func() { SetKeyset(nil) })
+
+	session := entity.NewSession("owner", "", time.Now().Add(time.Hour))
+	revoked := entity.NewSession("owner", "", time.Now().Add(time.Hour))
+
+	sessions := &fakeSessions{
+		active:       map[string]bool{session.ID.String(): true},
+		refreshToken: "valid-refresh",
+		session:      session,
+	}
+
+	signToken := func(sessionID string, exp time.Time) string {
+		token, err := currentKeyset().Sign(jwt.MapClaims{"user_id": "owner", "sid": sessionID, "exp": exp.Unix()})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name           string
+		accessToken    string
+		refreshToken   string
+		wantStatusCode int
+		wantRefreshed  bool
+	}{
+		{
+			name:           "active_session",
+			accessToken:    signToken(session.ID.String(), time.Now().Add(time.Minute)),
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "revoked_session",
+			accessToken:    signToken(revoked.ID.String(), time.Now().Add(time.Minute)),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "token_without_session",
+			accessToken:    signToken("", time.Now().Add(time.Minute)),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "token_without_session_with_refresh_token",
+			accessToken:    signToken("", time.Now().Add(time.Minute)),
+			refreshToken:   "valid-refresh",
+			wantStatusCode: http.StatusOK,
+			wantRefreshed:  true,
+		},
+		{
+			name:           "expired_token_without_refresh_token",
+			accessToken:    signToken(session.ID.String(), time.Now().Add(-time.Minute)),
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "expired_token_with_refresh_token",
+			accessToken:    signToken(session.ID.String(), time.Now().Add(-time.Minute)),
+			refreshToken:   "valid-refresh",
+			wantStatusCode: http.StatusOK,
+			wantRefreshed:  true,
+		},
+		{
+			name:           "refresh_token_only",
+			refreshToken:   "valid-refresh",
+			wantStatusCode: http.StatusOK,
+			wantRefreshed:  true,
+		},
+		{
+			name:           "invalid_refresh_token",
+			refreshToken:   "stolen-refresh",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+
+			handler := AuthCheckMiddleware(sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = userIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			if tt.accessToken != "" {
+				req.AddCookie(&http.Cookie{Name: AuthTokenCookie, Value: tt.accessToken})
+			}
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: RefreshTokenCookie, Value: tt.refreshToken})
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			if rw.Code != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", rw.Code, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode == http.StatusOK && gotUserID != "owner" {
+				t.Errorf("unexpected user ID: got %q, want %q", gotUserID, "owner")
+			}
+
+			var gotRefreshToken string
+			for _, cookie := range rw.Result().Cookies() {
+				if cookie.Name == RefreshTokenCookie {
+					gotRefreshToken = cookie.Value
+				}
+			}
+
+			if refreshed := gotRefreshToken == "rotated-refresh"; refreshed != tt.wantRefreshed {
+				t.Errorf("unexpected refresh: got refresh token %q, want refreshed=%v", gotRefreshToken, tt.wantRefreshed)
+			}
+		})
+	}
+}
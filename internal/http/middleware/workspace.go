@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+const (
+	// WorkspaceIDContextKey хранит идентификатор рабочего пространства, в котором выполняется запрос.
+	WorkspaceIDContextKey contextUserIDKey = "workspace_id"
+
+	// WorkspaceRoleContextKey хранит роль пользователя в рабочем пространстве запроса.
+	WorkspaceRoleContextKey contextUserIDKey = "workspace_role"
+)
+
+// WorkspaceMembers определяет проверку членства пользователя в рабочем пространстве.
+type WorkspaceMembers interface {
+	// MemberRole возвращает роль пользователя в рабочем пространстве или пустую строку, если он не участник.
+	MemberRole(workspaceID, userID string) (string, error)
+}
+
+// WorkspaceMiddleware пропускает запрос к рабочему пространству из параметра маршрута {workspace},
+// только если пользователь — его участник с ролью не ниже required.
+// Для посторонних пространство не раскрывается: на запрос возвращается 404.
+// Идентификатор пространства и роль пользователя сохраняются в контексте запроса.
+func WorkspaceMiddleware(members WorkspaceMembers, required string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := userIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			workspaceID := chi.URLParam(r, "workspace")
+
+			role, err := members.MemberRole(workspaceID, userID)
+			if err != nil {
+				slog.Error("Failed to check workspace membership", slog.String("workspaceID", workspaceID), slog.String("error", err.Error()))
+				http.Error(w, "failed to check workspace membership", http.StatusInternalServerError)
+				return
+			}
+
+			if role == "" {
+				http.Error(w, "workspace not found", http.StatusNotFound)
+				return
+			}
+
+			if !entity.RoleAllows(role, required) {
+				http.Error(w, "workspace role "+required+" required", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), WorkspaceIDContextKey, workspaceID)
+			ctx = context.WithValue(ctx, WorkspaceRoleContextKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+// fakeWorkspaceMembers хранит роли участников единственного рабочего пространства.
+type fakeWorkspaceMembers struct {
+	workspaceID string
+	roles       map[string]string
+	err         error
+}
+
+func (f *fakeWorkspaceMembers) MemberRole(workspaceID, userID string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if workspaceID != f.workspaceID {
+		return "", nil
+	}
+	return f.roles[userID], nil
+}
+
+// TestWorkspaceMiddleware проверяет доступ к рабочему пространству в зависимости от роли участника.
+func TestWorkspaceMiddleware(t *testing.T) {
+	members := &fakeWorkspaceMembers{
+		workspaceID: "team",
+		roles: map[string]string{
+			"viewer": entity.RoleViewer,
+			"editor": entity.RoleEditor,
+			"owner":  entity.RoleOwner,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		members        *fakeWorkspaceMembers
+		userID         string
+		workspaceID    string
+		required       string
+		wantStatusCode int
+	}{
+		{
+			name:           "editor_writes",
+			members:        members,
+			userID:         "editor",
+			workspaceID:    "team",
+			required:       entity.RoleEditor,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "owner_writes",
+			members:        members,
+			userID:         "owner",
+			workspaceID:    "team",
+			required:       entity.RoleEditor,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "viewer_writes",
+			members:        members,
+			userID:         "viewer",
+			workspaceID:    "team",
+			required:       entity.RoleEditor,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "stranger",
+			members:        members,
+			userID:         "stranger",
+			workspaceID:    "team",
+			required:       entity.RoleViewer,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "unknown_workspace",
+			members:        members,
+			userID:         "owner",
+			workspaceID:    "other",
+			required:       entity.RoleViewer,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "without_user",
+			members:        members,
+			workspaceID:    "team",
+			required:       entity.RoleViewer,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "storage_error",
+			members:        &fakeWorkspaceMembers{err: errors.New("connection refused")},
+			userID:         "owner",
+			workspaceID:    "team",
+			required:       entity.RoleViewer,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotWorkspaceID, gotRole interface{}
+			handler := WorkspaceMiddleware(tt.members, tt.required)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotWorkspaceID = r.Context().Value(WorkspaceIDContextKey)
+				gotRole = r.Context().Value(WorkspaceRoleContextKey)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/workspaces/"+tt.workspaceID+"/urls", nil)
+
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("workspace", tt.workspaceID)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx)
+			if tt.userID != "" {
+				ctx = context.WithValue(ctx, UserIDContextKey, tt.userID)
+			}
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req.WithContext(ctx))
+
+			if rw.Code != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", rw.Code, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode == http.StatusOK {
+				if gotWorkspaceID != tt.workspaceID {
+					t.Errorf("expected workspace in context: got %v, want %v", gotWorkspaceID, tt.workspaceID)
+				}
+				if gotRole != tt.members.roles[tt.userID] {
+					t.Errorf("expected role in context: got %v, want %v", gotRole, tt.members.roles[tt.userID])
+				}
+			}
+		})
+	}
+}
@@ -3,30 +3,97 @@ package http
 import (
 	"github.com/go-chi/chi/v5"
 
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+	workspace "github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http/handler"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/http/middleware"
 )
 
-func useRoutes(handler handler.Handler) *chi.Mux {
+func useRoutes(handler handler.Handler, authHandler handler.AuthHandler, workspaceHandler handler.WorkspaceHandler) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(
 		middleware.Gzip,
 		middleware.Logger,
+		middleware.ClientIP,
+		middleware.APIKeyMiddleware(authHandler.APIKeys()),
 	)
 
-	r.With(middleware.AuthMiddleware()).Post("/", handler.Post)
+	read := middleware.ScopeMiddleware(entity.ScopeLinksRead)
+	write := middleware.ScopeMiddleware(entity.ScopeLinksWrite)
+	remove := middleware.ScopeMiddleware(entity.ScopeLinksDelete)
+
+	sessions := authHandler.Sessions()
+
+	members := workspaceHandler.Members()
+	viewer := middleware.WorkspaceMiddleware(members, workspace.RoleViewer)
+	editor := middleware.WorkspaceMiddleware(members, workspace.RoleEditor)
+	owner := middleware.WorkspaceMiddleware(members, workspace.RoleOwner)
+
+	r.With(middleware.AuthMiddleware(sessions), write).Post("/", handler.Post)
 	r.Get("/{id}", handler.Get)
+	r.Get("/{id}+", handler.Preview)
 	r.Get("/ping", handler.Ping)
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
 
 	r.Route("/api", func(r chi.Router) {
-		r.With(middleware.AuthMiddleware()).Route("/shorten", func(r chi.Router) {
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/register", authHandler.Register)
+			r.Post("/login", authHandler.Login)
+			r.Post("/refresh", authHandler.Refresh)
+			r.Get("/oidc/login", authHandler.OIDCLogin)
+			r.Get("/oidc/callback", authHandler.OIDCCallback)
+			r.With(middleware.AuthCheckMiddleware(sessions), middleware.SessionOnlyMiddleware()).Group(func(r chi.Router) {
+				r.Post("/logout", authHandler.Logout)
+				r.Post("/logout-all", authHandler.LogoutAll)
+			})
+		})
+		r.With(middleware.AuthMiddleware(sessions), write).Route("/shorten", func(r chi.Router) {
 			r.Post("/", handler.PostAPI)
 			r.Post("/batch", handler.PostBatch)
 		})
-		r.With(middleware.AuthCheckMiddleware()).Route("/user", func(r chi.Router) {
-			r.Get("/urls", handler.GetAll)
-			r.Delete("/urls", handler.Delete)
+		r.With(middleware.AuthCheckMiddleware(sessions)).Route("/user", func(r chi.Router) {
+			r.With(read).Get("/urls", handler.GetAll)
+			r.With(remove).Delete("/urls", handler.Delete)
+			r.With(remove).Post("/urls/restore", handler.Restore)
+			r.With(write).Patch("/urls/{key}", handler.Update)
+			r.With(read).Get("/urls/{key}/stats", handler.Stats)
+			r.With(write).Post("/urls/{key}/tags", handler.AddTags)
+			r.With(write).Delete("/urls/{key}/tags", handler.RemoveTags)
+			r.With(middleware.SessionOnlyMiddleware()).Route("/api-keys", func(r chi.Router) {
+				r.Post("/", authHandler.CreateAPIKey)
+				r.Get("/", authHandler.ListAPIKeys)
+				r.Delete("/{id}", authHandler.RevokeAPIKey)
+			})
+		})
+		r.With(middleware.AuthCheckMiddleware(sessions)).Route("/workspaces", func(r chi.Router) {
+			r.With(middleware.SessionOnlyMiddleware()).Post("/", workspaceHandler.Create)
+			r.Get("/", workspaceHandler.List)
+			r.Route("/{workspace}", func(r chi.Router) {
+				r.With(viewer).Get("/members", workspaceHandler.ListMembers)
+				r.With(owner, middleware.SessionOnlyMiddleware()).Post("/members", workspaceHandler.AddMember)
+				r.With(owner, middleware.SessionOnlyMiddleware()).Patch("/members/{user}", workspaceHandler.UpdateMember)
+				r.With(viewer, middleware.SessionOnlyMiddleware()).Delete("/members/{user}", workspaceHandler.RemoveMember)
+				r.With(editor, write).Post("/shorten", handler.PostAPI)
+				r.With(editor, write).Post("/shorten/batch", handler.PostBatch)
+				r.With(viewer, read).Get("/urls", handler.GetAll)
+				r.With(editor, remove).Delete("/urls", handler.Delete)
+				r.With(editor, remove).Post("/urls/restore", handler.Restore)
+				r.With(editor, write).Patch("/urls/{key}", handler.Update)
+				r.With(viewer, read).Get("/urls/{key}/stats", handler.Stats)
+				r.With(editor, write).Post("/urls/{key}/tags", handler.AddTags)
+				r.With(editor, write).Delete("/urls/{key}/tags", handler.RemoveTags)
+			})
+		})
+		r.With(middleware.AdminMiddleware(sessions)).Route("/admin", func(r chi.Router) {
+			r.Get("/blocklist/matches", handler.BlockedURLs)
+			r.Get("/urls", handler.AdminListURLs)
+			r.Delete("/urls", handler.AdminDelete)
+			r.Post("/urls/restore", handler.AdminRestore)
+			r.Get("/users/{id}/urls", handler.AdminUserURLs)
+			r.Post("/users/{id}/disable", authHandler.DisableUser)
+			r.Post("/users/{id}/enable", authHandler.EnableUser)
+			r.Post("/users/{id}/role", authHandler.SetUserRole)
 		})
 	})
 
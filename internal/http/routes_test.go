@@ -0,0 +1,93 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/auth"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/shortener"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/app/workspace"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/http/handler"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/infra/storage"
+)
+
+// TestPreviewRoutes проверяет через маршрутизатор, что ключ со знаком "+" и параметр preview=1
+// открывают страницу предпросмотра, а обычный переход по ключу отвечает редиректом.
+func TestPreviewRoutes(t *testing.T) {
+	repository := storage.NewShortenerMemory()
+
+	link := entity.NewURL(nil, "https://practicum.yandex.ru/", "abcde")
+	link.Title = "Practicum"
+	if _, err := repository.Create(link); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	keyGenerator, err := valueobject.NewRandomKeyGenerator(valueobject.DefaultKeyLength, valueobject.DefaultKeyAlphabet)
+	if err != nil {
+		t.Fatalf("failed to create key generator: %v", err)
+	}
+
+	domains, err := valueobject.NewDomains("http://localhost:8080", nil)
+	if err != nil {
+		t.Fatalf("failed to create domains: %v", err)
+	}
+
+	shortenerService := shortener.New(repository, domains, keyGenerator, valueobject.DefaultRedirectType, nil, shortener.Quota{})
+	authService := auth.New(storage.NewUserMemory(), storage.NewAPIKeyMemory(), storage.NewSessionMemory(), nil, nil)
+
+	router := useRoutes(
+		handler.New(shortenerService, nil),
+		handler.NewAuth(authService, shortenerService),
+		handler.NewWorkspace(workspace.New(storage.NewWorkspaceMemory(), authService)),
+	)
+
+	tests := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+		wantPreview    bool
+	}{
+		{name: "redirect", path: "/abcde", wantStatusCode: http.StatusTemporaryRedirect},
+		{name: "plus_suffix", path: "/abcde+", wantStatusCode: http.StatusOK, wantPreview: true},
+		{name: "preview_query", path: "/abcde?preview=1", wantStatusCode: http.StatusOK, wantPreview: true},
+		{name: "preview_disabled", path: "/abcde?preview=0", wantStatusCode: http.StatusTemporaryRedirect},
+		{name: "unknown_key_with_plus_suffix", path: "/zzzzz+", wantStatusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = "localhost:8080"
+
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			response := rw.Result()
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("expected status: got %v, want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %v", err)
+			}
+
+			if preview := strings.Contains(string(body), "Practicum"); preview != tt.wantPreview {
+				t.Errorf("unexpected preview page: got %v, want %v", preview, tt.wantPreview)
+			}
+
+			if !tt.wantPreview && tt.wantStatusCode == http.StatusTemporaryRedirect {
+				if location := response.Header.Get("Location"); location != "https://practicum.yandex.ru/" {
+					t.Errorf("unexpected Location header: got %q", location)
+				}
+			}
+		})
+	}
+}
@@ -21,10 +21,10 @@ type Server struct {
 	ctx    context.Context
 }
 
-func NewServer(ctx context.Context, serverAddress string, handler handler.Handler) *Server {
+func NewServer(ctx context.Context, serverAddress string, handler handler.Handler, authHandler handler.AuthHandler, workspaceHandler handler.WorkspaceHandler) *Server {
 	server := &http.Server{
 		Addr:         serverAddress,
-		Handler:      useRoutes(handler),
+		Handler:      useRoutes(handler, authHandler, workspaceHandler),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  15 * time.Second,
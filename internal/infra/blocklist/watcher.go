@@ -0,0 +1,158 @@
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
+	"github.com/Kenny201/go-yandex-shortener.git/internal/utils/closer"
+)
+
+// reloadDelay — время без новых событий файла, после которого файл перечитывается.
+// Запись файла порождает несколько событий, и первое из них может прийти, когда файл ещё пуст или записан частично.
+const reloadDelay = 200 * time.Millisecond
+
+// errEmptyBlocklist возвращается, когда непустой список запрета заменяется пустым файлом.
+var errEmptyBlocklist = errors.New("blocklist file is empty")
+
+// FileWatcher хранит список запрета, загруженный из файла, и перечитывает его при изменении файла.
+// Если новая версия файла содержит ошибку, продолжает действовать предыдущий список.
+type FileWatcher struct {
+	path    string
+	current atomic.Pointer[valueobject.Blocklist]
+}
+
+// NewFileWatcher загружает список запрета из файла path. Возвращает ошибку, если файл не удалось прочитать или разобрать.
+func NewFileWatcher(path string) (*FileWatcher, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve blocklist path: %w", err)
+	}
+
+	fw := &FileWatcher{path: absPath}
+	if err := fw.reload(true); err != nil {
+		return nil, err
+	}
+
+	return fw, nil
+}
+
+// Match сообщает, запрещён ли адрес назначения текущим списком, и возвращает сработавшее правило.
+func (fw *FileWatcher) Match(rawURL string) (string, bool) {
+	return fw.current.Load().Match(rawURL)
+}
+
+// reload читает файл и заменяет текущий список.
+// Если allowEmpty не задан, непустой список не заменяется пустым и возвращается errEmptyBlocklist.
+func (fw *FileWatcher) reload(allowEmpty bool) error {
+	file, err := os.Open(fw.path)
+	if err != nil {
+		return fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer file.Close()
+
+	blocklist, err := valueobject.ParseBlocklist(file)
+	if err != nil {
+		return err
+	}
+
+	if current := fw.current.Load(); !allowEmpty && blocklist.Len() == 0 && current != nil && current.Len() > 0 {
+		return errEmptyBlocklist
+	}
+
+	fw.current.Store(blocklist)
+	slog.Info("Blocklist loaded", slog.String("path", fw.path), slog.Int("rules", blocklist.Len()))
+	return nil
+}
+
+// Watch запускает отслеживание изменений файла и регистрирует его остановку в closer.CL.
+// Отслеживается каталог файла, чтобы замена файла переименованием (как делают редакторы) тоже подхватывалась.
+func (fw *FileWatcher) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create blocklist watcher: %w", err)
+	}
+
+	if err := watcher.Add(filepath.Dir(fw.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch blocklist: %w", err)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		// Файл перечитывается, когда события перестают поступать в течение reloadDelay.
+		// Пустой файл заменяет непустой список, только если остаётся пустым ещё reloadDelay.
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		defer timer.Stop()
+
+		var emptyPending bool
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != fw.path || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+					continue
+				}
+				emptyPending = false
+				resetTimer(timer, reloadDelay)
+			case <-timer.C:
+				err := fw.reload(emptyPending)
+				emptyPending = false
+				if errors.Is(err, errEmptyBlocklist) {
+					emptyPending = true
+					timer.Reset(reloadDelay)
+					continue
+				}
+				if err != nil {
+					slog.Error("Failed to reload blocklist, keeping previous rules", slog.String("error", err.Error()))
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("Blocklist watcher error", slog.String("error", err.Error()))
+			}
+		}
+	}()
+
+	closer.CL.Add(func(ctx context.Context) error {
+		if err := watcher.Close(); err != nil {
+			return err
+		}
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	return nil
+}
+
+// resetTimer перезапускает таймер, отбрасывая срабатывание, которое ещё не было прочитано из канала.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
@@ -0,0 +1,53 @@
+package blocklist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFileWatcherReloadEmpty проверяет, что пустой файл не заменяет непустой список без подтверждения,
+// а файл с правилами и подтверждённый пустой файл заменяют список.
+func TestFileWatcherReloadEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write blocklist: %v", err)
+		}
+	}
+
+	writeFile("evil.com\n")
+	fw, err := NewFileWatcher(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		allowEmpty  bool
+		wantErr     error
+		wantBlocked bool
+	}{
+		{name: "empty_file_keeps_rules", content: "", wantErr: errEmptyBlocklist, wantBlocked: true},
+		{name: "comments_only_keep_rules", content: "# пусто\n", wantErr: errEmptyBlocklist, wantBlocked: true},
+		{name: "confirmed_empty_file_clears_rules", content: "", allowEmpty: true, wantBlocked: false},
+		{name: "rules_replace_empty_list", content: "evil.com\n", wantBlocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFile(tt.content)
+
+			if err := fw.reload(tt.allowEmpty); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error: got %v, want %v", err, tt.wantErr)
+			}
+
+			if _, blocked := fw.Match("https://evil.com/page"); blocked != tt.wantBlocked {
+				t.Errorf("unexpected match: got %v, want %v", blocked, tt.wantBlocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+const (
+	// requestTimeout ограничивает время запросов к провайдеру.
+	requestTimeout = 10 * time.Second
+
+	// keysRefreshInterval — минимальный интервал между повторными загрузками JWKS при встрече
+	// неизвестного идентификатора ключа, чтобы поддельные токены не заставляли постоянно обращаться к провайдеру.
+	keysRefreshInterval = time.Minute
+
+	// maxResponseSize ограничивает размер ответа провайдера.
+	maxResponseSize = 1 << 20
+
+	scopes = "openid email profile"
+)
+
+var (
+	ErrDiscovery       = errors.New("failed to discover OIDC provider configuration")
+	ErrIssuerMismatch  = errors.New("issuer in provider configuration does not match the configured issuer")
+	ErrTokenExchange   = errors.New("failed to exchange authorization code")
+	ErrIDTokenMissing  = errors.New("token response does not contain an ID token")
+	ErrIDTokenInvalid  = errors.New("ID token is invalid")
+	ErrNonceMismatch   = errors.New("ID token nonce does not match the login request")
+	ErrUnknownKey      = errors.New("ID token is signed with an unknown key")
+	ErrUnsupportedKey  = errors.New("unsupported JWK")
+	ErrFetchKeys       = errors.New("failed to fetch provider JWKS")
+	ErrAuthorizedParty = errors.New("ID token is issued to another client")
+)
+
+// signingMethods — алгоритмы подписи ID-токенов, которые принимает провайдер.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Config содержит параметры клиента, зарегистрированного у провайдера OpenID Connect.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // Адрес обработчика возврата от провайдера, зарегистрированный у провайдера
+}
+
+// discovery — нужная часть документа /.well-known/openid-configuration.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jwk — открытый ключ провайдера в формате JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// idTokenClaims — утверждения ID-токена, используемые при входе.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+}
+
+// Provider выполняет вход через внешний провайдер OpenID Connect по коду авторизации с PKCE
+// и проверяет ID-токены по открытым ключам провайдера.
+type Provider struct {
+	config    Config
+	endpoints discovery
+	client    *http.Client
+
+	mu            sync.Mutex
+	keys          map[string]interface{} // kid -> открытый ключ
+	keysFetchedAt time.Time
+}
+
+// NewProvider загружает конфигурацию провайдера по адресу издателя и его открытые ключи.
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	p := &Provider{
+		config: config,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, discoveryURL, &p.endpoints); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+
+	if p.endpoints.Issuer != config.Issuer {
+		return nil, fmt.Errorf("%w: got %q, want %q", ErrIssuerMismatch, p.endpoints.Issuer, config.Issuer)
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	slog.Info("OIDC provider configured", slog.String("issuer", config.Issuer), slog.Int("keys", len(p.keys)))
+	return p, nil
+}
+
+// Issuer возвращает идентификатор издателя провайдера.
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера, запрашивающий код авторизации
+// с параметрами state и nonce и кодом проверки PKCE по методу S256.
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.endpoints.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return p.endpoints.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange обменивает код авторизации на ID-токен, проверяет токен и возвращает подтверждённую им личность.
+// codeVerifier и nonce должны совпадать с теми, что использовались при запросе кода.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*entity.Identity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("%w: status %d: %v", ErrTokenExchange, resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s: %s", ErrTokenExchange, token.Error, token.ErrorDescription)
+	}
+
+	if token.IDToken == "" {
+		return nil, ErrIDTokenMissing
+	}
+
+	return p.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+// VerifyIDToken проверяет подпись ID-токена по JWKS провайдера, издателя, получателя, срок действия и nonce
+// и возвращает личность пользователя.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*entity.Identity, error) {
+	claims := &idTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(rawToken, claims,
+		func(token *jwt.Token) (interface{}, error) { return p.verifyKey(ctx, token) },
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalid, err)
+	}
+
+	// Если токен выпущен для нескольких получателей, azp должен указывать на наш клиент.
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, ErrAuthorizedParty
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: subject is empty", ErrIDTokenInvalid)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	return &entity.Identity{
+		Issuer:        p.config.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// verifyKey возвращает открытый ключ, которым подписан токен.
+// Если идентификатор ключа неизвестен, JWKS загружается заново: провайдер мог сменить ключи.
+func (p *Provider) verifyKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	key, ok := p.findKey(kid)
+	refresh := !ok && time.Since(p.keysFetchedAt) >= keysRefreshInterval
+	p.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if refresh {
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		key, ok = p.findKey(kid)
+		p.mu.Unlock()
+
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+// findKey ищет ключ по идентификатору. Токен без kid принимается, только если у провайдера один ключ.
+func (p *Provider) findKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// refreshKeys загружает открытые ключи подписи провайдера. Ключи неподдерживаемых типов пропускаются.
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := p.getJSON(ctx, p.endpoints.JWKSURI, &set); err != nil {
+		return fmt.Errorf("%w: %v", ErrFetchKeys, err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("Skipping OIDC provider key", slog.String("kid", k.Kid), slog.String("error", err.Error()))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// getJSON выполняет GET-запрос и декодирует JSON-ответ в v.
+func (p *Provider) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, rawURL)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+// publicKey преобразует JWK в открытый ключ RSA, ECDSA или Ed25519.
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, fmt.Errorf("%w: RSA exponent is too large", ErrUnsupportedKey)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key", ErrUnsupportedKey)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, k.Kty)
+}
+
+// decodeBigInt декодирует целое число из base64url без выравнивания.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("%w: invalid key parameter", ErrUnsupportedKey)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,119 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/testutil/oidctest"
+)
+
+// TestProviderVerifyIDToken проверяет, что принимаются только ID-токены, подписанные ключом провайдера,
+// выпущенные им для нашего клиента, не истёкшие и с nonce начатого входа.
+func TestProviderVerifyIDToken(t *testing.T) {
+	mockProvider := oidctest.NewProvider("shortener", "client-secret", oidctest.User{})
+	defer mockProvider.Close()
+
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:      mockProvider.Issuer(),
+		ClientID:    mockProvider.ClientID,
+		RedirectURL: "http://localhost/api/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("failed to configure provider: %v", err)
+	}
+
+	foreignKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		sign    func(claims jwt.MapClaims) (string, error)
+		wantErr error
+	}{
+		{
+			name:   "valid_token",
+			claims: jwt.MapClaims{"sub": "user-1", "nonce": "nonce", "email": "user@example.com", "email_verified": true},
+		},
+		{
+			name:    "nonce_mismatch",
+			claims:  jwt.MapClaims{"sub": "user-1", "nonce": "replayed"},
+			wantErr: ErrNonceMismatch,
+		},
+		{
+			name:    "another_audience",
+			claims:  jwt.MapClaims{"sub": "user-1", "nonce": "nonce", "aud": "another-client"},
+			wantErr: ErrIDTokenInvalid,
+		},
+		{
+			name:    "another_issuer",
+			claims:  jwt.MapClaims{"sub": "user-1", "nonce": "nonce", "iss": "https://evil.example"},
+			wantErr: ErrIDTokenInvalid,
+		},
+		{
+			name:    "expired",
+			claims:  jwt.MapClaims{"sub": "user-1", "nonce": "nonce", "exp": time.Now().Add(-time.Minute).Unix()},
+			wantErr: ErrIDTokenInvalid,
+		},
+		{
+			name:    "several_audiences_without_authorized_party",
+			claims:  jwt.MapClaims{"sub": "user-1", "nonce": "nonce", "aud": []string{"shortener", "another-client"}},
+			wantErr: ErrAuthorizedParty,
+		},
+		{
+			name:   "signed_with_foreign_key",
+			claims: jwt.MapClaims{"sub": "user-1", "nonce": "nonce"},
+			sign: func(claims jwt.MapClaims) (string, error) {
+				claims["iss"] = mockProvider.Issuer()
+				claims["aud"] = mockProvider.ClientID
+				claims["exp"] = time.Now().Add(time.Minute).Unix()
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				token.Header["kid"] = "test-key"
+				return token.SignedString(foreignKey)
+			},
+			wantErr: ErrIDTokenInvalid,
+		},
+		{
+			name: "unsigned",
+			sign: func(claims jwt.MapClaims) (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+					"sub": "user-1", "nonce": "nonce", "iss": mockProvider.Issuer(), "aud": mockProvider.ClientID,
+					"exp": time.Now().Add(time.Minute).Unix(),
+				})
+				return token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			},
+			wantErr: ErrIDTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sign := mockProvider.SignIDToken
+			if tt.sign != nil {
+				sign = tt.sign
+			}
+
+			rawToken, err := sign(tt.claims)
+			if err != nil {
+				t.Fatalf("failed to sign ID token: %v", err)
+			}
+
+			identity, err := provider.VerifyIDToken(context.Background(), rawToken, "nonce")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error: got %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && (identity.Subject != "user-1" || identity.Email != "user@example.com" || !identity.EmailVerified) {
+				t.Errorf("unexpected identity: %+v", identity)
+			}
+		})
+	}
+}
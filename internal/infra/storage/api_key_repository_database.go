@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+type APIKeyDatabase struct {
+	db *pgxpool.Pool
+}
+
+// NewAPIKeyDatabase создает хранилище API-ключей, использующее пул подключений репозитория ссылок.
+func NewAPIKeyDatabase(repo *ShortenerDatabase) *APIKeyDatabase {
+	return &APIKeyDatabase{db: repo.db}
+}
+
+// CreateAPIKey сохраняет новый API-ключ в базе данных.
+func (kd *APIKeyDatabase) CreateAPIKey(key *entity.APIKey) error {
+	query := `
+        INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := kd.db.Exec(context.Background(), query, key.ID, key.UserID, key.Name, key.Prefix, key.Hash, key.Scopes, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (kd *APIKeyDatabase) GetAPIKeyByHash(hash string) (*entity.APIKey, error) {
+	query := `
+        SELECT id, user_id::text, name, prefix, key_hash, scopes, created_at, revoked_at
+        FROM api_keys WHERE key_hash = $1`
+
+	key, err := scanAPIKey(kd.db.QueryRow(context.Background(), query, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (kd *APIKeyDatabase) ListAPIKeys(userID string) ([]*entity.APIKey, error) {
+	query := `
+        SELECT id, user_id::text, name, prefix, key_hash, scopes, created_at, revoked_at
+        FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := kd.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*entity.APIKey, 0)
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey отзывает действующий API-ключ пользователя.
+func (kd *APIKeyDatabase) RevokeAPIKey(id, userID string, revokedAt time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	query := "UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL"
+
+	tag, err := kd.db.Exec(context.Background(), query, revokedAt, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// scanAPIKey читает API-ключ из строки результата запроса.
+func scanAPIKey(row pgx.Row) (*entity.APIKey, error) {
+	var key entity.APIKey
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &key.Scopes, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
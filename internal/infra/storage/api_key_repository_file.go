@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// APIKeyFile хранит API-ключи в файле в формате JSON Lines.
+// Изменённый ключ дописывается в конец файла, при чтении последняя запись ключа заменяет предыдущие.
+type APIKeyFile struct {
+	filePath string
+	keys     map[string]entity.APIKey // хеш ключа -> ключ
+	mu       sync.Mutex
+}
+
+// NewAPIKeyFile создает новое хранилище API-ключей с сохранением данных в файл.
+func NewAPIKeyFile(filePath string) (*APIKeyFile, error) {
+	repo := &APIKeyFile{
+		filePath: filePath,
+		keys:     make(map[string]entity.APIKey),
+	}
+
+	if err := repo.readAll(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// CreateAPIKey сохраняет новый API-ключ в файл.
+func (kf *APIKeyFile) CreateAPIKey(key *entity.APIKey) error {
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+
+	if err := kf.append(*key); err != nil {
+		return err
+	}
+
+	kf.keys[key.Hash] = *key
+	return nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (kf *APIKeyFile) GetAPIKeyByHash(hash string) (*entity.APIKey, error) {
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+
+	key, ok := kf.keys[hash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (kf *APIKeyFile) ListAPIKeys(userID string) ([]*entity.APIKey, error) {
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+
+	return listUserAPIKeys(kf.keys, userID), nil
+}
+
+// RevokeAPIKey отзывает действующий API-ключ пользователя и дописывает изменение в файл.
+func (kf *APIKeyFile) RevokeAPIKey(id, userID string, revokedAt time.Time) error {
+	kf.mu.Lock()
+	defer kf.mu.Unlock()
+
+	key, err := findRevocableAPIKey(kf.keys, id, userID)
+	if err != nil {
+		return err
+	}
+
+	key.RevokedAt = &revokedAt
+
+	if err := kf.append(key); err != nil {
+		return err
+	}
+
+	kf.keys[key.Hash] = key
+	return nil
+}
+
+// append дописывает запись ключа в конец файла.
+func (kf *APIKeyFile) append(key entity.APIKey) error {
+	f, err := os.OpenFile(kf.filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(key); err != nil {
+		return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+	}
+
+	return nil
+}
+
+// readAll читает все API-ключи из файла и загружает их в память.
+func (kf *APIKeyFile) readAll() error {
+	if err := os.MkdirAll(path.Dir(kf.filePath), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrCreateDir, err)
+	}
+
+	f, err := os.OpenFile(kf.filePath, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+
+	for {
+		var key entity.APIKey
+		if err := decoder.Decode(&key); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %v", ErrDecodeFile, err)
+		}
+
+		kf.keys[key.Hash] = key
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+type APIKeyMemory struct {
+	keys map[string]entity.APIKey // хеш ключа -> ключ
+	mu   sync.Mutex
+}
+
+// NewAPIKeyMemory создает новое хранилище API-ключей в памяти.
+func NewAPIKeyMemory() *APIKeyMemory {
+	return &APIKeyMemory{
+		keys: make(map[string]entity.APIKey),
+	}
+}
+
+// CreateAPIKey сохраняет новый API-ключ.
+func (km *APIKeyMemory) CreateAPIKey(key *entity.APIKey) error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	km.keys[key.Hash] = *key
+	return nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хешу.
+func (km *APIKeyMemory) GetAPIKeyByHash(hash string) (*entity.APIKey, error) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	key, ok := km.keys[hash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys возвращает API-ключи пользователя в порядке создания.
+func (km *APIKeyMemory) ListAPIKeys(userID string) ([]*entity.APIKey, error) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	return listUserAPIKeys(km.keys, userID), nil
+}
+
+// RevokeAPIKey отзывает действующий API-ключ пользователя.
+func (km *APIKeyMemory) RevokeAPIKey(id, userID string, revokedAt time.Time) error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	key, err := findRevocableAPIKey(km.keys, id, userID)
+	if err != nil {
+		return err
+	}
+
+	key.RevokedAt = &revokedAt
+	km.keys[key.Hash] = key
+	return nil
+}
+
+// listUserAPIKeys выбирает ключи пользователя и сортирует их по времени создания.
+func listUserAPIKeys(keys map[string]entity.APIKey, userID string) []*entity.APIKey {
+	result := make([]*entity.APIKey, 0)
+
+	for _, key := range keys {
+		if key.UserID == userID {
+			key := key
+			result = append(result, &key)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b *entity.APIKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return result
+}
+
+// findRevocableAPIKey находит действующий ключ пользователя по идентификатору.
+func findRevocableAPIKey(keys map[string]entity.APIKey, id, userID string) (entity.APIKey, error) {
+	for _, key := range keys {
+		if key.ID.String() == id && key.UserID == userID && !key.IsRevoked() {
+			return key, nil
+		}
+	}
+
+	return entity.APIKey{}, ErrAPIKeyNotFound
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+const statsDateLayout = "2006-01-02"
+
+// clickStatsBuilder накапливает переходы по ссылке и группирует их по дням (UTC).
+type clickStatsBuilder struct {
+	shortKey string
+	total    int64
+	daily    map[string]int64
+}
+
+func newClickStatsBuilder(shortKey string) *clickStatsBuilder {
+	return &clickStatsBuilder{
+		shortKey: shortKey,
+		daily:    make(map[string]int64),
+	}
+}
+
+// add учитывает один переход.
+func (b *clickStatsBuilder) add(click entity.Click) {
+	b.total++
+	b.daily[click.Timestamp.UTC().Format(statsDateLayout)]++
+}
+
+// build возвращает статистику с днями, упорядоченными по возрастанию.
+func (b *clickStatsBuilder) build() *entity.ClickStats {
+	stats := &entity.ClickStats{
+		ShortKey: b.shortKey,
+		Total:    b.total,
+		Daily:    make([]entity.DailyClicks, 0, len(b.daily)),
+	}
+
+	for date, count := range b.daily {
+		stats.Daily = append(stats.Daily, entity.DailyClicks{Date: date, Count: count})
+	}
+
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+
+	return stats
+}
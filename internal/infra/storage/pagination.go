@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+)
+
+// listCursor описывает позицию последней выданной ссылки в выбранном порядке сортировки.
+type listCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// encodeCursor кодирует позицию ссылки в непрозрачную строку курсора.
+func encodeCursor(url entity.URL, sortBy string) string {
+	cursor := listCursor{ID: url.ID, Value: url.CreatedAt.UTC().Format(time.RFC3339Nano)}
+	if sortBy == entity.SortByOriginalURL {
+		cursor.Value = url.OriginalURL
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor восстанавливает позицию ссылки из строки курсора.
+// Возвращает nil, если курсор не задан.
+func decodeCursor(cursor string, sortBy string) (*entity.URL, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	url := &entity.URL{ID: c.ID}
+	if sortBy == entity.SortByOriginalURL {
+		url.OriginalURL = c.Value
+		return url, nil
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	url.CreatedAt = createdAt
+
+	return url, nil
+}
+
+// compareURLs сравнивает ссылки по полю сортировки, а при равенстве — по идентификатору.
+func compareURLs(a, b entity.URL, sortBy string) int {
+	var c int
+	if sortBy == entity.SortByOriginalURL {
+		c = strings.Compare(a.OriginalURL, b.OriginalURL)
+	} else {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// matchesListOptions проверяет, попадает ли ссылка в выборку владельца с заданными фильтрами.
+func matchesListOptions(url entity.URL, owner entity.Owner, opts entity.ListOptions) bool {
+	return owner.Owns(url) && matchesFilters(url, opts)
+}
+
+// matchesFilters проверяет, удовлетворяет ли ссылка фильтрам выборки независимо от владельца.
+func matchesFilters(url entity.URL, opts entity.ListOptions) bool {
+	return url.DeletedFlag == opts.Deleted &&
+		strings.Contains(url.OriginalURL, opts.Contains) &&
+		(opts.Tag == "" || url.HasTag(opts.Tag))
+}
+
+// paginateURLs сортирует отфильтрованные ссылки и возвращает страницу после курсора
+// вместе с курсором следующей страницы (пустым, если страница последняя).
+func paginateURLs(urls []entity.URL, opts entity.ListOptions) ([]entity.URL, string, error) {
+	after, err := decodeCursor(opts.Cursor, opts.SortBy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		c := compareURLs(urls[i], urls[j], opts.SortBy)
+		if opts.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	page := make([]entity.URL, 0, len(urls))
+
+	for _, url := range urls {
+		if after != nil {
+			c := compareURLs(url, *after, opts.SortBy)
+			if (!opts.Desc && c <= 0) || (opts.Desc && c >= 0) {
+				continue
+			}
+		}
+
+		if opts.Limit > 0 && len(page) == opts.Limit {
+			return page, encodeCursor(page[len(page)-1], opts.SortBy), nil
+		}
+
+		page = append(page, url)
+	}
+
+	return page, "", nil
+}
+
+// toURLItem преобразует ссылку в элемент списка. Полный короткий URL формирует сервис по домену и ключу.
+func toURLItem(url entity.URL) *entity.URLItem {
+	item := &entity.URLItem{
+		WorkspaceID:  url.WorkspaceID,
+		ShortKey:     url.ShortKey,
+		Domain:       url.Domain,
+		OriginalURL:  url.OriginalURL,
+		ExpiresAt:    url.ExpiresAt,
+		Tags:         url.Tags,
+		RedirectType: url.RedirectType,
+		PassQuery:    url.PassQuery,
+		UTM:          url.UTM,
+		Title:        url.Title,
+	}
+
+	if !url.CreatedAt.IsZero() {
+		createdAt := url.CreatedAt
+		item.CreatedAt = &createdAt
+	}
+
+	return item
+}
+
+// toOwnedURLItems преобразует ссылки в элементы списка с идентификаторами владельцев
+// для выборок по всем пользователям.
+func toOwnedURLItems(urls []entity.URL) []*entity.URLItem {
+	items := make([]*entity.URLItem, 0, len(urls))
+	for _, url := range urls {
+		item := toURLItem(url)
+		item.UserID, _ = url.UserID.(string)
+		items = append(items, item)
+	}
+	return items
+}
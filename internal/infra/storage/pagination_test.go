@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+// TestPaginateURLs проверяет, что постраничный обход по курсору возвращает все ссылки ровно один раз в нужном порядке.
+func TestPaginateURLs(t *testing.T) {
+	createdAt := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	var urls []entity.URL
+	for i := 0; i < 5; i++ {
+		urls = append(urls, entity.URL{
+			ID:          fmt.Sprintf("id-%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", 4-i),
+			// Две последние ссылки созданы одновременно, порядок между ними определяется идентификатором.
+			CreatedAt: createdAt.Add(time.Duration(min(i, 3)) * time.Minute),
+		})
+	}
+
+	tests := []struct {
+		name    string
+		opts    entity.ListOptions
+		wantIDs []string
+	}{
+		{
+			name:    "created_at_ascending",
+			opts:    entity.ListOptions{Limit: 2, SortBy: entity.SortByCreatedAt},
+			wantIDs: []string{"id-0", "id-1", "id-2", "id-3", "id-4"},
+		},
+		{
+			name:    "created_at_descending",
+			opts:    entity.ListOptions{Limit: 2, SortBy: entity.SortByCreatedAt, Desc: true},
+			wantIDs: []string{"id-4", "id-3", "id-2", "id-1", "id-0"},
+		},
+		{
+			name:    "original_url_ascending",
+			opts:    entity.ListOptions{Limit: 3, SortBy: entity.SortByOriginalURL},
+			wantIDs: []string{"id-4", "id-3", "id-2", "id-1", "id-0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIDs []string
+			opts := tt.opts
+
+			for pages := 0; ; pages++ {
+				if pages > len(urls) {
+					t.Fatalf("pagination did not terminate")
+				}
+
+				page, next, err := paginateURLs(append([]entity.URL(nil), urls...), opts)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if len(page) > opts.Limit {
+					t.Fatalf("page size %d exceeds limit %d", len(page), opts.Limit)
+				}
+
+				for _, url := range page {
+					gotIDs = append(gotIDs, url.ID)
+				}
+
+				if next == "" {
+					break
+				}
+				opts.Cursor = next
+			}
+
+			if diff := cmp.Diff(tt.wantIDs, gotIDs); diff != "" {
+				t.Errorf("unexpected order (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestPaginateURLsInvalidCursor проверяет, что повреждённый курсор отклоняется.
+func TestPaginateURLsInvalidCursor(t *testing.T) {
+	_, _, err := paginateURLs(nil, entity.ListOptions{Cursor: "not a cursor"})
+	if err != ErrInvalidCursor {
+		t.Errorf("expected %v, got %v", ErrInvalidCursor, err)
+	}
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+type SessionDatabase struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionDatabase создает хранилище сессий, использующее пул подключений репозитория ссылок.
+func NewSessionDatabase(repo *ShortenerDatabase) *SessionDatabase {
+	return &SessionDatabase{db: repo.db}
+}
+
+// CreateSession сохраняет новую сессию в базе данных.
+func (sd *SessionDatabase) CreateSession(session *entity.Session) error {
+	query := `
+        INSERT INTO sessions (id, user_id, token_hash, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := sd.db.Exec(context.Background(), query, session.ID, session.UserID, session.TokenHash, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSession возвращает сессию по идентификатору.
+func (sd *SessionDatabase) GetSession(id string) (*entity.Session, error) {
+	var session entity.Session
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrSessionNotFound
+	}
+
+	query := `
+        SELECT id, user_id::text, token_hash, created_at, expires_at, revoked_at
+        FROM sessions WHERE id = $1`
+
+	err := sd.db.QueryRow(context.Background(), query, id).Scan(&session.ID, &session.UserID, &session.TokenHash,
+		&session.CreatedAt, &session.ExpiresAt, &session.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// RotateSession заменяет хеш refresh-токена и срок действия неотозванной сессии одним запросом,
+// только если текущий хеш совпадает с oldHash, поэтому один токен нельзя обменять дважды.
+func (sd *SessionDatabase) RotateSession(id, oldHash, newHash string, expiresAt time.Time) error {
+	query := `
+        UPDATE sessions SET token_hash = $1, expires_at = $2
+        WHERE id = $3 AND token_hash = $4 AND revoked_at IS NULL`
+
+	tag, err := sd.db.Exec(context.Background(), query, newHash, expiresAt, id, oldHash)
+	if err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeSession отзывает сессию.
+func (sd *SessionDatabase) RevokeSession(id string, revokedAt time.Time) error {
+	query := "UPDATE sessions SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2"
+
+	tag, err := sd.db.Exec(context.Background(), query, revokedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeUserSessions отзывает все действующие сессии пользователя и возвращает их количество.
+func (sd *SessionDatabase) RevokeUserSessions(userID string, revokedAt time.Time) (int64, error) {
+	query := "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+
+	tag, err := sd.db.Exec(context.Background(), query, revokedAt, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DeleteExpired удаляет сессии, истёкшие к моменту before, и возвращает их количество.
+func (sd *SessionDatabase) DeleteExpired(before time.Time) (int64, error) {
+	tag, err := sd.db.Exec(context.Background(), "DELETE FROM sessions WHERE expires_at <= $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
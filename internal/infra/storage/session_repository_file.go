@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// SessionFile хранит сессии в файле в формате JSON Lines.
+// Изменённая сессия дописывается в конец файла, при чтении последняя запись сессии заменяет предыдущие.
+// При запуске файл перезаписывается без истёкших сессий и устаревших записей.
+type SessionFile struct {
+	filePath string
+	sessions map[string]entity.Session // идентификатор сессии -> сессия
+	mu       sync.Mutex
+}
+
+// NewSessionFile создает новое хранилище сессий с сохранением данных в файл.
+func NewSessionFile(filePath string) (*SessionFile, error) {
+	repo := &SessionFile{
+		filePath: filePath,
+		sessions: make(map[string]entity.Session),
+	}
+
+	if err := repo.readAll(); err != nil {
+		return nil, err
+	}
+
+	if _, err := repo.compact(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// CreateSession сохраняет новую сессию в файл.
+func (sf *SessionFile) CreateSession(session *entity.Session) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if err := sf.append(*session); err != nil {
+		return err
+	}
+
+	sf.sessions[session.ID.String()] = *session
+	return nil
+}
+
+// GetSession возвращает сессию по идентификатору.
+func (sf *SessionFile) GetSession(id string) (*entity.Session, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	session, ok := sf.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return &session, nil
+}
+
+// RotateSession заменяет хеш refresh-токена и срок действия неотозванной сессии,
+// только если текущий хеш совпадает с oldHash.
+func (sf *SessionFile) RotateSession(id, oldHash, newHash string, expiresAt time.Time) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	session, err := rotateSession(sf.sessions, id, oldHash, newHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if err := sf.append(session); err != nil {
+		return err
+	}
+
+	sf.sessions[id] = session
+	return nil
+}
+
+// RevokeSession отзывает сессию и дописывает изменение в файл.
+func (sf *SessionFile) RevokeSession(id string, revokedAt time.Time) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	session, ok := sf.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	session.RevokedAt = &revokedAt
+
+	if err := sf.append(session); err != nil {
+		return err
+	}
+
+	sf.sessions[id] = session
+	return nil
+}
+
+// RevokeUserSessions отзывает все действующие сессии пользователя и возвращает их количество.
+func (sf *SessionFile) RevokeUserSessions(userID string, revokedAt time.Time) (int64, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	revoked := revokeUserSessions(sf.sessions, userID, revokedAt)
+	if len(revoked) == 0 {
+		return 0, nil
+	}
+
+	if err := sf.append(revoked...); err != nil {
+		return 0, err
+	}
+
+	for _, session := range revoked {
+		sf.sessions[session.ID.String()] = session
+	}
+
+	return int64(len(revoked)), nil
+}
+
+// DeleteExpired удаляет сессии, истёкшие к моменту before, перезаписывает файл и возвращает их количество.
+func (sf *SessionFile) DeleteExpired(before time.Time) (int64, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	return sf.compact(before)
+}
+
+// append дописывает записи сессий в конец файла.
+func (sf *SessionFile) append(sessions ...entity.Session) error {
+	f, err := os.OpenFile(sf.filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	return encodeSessions(f, sessions)
+}
+
+// compact удаляет истёкшие к моменту now сессии и перезаписывает файл по одной записи на сессию.
+// Возвращает количество удалённых сессий; если файл перезаписать не удалось, сессии остаются в памяти.
+func (sf *SessionFile) compact(now time.Time) (int64, error) {
+	expired := deleteExpiredSessions(sf.sessions, now)
+
+	sessions := make([]entity.Session, 0, len(sf.sessions))
+	for _, session := range sf.sessions {
+		sessions = append(sessions, session)
+	}
+
+	if err := sf.rewriteFile(sessions); err != nil {
+		for _, session := range expired {
+			sf.sessions[session.ID.String()] = session
+		}
+		return 0, err
+	}
+
+	return int64(len(expired)), nil
+}
+
+// rewriteFile атомарно заменяет содержимое файла записями sessions.
+func (sf *SessionFile) rewriteFile(sessions []entity.Session) error {
+	var buf bytes.Buffer
+	if err := encodeSessions(&buf, sessions); err != nil {
+		return err
+	}
+
+	tmpFilePath := sf.filePath + ".tmp"
+	if err := os.WriteFile(tmpFilePath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+
+	return os.Rename(tmpFilePath, sf.filePath)
+}
+
+// readAll читает все сессии из файла и загружает их в память.
+func (sf *SessionFile) readAll() error {
+	if err := os.MkdirAll(path.Dir(sf.filePath), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrCreateDir, err)
+	}
+
+	f, err := os.OpenFile(sf.filePath, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+
+	for {
+		var session entity.Session
+		if err := decoder.Decode(&session); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %v", ErrDecodeFile, err)
+		}
+
+		sf.sessions[session.ID.String()] = session
+	}
+
+	return nil
+}
+
+// encodeSessions записывает сессии в формате JSON Lines.
+func encodeSessions(w io.Writer, sessions []entity.Session) error {
+	encoder := json.NewEncoder(w)
+
+	for _, session := range sessions {
+		if err := encoder.Encode(session); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+		}
+	}
+
+	return nil
+}
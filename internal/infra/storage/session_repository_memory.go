@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+type SessionMemory struct {
+	sessions map[string]entity.Session // идентификатор сессии -> сессия
+	mu       sync.Mutex
+}
+
+// NewSessionMemory создает новое хранилище сессий в памяти.
+func NewSessionMemory() *SessionMemory {
+	return &SessionMemory{
+		sessions: make(map[string]entity.Session),
+	}
+}
+
+// CreateSession сохраняет новую сессию.
+func (sm *SessionMemory) CreateSession(session *entity.Session) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.sessions[session.ID.String()] = *session
+	return nil
+}
+
+// GetSession возвращает сессию по идентификатору.
+func (sm *SessionMemory) GetSession(id string) (*entity.Session, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return &session, nil
+}
+
+// RotateSession заменяет хеш refresh-токена и срок действия неотозванной сессии,
+// только если текущий хеш совпадает с oldHash.
+func (sm *SessionMemory) RotateSession(id, oldHash, newHash string, expiresAt time.Time) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, err := rotateSession(sm.sessions, id, oldHash, newHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	sm.sessions[id] = session
+	return nil
+}
+
+// RevokeSession отзывает сессию.
+func (sm *SessionMemory) RevokeSession(id string, revokedAt time.Time) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if session.RevokedAt == nil {
+		session.RevokedAt = &revokedAt
+		sm.sessions[id] = session
+	}
+
+	return nil
+}
+
+// RevokeUserSessions отзывает все действующие сессии пользователя и возвращает их количество.
+func (sm *SessionMemory) RevokeUserSessions(userID string, revokedAt time.Time) (int64, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	revoked := revokeUserSessions(sm.sessions, userID, revokedAt)
+	for _, session := range revoked {
+		sm.sessions[session.ID.String()] = session
+	}
+
+	return int64(len(revoked)), nil
+}
+
+// DeleteExpired удаляет сессии, истёкшие к моменту before, и возвращает их количество.
+func (sm *SessionMemory) DeleteExpired(before time.Time) (int64, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	return int64(len(deleteExpiredSessions(sm.sessions, before))), nil
+}
+
+// rotateSession возвращает сессию с новым хешем refresh-токена, если текущий хеш совпадает с oldHash.
+func rotateSession(sessions map[string]entity.Session, id, oldHash, newHash string, expiresAt time.Time) (entity.Session, error) {
+	session, ok := sessions[id]
+	if !ok || session.RevokedAt != nil || session.TokenHash != oldHash {
+		return entity.Session{}, ErrSessionNotFound
+	}
+
+	session.TokenHash = newHash
+	session.ExpiresAt = expiresAt
+	return session, nil
+}
+
+// revokeUserSessions возвращает отозванные копии действующих сессий пользователя.
+func revokeUserSessions(sessions map[string]entity.Session, userID string, revokedAt time.Time) []entity.Session {
+	var revoked []entity.Session
+
+	for _, session := range sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+			revoked = append(revoked, session)
+		}
+	}
+
+	return revoked
+}
+
+// deleteExpiredSessions удаляет из sessions сессии, истёкшие к моменту before, и возвращает их.
+func deleteExpiredSessions(sessions map[string]entity.Session, before time.Time) []entity.Session {
+	var expired []entity.Session
+
+	for id, session := range sessions {
+		if !before.Before(session.ExpiresAt) {
+			expired = append(expired, session)
+			delete(sessions, id)
+		}
+	}
+
+	return expired
+}
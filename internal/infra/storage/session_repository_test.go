@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// sessionTestRepository — методы хранилищ сессий в памяти и в файле, которые проверяются одинаково.
+type sessionTestRepository interface {
+	CreateSession(session *entity.Session) error
+	GetSession(id string) (*entity.Session, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// TestSessionDeleteExpired проверяет, что удаляются только истёкшие сессии,
+// а файловое хранилище не загружает их после перезапуска.
+func TestSessionDeleteExpired(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "urls.json.sessions")
+
+	fileRepo, err := NewSessionFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to create file repository: %v", err)
+	}
+
+	repositories := map[string]sessionTestRepository{
+		"memory": NewSessionMemory(),
+		"file":   fileRepo,
+	}
+
+	now := time.Now().UTC()
+
+	for name, repo := range repositories {
+		t.Run(name, func(t *testing.T) {
+			expired := entity.NewSession("user", "expired", now.Add(-time.Minute))
+			active := entity.NewSession("user", "active", now.Add(time.Hour))
+
+			for _, session := range []*entity.Session{expired, active} {
+				if err := repo.CreateSession(session); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			count, err := repo.DeleteExpired(now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("expected 1 deleted session, got %d", count)
+			}
+
+			if _, err := repo.GetSession(expired.ID.String()); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected expired session to be deleted, got %v", err)
+			}
+			if _, err := repo.GetSession(active.ID.String()); err != nil {
+				t.Errorf("expected active session to remain, got %v", err)
+			}
+		})
+	}
+
+	reopened, err := NewSessionFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to reopen file repository: %v", err)
+	}
+	if len(reopened.sessions) != 1 {
+		t.Errorf("expected 1 session in file after reopening, got %d", len(reopened.sessions))
+	}
+}
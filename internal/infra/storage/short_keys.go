@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+// domainKey возвращает ключ индекса ссылок: короткий ключ уникален в пределах домена.
+func domainKey(domain, shortKey string) string {
+	return domain + "/" + shortKey
+}
+
+// urlKey возвращает ключ хранения ссылки: оригинальный URL сокращается один раз в пределах домена.
+func urlKey(domain, originalURL string) string {
+	return domain + "/" + originalURL
+}
+
+// checkBatchKeys проверяет, что короткие ключи пачки не заняты в хранилище и не повторяются внутри самой пачки.
+func checkBatchKeys(urls []*entity.URLItem, taken func(domain, shortKey string) bool) error {
+	seen := make(map[string]struct{}, len(urls))
+
+	for _, urlItem := range urls {
+		key := domainKey(urlItem.Domain, urlItem.ShortKey)
+		if _, duplicate := seen[key]; duplicate || taken(urlItem.Domain, urlItem.ShortKey) {
+			return ErrShortKeyTaken
+		}
+		seen[key] = struct{}{}
+	}
+
+	return nil
+}
+
+// deadURLs возвращает удалённые или истёкшие к моменту now ссылки, хранящиеся под ключами keys.
+// Такие ссылки не занимают адрес: новая ссылка на тот же адрес заменяет их.
+func deadURLs(urls map[string]entity.URL, now time.Time, keys ...string) map[string]entity.URL {
+	dead := make(map[string]entity.URL)
+
+	for _, key := range keys {
+		if url, exists := urls[key]; exists && !url.IsActive(now) {
+			dead[key] = url
+		}
+	}
+
+	return dead
+}
+
+// shortKeyTaken сообщает, занят ли короткий ключ на домене ссылкой, которая не будет заменена.
+func shortKeyTaken(keys map[string]string, replaced map[string]entity.URL, domain, shortKey string) bool {
+	key, exists := keys[domainKey(domain, shortKey)]
+	if !exists {
+		return false
+	}
+
+	_, dead := replaced[key]
+	return !dead
+}
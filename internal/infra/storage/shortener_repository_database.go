@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -28,20 +29,44 @@ var (
 	ErrCopyFrom           = errors.New("error during copy operation")
 	ErrCopyCount          = errors.New("discrepancy in copied data count")
 	ErrURLAlreadyExist    = errors.New("duplicate key found")
+	ErrShortKeyTaken      = errors.New("short key is already taken")
 	ErrEmptyURL           = errors.New("empty URL list provided")
 	ErrUserListURL        = errors.New("no short URLs found for user ID")
 	ErrURLDeleted         = errors.New("URL is deleted")
+	ErrURLExpired         = errors.New("URL is expired")
 	ErrURLNotFound        = errors.New("URL not found")
+	ErrUserAlreadyExists  = errors.New("user with this email already exists")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrMemberExists       = errors.New("user is already a member of the workspace")
+	ErrLastOwner          = errors.New("workspace must keep at least one owner")
 )
 
+// shortKeyConstraint — имя ограничения уникальности короткого ключа в пределах домена в таблице shorteners.
+const shortKeyConstraint = "shorteners_domain_short_key_unique"
+
+// originalURLConstraint — имя ограничения уникальности оригинального URL в пределах домена в таблице shorteners.
+const originalURLConstraint = "shorteners_domain_original_url_unique"
+
+// findActivePageSize — количество ссылок, читаемых за один запрос при поиске ссылок по адресу назначения.
+const findActivePageSize = 1000
+
+// tagsColumn выбирает отсортированный массив тегов ссылки из таблицы shorteners.
+const tagsColumn = `COALESCE((
+    SELECT array_agg(t.name ORDER BY t.name)
+    FROM shortener_tags st JOIN tags t ON t.id = st.tag_id
+    WHERE st.shortener_id = shorteners.id), '{}') AS tags`
+
 type ShortenerDatabase struct {
 	db          *pgxpool.Pool
 	databaseDNS string
-	baseURL     string
 }
 
 // NewShortenerDatabase создает новый экземпляр ShortenerDatabase и устанавливает подключение к базе данных.
-func NewShortenerDatabase(baseURL string, databaseDNS string) (*ShortenerDatabase, error) {
+func NewShortenerDatabase(databaseDNS string) (*ShortenerDatabase, error) {
 	config, err := pgxpool.ParseConfig(databaseDNS)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrParseConfigPGXPool, err)
@@ -61,7 +86,6 @@ func NewShortenerDatabase(baseURL string, databaseDNS string) (*ShortenerDatabas
 	repo := &ShortenerDatabase{
 		db:          dbPool,
 		databaseDNS: databaseDNS,
-		baseURL:     baseURL,
 	}
 
 	// Добавляем закрытие соединения в closer
@@ -79,148 +103,708 @@ func (dr *ShortenerDatabase) Close(ctx context.Context) error {
 }
 
 // Get извлекает информацию о коротком URL из базы данных по короткому ключу.
-func (dr *ShortenerDatabase) Get(shortKey string) (*entity.URL, error) {
+func (dr *ShortenerDatabase) Get(domain, shortKey string) (*entity.URL, error) {
 	url := &entity.URL{}
-	query := "SELECT id, short_key, original_url, is_deleted FROM shorteners WHERE short_key = $1"
+	var utm entity.UTM
+	query := `
+        SELECT id, short_key, domain, original_url, is_deleted, expires_at, created_at,
+               redirect_type, pass_query, utm_source, utm_medium, utm_campaign, title
+        FROM shorteners WHERE domain = $1 AND short_key = $2`
 
-	if err := dr.db.QueryRow(context.Background(), query, shortKey).Scan(&url.ID, &url.ShortKey, &url.OriginalURL, &url.DeletedFlag); err != nil {
+	if err := dr.db.QueryRow(context.Background(), query, domain, shortKey).Scan(&url.ID, &url.ShortKey, &url.Domain, &url.OriginalURL, &url.DeletedFlag, &url.ExpiresAt, &url.CreatedAt,
+		&url.RedirectType, &url.PassQuery, &utm.Source, &utm.Medium, &utm.Campaign, &url.Title); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrURLNotFound
 		}
 		return nil, fmt.Errorf("error executing query: %w", err)
 	}
+	url.UTM = utmOrNil(utm)
 
 	if url.DeletedFlag {
 		return nil, ErrURLDeleted // URL помечен как удаленный
 	}
 
+	if url.IsExpired(time.Now()) {
+		return nil, ErrURLExpired
+	}
+
 	slog.Info("URL retrieved", slog.String("shortKey", shortKey), slog.String("originalURL", url.OriginalURL))
 	return url, nil
 }
 
 // Create добавляет новый URL в базу данных.
+// Удалённая или истёкшая ссылка на тот же адрес заменяется новой вместе с её переходами.
 func (dr *ShortenerDatabase) Create(url *entity.URL) (*entity.URL, error) {
-	query := "INSERT INTO shorteners (id, user_id, short_key, original_url) VALUES ($1,$2,$3,$4)"
-	_, err := dr.db.Exec(context.Background(), query, url.ID, url.UserID, url.ShortKey, url.OriginalURL)
+	ctx := context.Background()
+
+	tx, err := dr.db.Begin(ctx)
 	if err != nil {
-		if pgErr := parsePGError(err); pgErr != nil {
-			return dr.handleDuplicateURL(url.OriginalURL)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deleteDeadURLs(ctx, tx, []string{url.Domain}, []string{url.OriginalURL}, time.Now()); err != nil {
+		return nil, err
+	}
+
+	utmSource, utmMedium, utmCampaign := utmColumns(url.UTM)
+	query := `
+        INSERT INTO shorteners (id, user_id, workspace_id, short_key, domain, original_url, expires_at, redirect_type, pass_query, utm_source, utm_medium, utm_campaign, title, creator_ip_hash)
+        VALUES ($1,$2,NULLIF($3, '')::uuid,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,NULLIF($14, ''))`
+	_, err = tx.Exec(ctx, query, url.ID, url.UserID, url.WorkspaceID, url.ShortKey, url.Domain, url.OriginalURL, url.ExpiresAt,
+		url.RedirectType, url.PassQuery, utmSource, utmMedium, utmCampaign, url.Title, url.CreatorIPHash)
+	if err != nil {
+		if pgErr := uniqueViolation(err); pgErr != nil {
+			// Если заняты и ключ, и адрес, ограничение ключа может сработать первым;
+			// уже сокращённый адрес важнее, чтобы клиент получил существующую ссылку, как в других хранилищах.
+			if pgErr.ConstraintName == shortKeyConstraint {
+				if existingURL, err := dr.findExistingURL(url.Domain, url.OriginalURL); err == nil {
+					return existingURL, ErrURLAlreadyExist
+				}
+				return nil, ErrShortKeyTaken
+			}
+			return dr.handleDuplicateURL(url.Domain, url.OriginalURL)
 		}
 		return nil, err
 	}
+
+	if err := attachTags(ctx, tx, url.ID, url.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return url, nil
 }
 
-// handleDuplicateURL обрабатывает ситуацию с дублирующимся URL.
-func (dr *ShortenerDatabase) handleDuplicateURL(originalURL string) (*entity.URL, error) {
-	existingURL, err := dr.findExistingURL(originalURL)
+// deleteDeadURLs физически удаляет удалённые или истёкшие к моменту now ссылки с адресами originalURLs
+// на доменах domains вместе с их переходами, чтобы новые ссылки могли занять эти адреса.
+func deleteDeadURLs(ctx context.Context, tx pgx.Tx, domains, originalURLs []string, now time.Time) error {
+	query := `
+        WITH dead AS (
+            DELETE FROM shorteners s USING unnest($1::varchar[], $2::varchar[]) AS u (domain, original_url)
+            WHERE s.domain = u.domain AND s.original_url = u.original_url AND (s.is_deleted OR s.expires_at <= $3)
+            RETURNING s.domain, s.short_key
+        )
+        DELETE FROM clicks c USING dead d WHERE c.domain = d.domain AND c.short_key = d.short_key`
+
+	if _, err := tx.Exec(ctx, query, domains, originalURLs, now); err != nil {
+		return fmt.Errorf("failed to delete replaced URLs: %w", err)
+	}
+
+	return nil
+}
+
+// attachTags создаёт недостающие теги и привязывает их к ссылке с идентификатором shortenerID.
+func attachTags(ctx context.Context, tx pgx.Tx, shortenerID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "INSERT INTO tags (name) SELECT unnest($1::varchar[]) ON CONFLICT (name) DO NOTHING", tags); err != nil {
+		return fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	query := `
+        INSERT INTO shortener_tags (shortener_id, tag_id)
+        SELECT $1, id FROM tags WHERE name = ANY($2)
+        ON CONFLICT DO NOTHING`
+
+	if _, err := tx.Exec(ctx, query, shortenerID, tags); err != nil {
+		return fmt.Errorf("failed to attach tags: %w", err)
+	}
+
+	return nil
+}
+
+// handleDuplicateURL обрабатывает ситуацию с дублирующимся на домене URL.
+func (dr *ShortenerDatabase) handleDuplicateURL(domain, originalURL string) (*entity.URL, error) {
+	existingURL, err := dr.findExistingURL(domain, originalURL)
 	if err != nil {
 		return nil, err
 	}
 	return existingURL, ErrURLAlreadyExist
 }
 
-// findExistingURL ищет уже существующий URL в базе данных по оригинальному URL.
-func (dr *ShortenerDatabase) findExistingURL(originalURL string) (*entity.URL, error) {
+// findExistingURL ищет действующую ссылку в базе данных по домену и оригинальному URL.
+// Удалённые и истёкшие ссылки не учитываются: новая ссылка на тот же адрес заменяет их.
+func (dr *ShortenerDatabase) findExistingURL(domain, originalURL string) (*entity.URL, error) {
 	var existingURL entity.URL
-	query := "SELECT id, short_key, original_url FROM shorteners WHERE original_url = $1"
+	query := `
+        SELECT id, short_key, domain, original_url FROM shorteners
+        WHERE domain = $1 AND original_url = $2 AND is_deleted = false AND (expires_at IS NULL OR expires_at > $3)`
 
-	if err := dr.db.QueryRow(context.Background(), query, originalURL).Scan(&existingURL.ID, &existingURL.ShortKey, &existingURL.OriginalURL); err != nil {
+	if err := dr.db.QueryRow(context.Background(), query, domain, originalURL, time.Now()).Scan(&existingURL.ID, &existingURL.ShortKey, &existingURL.Domain, &existingURL.OriginalURL); err != nil {
 		return nil, err
 	}
 	return &existingURL, nil
 }
 
 // CreateList добавляет список новых URL в базу данных и возвращает их сокращенные версии.
+// Удалённые или истёкшие ссылки на те же адреса заменяются новыми вместе с их переходами.
 func (dr *ShortenerDatabase) CreateList(userID interface{}, urls []*entity.URLItem) ([]*entity.URLItem, error) {
 	if len(urls) == 0 {
 		return nil, ErrEmptyURL
 	}
 
-	baseURL, err := valueobject.NewBaseURL(dr.baseURL)
-	if err != nil {
-		return nil, err
-	}
-
 	var (
-		duplicates = make([]*entity.URLItem, 0, len(urls))
-		shortURLs  = make([]*entity.URLItem, 0, len(urls))
-		urlBatch   [][]interface{}
+		duplicates   = make([]*entity.URLItem, 0, len(urls))
+		shortURLs    = make([]*entity.URLItem, 0, len(urls))
+		urlBatch     [][]interface{}
+		domains      = make([]string, 0, len(urls))
+		originalURLs = make([]string, 0, len(urls))
 	)
 
 	for _, urlItem := range urls {
-		existingURL, err := dr.findExistingURL(urlItem.OriginalURL)
+		existingURL, err := dr.findExistingURL(urlItem.Domain, urlItem.OriginalURL)
 		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return nil, err
 		}
 
 		if existingURL != nil {
-			duplicates = append(duplicates, &entity.URLItem{ID: existingURL.ID, ShortURL: fmt.Sprintf("%s/%s", baseURL.ToString(), existingURL.ShortKey)})
+			duplicates = append(duplicates, &entity.URLItem{ID: existingURL.ID, ShortKey: existingURL.ShortKey, Domain: existingURL.Domain})
 			return duplicates, ErrURLAlreadyExist
 		}
 
-		shortURL := valueobject.NewShortURL(baseURL)
-		urlItem.ShortKey = shortURL.ShortKey()
-		urlItem.ShortURL = fmt.Sprintf("%s/%s", baseURL.ToString(), urlItem.ShortKey)
-		urlBatch = append(urlBatch, []interface{}{urlItem.ID, userID, urlItem.ShortKey, urlItem.OriginalURL})
-		shortURLs = append(shortURLs, &entity.URLItem{ID: urlItem.ID, ShortURL: fmt.Sprintf("%s/%s", baseURL.ToString(), urlItem.ShortKey)})
+		utmSource, utmMedium, utmCampaign := utmColumns(urlItem.UTM)
+		var workspaceID, creatorIPHash interface{}
+		if urlItem.WorkspaceID != "" {
+			workspaceID = urlItem.WorkspaceID
+		}
+		if urlItem.CreatorIPHash != "" {
+			creatorIPHash = urlItem.CreatorIPHash
+		}
+
+		urlBatch = append(urlBatch, []interface{}{urlItem.ID, userID, workspaceID, urlItem.ShortKey, urlItem.Domain, urlItem.OriginalURL, urlItem.ExpiresAt,
+			urlItem.RedirectType, urlItem.PassQuery, utmSource, utmMedium, utmCampaign, urlItem.Title, creatorIPHash})
+		shortURLs = append(shortURLs, &entity.URLItem{ID: urlItem.ID, ShortKey: urlItem.ShortKey, Domain: urlItem.Domain})
+		domains = append(domains, urlItem.Domain)
+		originalURLs = append(originalURLs, urlItem.OriginalURL)
 	}
 
-	if err := dr.copyURLsToDB(urlBatch); err != nil {
+	ctx := context.Background()
+
+	tx, err := dr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deleteDeadURLs(ctx, tx, domains, originalURLs, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := dr.copyURLsToDB(ctx, tx, urlBatch); err != nil {
 		return nil, err
 	}
 
+	for _, urlItem := range urls {
+		if err := attachTags(ctx, tx, urlItem.ID, urlItem.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return shortURLs, nil
 }
 
-// GetAll получает все ссылки определённого пользователя
-func (dr *ShortenerDatabase) GetAll(userID string) ([]*entity.URLItem, error) {
-	var shortURLs []*entity.URLItem
+// GetAll получает ссылки определённого владельца с учётом фильтров, сортировки и курсора.
+// Используется keyset-пагинация по паре (поле сортировки, id), поэтому глубина страницы не влияет на скорость запроса.
+func (dr *ShortenerDatabase) GetAll(owner entity.Owner, opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	condition, arg := ownerCondition(owner, 1)
+	urls, nextCursor, err := dr.listURLs([]string{condition}, []interface{}{arg}, opts)
+	if err != nil {
+		return nil, "", err
+	}
 
-	query := `
-        SELECT short_key, original_url
-        FROM shorteners 
-        WHERE user_id = $1`
+	// Если ссылки не найдены
+	if len(urls) == 0 {
+		return nil, "", fmt.Errorf("%w:%s", ErrUserListURL, owner)
+	}
+
+	shortURLs := make([]*entity.URLItem, 0, len(urls))
+	for _, url := range urls {
+		shortURLs = append(shortURLs, toURLItem(url))
+	}
+
+	return shortURLs, nextCursor, nil
+}
+
+// FindAll получает ссылки всех пользователей с учётом фильтров, сортировки и курсора.
+func (dr *ShortenerDatabase) FindAll(opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	urls, nextCursor, err := dr.listURLs(nil, nil, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return toOwnedURLItems(urls), nextCursor, nil
+}
+
+// listURLs выбирает страницу ссылок, удовлетворяющих условиям conditions с аргументами args и фильтрам opts,
+// и возвращает её вместе с курсором следующей страницы.
+func (dr *ShortenerDatabase) listURLs(conditions []string, args []interface{}, opts entity.ListOptions) ([]entity.URL, string, error) {
+	after, err := decodeCursor(opts.Cursor, opts.SortBy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	column := entity.SortByCreatedAt
+	if opts.SortBy == entity.SortByOriginalURL {
+		column = entity.SortByOriginalURL
+	}
 
-	rows, err := dr.db.Query(context.Background(), query, userID)
+	direction, comparison := "ASC", ">"
+	if opts.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	args = append(args, opts.Deleted)
+	conditions = append(conditions, fmt.Sprintf("is_deleted = $%d", len(args)))
+
+	if opts.Contains != "" {
+		args = append(args, opts.Contains)
+		conditions = append(conditions, fmt.Sprintf("strpos(original_url, $%d) > 0", len(args)))
+	}
+
+	if opts.Tag != "" {
+		args = append(args, opts.Tag)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+            SELECT 1 FROM shortener_tags st JOIN tags t ON t.id = st.tag_id
+            WHERE st.shortener_id = shorteners.id AND t.name = $%d)`, len(args)))
+	}
+
+	if after != nil {
+		var value interface{} = after.CreatedAt
+		if column == entity.SortByOriginalURL {
+			value = after.OriginalURL
+		}
+		args = append(args, value, after.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d::uuid)", column, comparison, len(args)-1, len(args)))
+	}
+
+	query := fmt.Sprintf(`
+        SELECT id, COALESCE(user_id::text, ''), COALESCE(workspace_id::text, ''), short_key, domain, original_url, expires_at, created_at, redirect_type, pass_query, utm_source, utm_medium, utm_campaign, title, %s
+        FROM shorteners
+        WHERE %s
+        ORDER BY %s %s, id %s`, tagsColumn, strings.Join(conditions, " AND "), column, direction, direction)
+
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := dr.db.Query(context.Background(), query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get short URLs: %w", err)
+		return nil, "", fmt.Errorf("failed to get short URLs: %w", err)
 	}
 	defer rows.Close()
 
+	var urls []entity.URL
+
 	for rows.Next() {
-		var shortURL entity.URLItem
-		if err := rows.Scan(&shortURL.ShortURL, &shortURL.OriginalURL); err != nil {
-			return nil, fmt.Errorf("failed to scan short URL: %w", err)
+		var url entity.URL
+		var userID string
+		var utm entity.UTM
+		if err := rows.Scan(&url.ID, &userID, &url.WorkspaceID, &url.ShortKey, &url.Domain, &url.OriginalURL, &url.ExpiresAt, &url.CreatedAt,
+			&url.RedirectType, &url.PassQuery, &utm.Source, &utm.Medium, &utm.Campaign, &url.Title, &url.Tags); err != nil {
+			return nil, "", fmt.Errorf("failed to scan short URL: %w", err)
 		}
-		shortURL.ShortURL = fmt.Sprintf("%s/%s", dr.baseURL, shortURL.ShortURL)
-		shortURLs = append(shortURLs, &shortURL)
+		if userID != "" {
+			url.UserID = userID
+		}
+		url.UTM = utmOrNil(utm)
+		urls = append(urls, url)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+		return nil, "", fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	// Если ссылки не найдены
-	if len(shortURLs) == 0 {
-		return nil, fmt.Errorf("%s:%s", ErrUserListURL, userID)
+	var nextCursor string
+	if opts.Limit > 0 && len(urls) > opts.Limit {
+		urls = urls[:opts.Limit]
+		nextCursor = encodeCursor(urls[len(urls)-1], opts.SortBy)
 	}
 
-	return shortURLs, nil
+	return urls, nextCursor, nil
+}
+
+// AddTags добавляет теги к ссылке владельца на домене и возвращает итоговый набор тегов.
+func (dr *ShortenerDatabase) AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	return dr.updateTags(domain, shortKey, owner, func(ctx context.Context, tx pgx.Tx, shortenerID string) error {
+		return attachTags(ctx, tx, shortenerID, tags)
+	})
+}
+
+// RemoveTags удаляет теги у ссылки владельца на домене и возвращает итоговый набор тегов.
+func (dr *ShortenerDatabase) RemoveTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	return dr.updateTags(domain, shortKey, owner, func(ctx context.Context, tx pgx.Tx, shortenerID string) error {
+		query := `
+            DELETE FROM shortener_tags st USING tags t
+            WHERE st.tag_id = t.id AND st.shortener_id = $1 AND t.name = ANY($2)`
+
+		if _, err := tx.Exec(ctx, query, shortenerID, tags); err != nil {
+			return fmt.Errorf("failed to detach tags: %w", err)
+		}
+		return nil
+	})
+}
+
+// updateTags находит ссылку владельца на домене, применяет к её тегам update в транзакции
+// и возвращает итоговый набор тегов. Проверяет, что итоговое количество тегов не превышает допустимое.
+func (dr *ShortenerDatabase) updateTags(domain, shortKey string, owner entity.Owner, update func(ctx context.Context, tx pgx.Tx, shortenerID string) error) ([]string, error) {
+	ctx := context.Background()
+
+	tx, err := dr.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var shortenerID string
+	condition, arg := ownerCondition(owner, 3)
+	query := "SELECT id FROM shorteners WHERE domain = $1 AND short_key = $2 AND " + condition + " AND is_deleted = false FOR UPDATE"
+
+	if err := tx.QueryRow(ctx, query, domain, shortKey, arg).Scan(&shortenerID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrURLNotFound
+		}
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+
+	if err := update(ctx, tx, shortenerID); err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	if err := tx.QueryRow(ctx, "SELECT "+tagsColumn+" FROM shorteners WHERE id = $1", shortenerID).Scan(&tags); err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	// Проверяем ограничение на количество тегов по итоговому набору.
+	if _, err := valueobject.NormalizeTags(tags); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tags, nil
 }
 
-// MarkAsDeleted обновляет поле is_deleted на true для списка коротких URL.
-func (dr *ShortenerDatabase) MarkAsDeleted(batch []string, userID string) error {
+// MarkAsDeleted обновляет поле is_deleted на true для списка коротких URL владельца на домене.
+func (dr *ShortenerDatabase) MarkAsDeleted(domain string, batch []string, owner entity.Owner) error {
 	batchObj := &pgx.Batch{}
+	condition, arg := ownerCondition(owner, 3)
 
 	for _, key := range batch {
-		query := "UPDATE shorteners SET is_deleted = true WHERE short_key = $1 AND user_id = $2"
-		batchObj.Queue(query, key, userID)
+		query := "UPDATE shorteners SET is_deleted = true, deleted_at = now() WHERE domain = $1 AND short_key = $2 AND " + condition + " AND is_deleted = false"
+		batchObj.Queue(query, domain, key, arg)
 	}
 
 	return dr.executeBatch(batchObj)
 }
 
+// Restore обновляет поле is_deleted на false для списка коротких URL владельца на домене,
+// срок жизни которых не истёк, и возвращает количество восстановленных ссылок.
+func (dr *ShortenerDatabase) Restore(domain string, batch []string, owner entity.Owner) (int64, error) {
+	condition, arg := ownerCondition(owner, 3)
+	query := `
+        UPDATE shorteners SET is_deleted = false, deleted_at = NULL
+        WHERE domain = $1 AND short_key = ANY($2) AND ` + condition + `
+          AND is_deleted = true AND (expires_at IS NULL OR expires_at > now())`
+
+	tag, err := dr.db.Exec(context.Background(), query, domain, batch, arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore URLs: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ForceMarkAsDeleted помечает как удалённые ссылки на домене с заданными ключами независимо от владельца
+// и возвращает их количество.
+func (dr *ShortenerDatabase) ForceMarkAsDeleted(domain string, shortKeys []string) (int64, error) {
+	query := "UPDATE shorteners SET is_deleted = true, deleted_at = now() WHERE domain = $1 AND short_key = ANY($2) AND is_deleted = false"
+
+	tag, err := dr.db.Exec(context.Background(), query, domain, shortKeys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete URLs: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ForceRestore восстанавливает ссылки на домене с заданными ключами, срок жизни которых не истёк,
+// независимо от владельца и возвращает их количество.
+func (dr *ShortenerDatabase) ForceRestore(domain string, shortKeys []string) (int64, error) {
+	query := `
+        UPDATE shorteners SET is_deleted = false, deleted_at = NULL
+        WHERE domain = $1 AND short_key = ANY($2) AND is_deleted = true AND (expires_at IS NULL OR expires_at > now())`
+
+	tag, err := dr.db.Exec(context.Background(), query, domain, shortKeys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore URLs: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// TransferOwnership передаёт все ссылки пользователя fromUserID пользователю toUserID
+// одним запросом, поэтому ссылки переходят либо все, либо ни одна.
+func (dr *ShortenerDatabase) TransferOwnership(fromUserID, toUserID string) (int64, error) {
+	query := "UPDATE shorteners SET user_id = $2 WHERE user_id = $1"
+
+	tag, err := dr.db.Exec(context.Background(), query, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to transfer URLs ownership: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Update применяет изменения к ссылке владельца на домене с заданным коротким ключом.
+// Возвращает ErrURLAlreadyExist, если новый URL уже сокращён на этом домене (ограничение UNIQUE на domain и original_url).
+func (dr *ShortenerDatabase) Update(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URL, error) {
+	url := &entity.URL{WorkspaceID: owner.WorkspaceID}
+	if owner.WorkspaceID == "" {
+		url.UserID = owner.UserID
+	}
+	condition, arg := ownerCondition(owner, 10)
+	var utm entity.UTM
+	var utmSource, utmMedium, utmCampaign *string
+	if update.UTM != nil {
+		utmSource, utmMedium, utmCampaign = &update.UTM.Source, &update.UTM.Medium, &update.UTM.Campaign
+	}
+
+	query := `
+        UPDATE shorteners SET
+            original_url = COALESCE(NULLIF($1::varchar, ''), original_url),
+            redirect_type = COALESCE($2::integer, redirect_type),
+            pass_query = COALESCE($3::boolean, pass_query),
+            utm_source = COALESCE($4::varchar, utm_source),
+            utm_medium = COALESCE($5::varchar, utm_medium),
+            utm_campaign = COALESCE($6::varchar, utm_campaign),
+            title = COALESCE($7::varchar, title)
+        WHERE domain = $8 AND short_key = $9 AND ` + condition + ` AND is_deleted = false
+        RETURNING id, short_key, domain, original_url, expires_at, redirect_type, pass_query, utm_source, utm_medium, utm_campaign, title, ` + tagsColumn
+
+	err := dr.db.QueryRow(context.Background(), query, update.OriginalURL, update.RedirectType, update.PassQuery,
+		utmSource, utmMedium, utmCampaign, update.Title, domain, shortKey, arg).
+		Scan(&url.ID, &url.ShortKey, &url.Domain, &url.OriginalURL, &url.ExpiresAt,
+			&url.RedirectType, &url.PassQuery, &utm.Source, &utm.Medium, &utm.Campaign, &url.Title, &url.Tags)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrURLNotFound
+		}
+		if pgErr := uniqueViolation(err); pgErr != nil && pgErr.ConstraintName == originalURLConstraint {
+			return nil, ErrURLAlreadyExist
+		}
+		return nil, fmt.Errorf("failed to update URL: %w", err)
+	}
+	url.UTM = utmOrNil(utm)
+
+	return url, nil
+}
+
+// MarkExpiredAsDeleted помечает как удалённые все ссылки, срок жизни которых истёк к моменту now.
+func (dr *ShortenerDatabase) MarkExpiredAsDeleted(now time.Time) (int64, error) {
+	query := "UPDATE shorteners SET is_deleted = true, deleted_at = $1 WHERE expires_at <= $1 AND is_deleted = false"
+
+	tag, err := dr.db.Exec(context.Background(), query, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark expired URLs as deleted: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Purge физически удаляет ссылки, помеченные как удалённые не позднее deletedBefore, вместе с их переходами.
+// Ссылки и переходы удаляются одним запросом, поэтому удаляются либо вместе, либо не удаляются вовсе.
+func (dr *ShortenerDatabase) Purge(deletedBefore time.Time) (int64, error) {
+	var count int64
+	query := `
+        WITH purged AS (
+            DELETE FROM shorteners WHERE is_deleted = true AND deleted_at <= $1
+            RETURNING domain, short_key
+        ), purged_clicks AS (
+            DELETE FROM clicks c USING purged p WHERE c.domain = p.domain AND c.short_key = p.short_key
+        )
+        SELECT count(*) FROM purged`
+
+	if err := dr.db.QueryRow(context.Background(), query, deletedBefore).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to purge deleted URLs: %w", err)
+	}
+
+	return count, nil
+}
+
+// SaveClicks сохраняет пачку событий перехода по ссылкам с использованием CopyFrom.
+func (dr *ShortenerDatabase) SaveClicks(clicks []*entity.Click) error {
+	rows := make([][]interface{}, 0, len(clicks))
+	for _, click := range clicks {
+		rows = append(rows, []interface{}{click.Domain, click.ShortKey, click.Timestamp, click.Referrer, click.UserAgent, click.IP})
+	}
+
+	rowsCopied, err := dr.db.CopyFrom(
+		context.Background(),
+		pgx.Identifier{"clicks"},
+		[]string{"domain", "short_key", "clicked_at", "referrer", "user_agent", "ip"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCopyFrom, err)
+	}
+	if int(rowsCopied) != len(rows) {
+		return fmt.Errorf("%w: %d rows copied, expected %d", ErrCopyCount, rowsCopied, len(rows))
+	}
+	return nil
+}
+
+// CountUsage возвращает количество активных ссылок пользователя, ссылок, созданных им начиная с createdSince,
+// и ссылок, созданных начиная с createdSince с IP-адреса с хешем ipHash. Пустые userID и ipHash не подсчитываются.
+func (dr *ShortenerDatabase) CountUsage(userID, ipHash string, createdSince time.Time) (entity.URLUsage, error) {
+	var usage entity.URLUsage
+	query := `
+        SELECT
+            COUNT(*) FILTER (WHERE user_id = NULLIF($1, '')::uuid AND is_deleted = false AND (expires_at IS NULL OR expires_at > now())),
+            COUNT(*) FILTER (WHERE user_id = NULLIF($1, '')::uuid AND created_at >= $2),
+            COUNT(*) FILTER (WHERE creator_ip_hash = NULLIF($3, '') AND created_at >= $2)
+        FROM shorteners
+        WHERE user_id = NULLIF($1, '')::uuid OR (creator_ip_hash = NULLIF($3, '') AND created_at >= $2)`
+
+	err := dr.db.QueryRow(context.Background(), query, userID, createdSince, ipHash).Scan(&usage.Active, &usage.Created, &usage.IPCreated)
+	if err != nil {
+		return entity.URLUsage{}, fmt.Errorf("failed to count URLs of user: %w", err)
+	}
+
+	return usage, nil
+}
+
+// FindActive возвращает не удалённые ссылки, адрес назначения которых удовлетворяет match.
+// Проверка выполняется на стороне приложения, поэтому ссылки читаются страницами по первичному ключу,
+// и в памяти одновременно находятся только текущая страница и подошедшие ссылки.
+func (dr *ShortenerDatabase) FindActive(match func(originalURL string) bool) ([]*entity.URL, error) {
+	var found []*entity.URL
+	var cursor *string
+
+	for {
+		page, err := dr.findActivePage(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, url := range page {
+			if match(url.OriginalURL) {
+				found = append(found, url)
+			}
+		}
+
+		if len(page) < findActivePageSize {
+			return found, nil
+		}
+		cursor = &page[len(page)-1].ID
+	}
+}
+
+// findActivePage возвращает страницу не удалённых ссылок, идентификаторы которых больше cursor.
+// Пустой cursor означает первую страницу.
+func (dr *ShortenerDatabase) findActivePage(cursor *string) ([]*entity.URL, error) {
+	query := `
+        SELECT id::text, user_id::text, short_key, domain, original_url
+        FROM shorteners
+        WHERE is_deleted = false AND ($1::uuid IS NULL OR id > $1::uuid)
+        ORDER BY id
+        LIMIT $2`
+
+	rows, err := dr.db.Query(context.Background(), query, cursor, findActivePageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active URLs: %w", err)
+	}
+	defer rows.Close()
+
+	page := make([]*entity.URL, 0, findActivePageSize)
+	for rows.Next() {
+		var url entity.URL
+		var userID *string
+		if err := rows.Scan(&url.ID, &userID, &url.ShortKey, &url.Domain, &url.OriginalURL); err != nil {
+			return nil, fmt.Errorf("failed to scan active URL: %w", err)
+		}
+		if userID != nil {
+			url.UserID = *userID
+		}
+		page = append(page, &url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return page, nil
+}
+
+// GetClickStats возвращает статистику переходов по ссылке на домене, принадлежащей владельцу.
+func (dr *ShortenerDatabase) GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error) {
+	var owned bool
+	condition, arg := ownerCondition(owner, 3)
+	ownerQuery := "SELECT EXISTS(SELECT 1 FROM shorteners WHERE domain = $1 AND short_key = $2 AND " + condition + ")"
+
+	if err := dr.db.QueryRow(context.Background(), ownerQuery, domain, shortKey, arg).Scan(&owned); err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+
+	if !owned {
+		return nil, ErrURLNotFound
+	}
+
+	query := `
+        SELECT (clicked_at AT TIME ZONE 'UTC')::date AS day, count(*)
+        FROM clicks
+        WHERE domain = $1 AND short_key = $2
+        GROUP BY day
+        ORDER BY day`
+
+	rows, err := dr.db.Query(context.Background(), query, domain, shortKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &entity.ClickStats{ShortKey: shortKey, Daily: []entity.DailyClicks{}}
+
+	for rows.Next() {
+		var (
+			day   time.Time
+			count int64
+		)
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan click stats: %w", err)
+		}
+		stats.Total += count
+		stats.Daily = append(stats.Daily, entity.DailyClicks{Date: day.Format(statsDateLayout), Count: count})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ownerCondition возвращает условие отбора ссылок владельца с параметром номер n и значение этого параметра.
+// Личными считаются только ссылки пользователя вне рабочих пространств.
+func ownerCondition(owner entity.Owner, n int) (string, interface{}) {
+	if owner.WorkspaceID != "" {
+		return fmt.Sprintf("workspace_id = $%d", n), owner.WorkspaceID
+	}
+	return fmt.Sprintf("user_id = $%d AND workspace_id IS NULL", n), owner.UserID
+}
+
 // executeBatch выполняет пакетный запрос и передает ошибки в errorsChan.
 func (dr *ShortenerDatabase) executeBatch(batch *pgx.Batch) error {
 	br := dr.db.SendBatch(context.Background(), batch)
@@ -240,11 +824,11 @@ func (dr *ShortenerDatabase) executeBatch(batch *pgx.Batch) error {
 }
 
 // copyURLsToDB копирует данные URL в базу данных с использованием CopyFrom.
-func (dr *ShortenerDatabase) copyURLsToDB(urlBatch [][]interface{}) error {
-	rowsCopied, err := dr.db.CopyFrom(
-		context.Background(),
+func (dr *ShortenerDatabase) copyURLsToDB(ctx context.Context, tx pgx.Tx, urlBatch [][]interface{}) error {
+	rowsCopied, err := tx.CopyFrom(
+		ctx,
 		pgx.Identifier{"shorteners"},
-		[]string{"id", "user_id", "short_key", "original_url"},
+		[]string{"id", "user_id", "workspace_id", "short_key", "domain", "original_url", "expires_at", "redirect_type", "pass_query", "utm_source", "utm_medium", "utm_campaign", "title", "creator_ip_hash"},
 		pgx.CopyFromRows(urlBatch),
 	)
 	if err != nil || int(rowsCopied) != len(urlBatch) {
@@ -255,7 +839,10 @@ func (dr *ShortenerDatabase) copyURLsToDB(urlBatch [][]interface{}) error {
 
 // handleCopyError обрабатывает ошибки при копировании данных в базу данных.
 func (dr *ShortenerDatabase) handleCopyError(err error, rowsCopied int64, expectedRows int) error {
-	if pgErr := parsePGError(err); pgErr != nil {
+	if pgErr := uniqueViolation(err); pgErr != nil {
+		if pgErr.ConstraintName == shortKeyConstraint {
+			return ErrShortKeyTaken
+		}
 		return fmt.Errorf("%w for id: %v", ErrURLAlreadyExist, pgErr)
 	}
 	if int(rowsCopied) != expectedRows {
@@ -264,7 +851,9 @@ func (dr *ShortenerDatabase) handleCopyError(err error, rowsCopied int64, expect
 	return fmt.Errorf("%w: %v", ErrCopyFrom, err)
 }
 
-func parsePGError(err error) *pgconn.PgError {
+// uniqueViolation возвращает ошибку PostgreSQL, если err — нарушение ограничения уникальности (код 23505),
+// и nil для любых других ошибок.
+func uniqueViolation(err error) *pgconn.PgError {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
 		return pgErr
@@ -286,6 +875,15 @@ func (dr *ShortenerDatabase) Migrate() error {
 	return nil
 }
 
+// NextSequence возвращает следующее значение последовательности short_key_seq для последовательных ключей.
+func (dr *ShortenerDatabase) NextSequence() (uint64, error) {
+	var n int64
+	if err := dr.db.QueryRow(context.Background(), "SELECT nextval('short_key_seq')").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to get next sequence value: %w", err)
+	}
+	return uint64(n), nil
+}
+
 // CheckHealth проверяет состояние соединения с базой данных.
 func (dr *ShortenerDatabase) CheckHealth() error {
 	if err := dr.db.Ping(context.Background()); err != nil {
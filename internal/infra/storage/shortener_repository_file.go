@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,11 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
@@ -23,19 +27,30 @@ var (
 	ErrCreateDir  = errors.New("failed to create or open directory")
 )
 
+// sequenceBlockSize — количество значений счётчика последовательных ключей, резервируемых одной записью в файл.
+const sequenceBlockSize = 100
+
 type ShortenerFile struct {
-	baseURL  string
-	filePath string
-	urls     map[string]entity.URL
-	mu       sync.Mutex
+	filePath       string
+	clicksFilePath string
+	seqFilePath    string
+	urls           map[string]entity.URL // домен и оригинальный URL -> ссылка
+	keys           map[string]string     // домен и короткий ключ -> ключ ссылки в urls
+	sequence       uint64                // последнее выданное значение счётчика
+	sequenceLimit  uint64                // верхняя граница диапазона значений, сохранённая в файле счётчика
+	mu             sync.Mutex
+	clicksMu       sync.Mutex
+	seqMu          sync.Mutex
 }
 
 // NewShortenerFile создает новый репозиторий сокращения ссылок с сохранением данных в файл.
-func NewShortenerFile(baseURL, filePath string) (*ShortenerFile, error) {
+func NewShortenerFile(filePath string) (*ShortenerFile, error) {
 	repo := &ShortenerFile{
-		baseURL:  baseURL,
-		filePath: filePath,
-		urls:     make(map[string]entity.URL),
+		filePath:       filePath,
+		clicksFilePath: filePath + ".clicks",
+		seqFilePath:    filePath + ".seq",
+		urls:           make(map[string]entity.URL),
+		keys:           make(map[string]string),
 	}
 
 	// Чтение всех существующих URL-ов из файла при инициализации репозитория.
@@ -43,107 +58,613 @@ func NewShortenerFile(baseURL, filePath string) (*ShortenerFile, error) {
 		return nil, err
 	}
 
+	if err := repo.readSequence(); err != nil {
+		return nil, err
+	}
+
 	return repo, nil
 }
 
-// Get возвращает URL по короткому ключу, если он существует в файле.
-func (fr *ShortenerFile) Get(shortKey string) (*entity.URL, error) {
-	for _, v := range fr.urls {
-		if v.ShortKey == shortKey && v.DeletedFlag {
-			return nil, ErrURLDeleted // URL помечен как удаленный
-		}
+// Get возвращает URL по домену и короткому ключу, если он существует в файле.
+func (fr *ShortenerFile) Get(domain, shortKey string) (*entity.URL, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
 
-		if v.ShortKey == shortKey && !v.DeletedFlag {
-			slog.Info("URL retrieved successfully", slog.String("shortKey", shortKey))
-			return &v, nil
-		}
+	key, ok := fr.keys[domainKey(domain, shortKey)]
+	if !ok {
+		return nil, fmt.Errorf("URL %v not found", shortKey)
+	}
+
+	v := fr.urls[key]
+
+	if v.DeletedFlag {
+		return nil, ErrURLDeleted // URL помечен как удаленный
+	}
+
+	if v.IsExpired(time.Now()) {
+		return nil, ErrURLExpired
 	}
-	return nil, fmt.Errorf("URL %v not found", shortKey)
+
+	slog.Info("URL retrieved successfully", slog.String("shortKey", shortKey))
+	return &v, nil
 }
 
 // Create добавляет новый URL в файл и возвращает его сокращенную версию.
+// Удалённая или истёкшая ссылка на тот же адрес заменяется новой вместе с её переходами.
 func (fr *ShortenerFile) Create(url *entity.URL) (*entity.URL, error) {
-	if existingURL := fr.findExistingURL(url.OriginalURL); existingURL != nil {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	key := urlKey(url.Domain, url.OriginalURL)
+	dead := deadURLs(fr.urls, time.Now(), key)
+
+	if existingURL := fr.findExistingURL(url.Domain, url.OriginalURL); existingURL != nil && len(dead) == 0 {
 		return existingURL, ErrURLAlreadyExist
 	}
 
-	fr.urls[url.OriginalURL] = *url
-	err := fr.saveURLToFile(*url)
-	if err != nil {
+	if shortKeyTaken(fr.keys, dead, url.Domain, url.ShortKey) {
+		return nil, ErrShortKeyTaken
+	}
+
+	if len(dead) > 0 {
+		if err := fr.replaceURLs(dead, *url); err != nil {
+			return nil, err
+		}
+		return url, nil
+	}
+
+	if err := fr.saveURLsToFile(*url); err != nil {
 		return nil, err
 	}
 
+	fr.urls[key] = *url
+	fr.keys[domainKey(url.Domain, url.ShortKey)] = key
 	return url, nil
 }
 
+// replaceURLs заменяет удалённые или истёкшие ссылки dead новыми ссылками urls, удаляет переходы
+// заменённых ссылок и перезаписывает файл. При ошибке восстанавливает прежнее состояние в памяти.
+// Вызывающий код должен удерживать мьютекс.
+func (fr *ShortenerFile) replaceURLs(dead map[string]entity.URL, urls ...entity.URL) error {
+	restore := func() {
+		for key, url := range dead {
+			fr.urls[key] = url
+			if shortKey := domainKey(url.Domain, url.ShortKey); fr.keys[shortKey] == "" {
+				fr.keys[shortKey] = key
+			}
+		}
+	}
+
+	for key, url := range dead {
+		delete(fr.urls, key)
+		if shortKey := domainKey(url.Domain, url.ShortKey); fr.keys[shortKey] == key {
+			delete(fr.keys, shortKey)
+		}
+	}
+
+	if err := fr.rewriteClicksFile(); err != nil {
+		restore()
+		return fmt.Errorf("failed to delete clicks of replaced URLs: %w", err)
+	}
+
+	for _, url := range urls {
+		key := urlKey(url.Domain, url.OriginalURL)
+		fr.urls[key] = url
+		fr.keys[domainKey(url.Domain, url.ShortKey)] = key
+	}
+
+	if err := fr.rewriteFile(); err != nil {
+		for _, url := range urls {
+			delete(fr.urls, urlKey(url.Domain, url.OriginalURL))
+			delete(fr.keys, domainKey(url.Domain, url.ShortKey))
+		}
+		restore()
+		return err
+	}
+
+	return nil
+}
+
 // CreateList добавляет список новых URL в файл и возвращает их сокращенные версии.
+// Пачка сохраняется целиком: если хотя бы один URL уже сокращён или ключ занят, не сохраняется ничего.
+// Удалённые или истёкшие ссылки на те же адреса заменяются новыми вместе с их переходами.
 func (fr *ShortenerFile) CreateList(userID interface{}, urls []*entity.URLItem) ([]*entity.URLItem, error) {
-	shortUrls := make([]*entity.URLItem, 0, len(urls))
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
 
-	baseURL, err := valueobject.NewBaseURL(fr.baseURL)
+	keys := make([]string, 0, len(urls))
+	for _, urlItem := range urls {
+		keys = append(keys, urlKey(urlItem.Domain, urlItem.OriginalURL))
+	}
+	dead := deadURLs(fr.urls, time.Now(), keys...)
 
-	if err != nil {
+	for i, urlItem := range urls {
+		existingURL := fr.findExistingURL(urlItem.Domain, urlItem.OriginalURL)
+		if _, replaced := dead[keys[i]]; existingURL != nil && !replaced {
+			return []*entity.URLItem{{ID: urlItem.ID, ShortKey: existingURL.ShortKey, Domain: existingURL.Domain}}, ErrURLAlreadyExist
+		}
+	}
+
+	if err := checkBatchKeys(urls, func(domain, shortKey string) bool {
+		return shortKeyTaken(fr.keys, dead, domain, shortKey)
+	}); err != nil {
 		return nil, err
 	}
 
+	shortUrls := make([]*entity.URLItem, 0, len(urls))
+	urlEntities := make([]entity.URL, 0, len(urls))
+
 	for _, urlItem := range urls {
-		shortURL := valueobject.NewShortURL(baseURL)
+		urlEntities = append(urlEntities, entity.URL{
+			ID:            urlItem.ID,
+			UserID:        userID,
+			WorkspaceID:   urlItem.WorkspaceID,
+			ShortKey:      urlItem.ShortKey,
+			Domain:        urlItem.Domain,
+			OriginalURL:   urlItem.OriginalURL,
+			ExpiresAt:     urlItem.ExpiresAt,
+			CreatedAt:     time.Now().UTC(),
+			Tags:          urlItem.Tags,
+			RedirectType:  urlItem.RedirectType,
+			PassQuery:     urlItem.PassQuery,
+			UTM:           urlItem.UTM,
+			Title:         urlItem.Title,
+			CreatorIPHash: urlItem.CreatorIPHash,
+		})
+	}
 
-		if existingURL := fr.findExistingURL(urlItem.OriginalURL); existingURL != nil {
-			return []*entity.URLItem{{ID: urlItem.ID, ShortURL: fmt.Sprintf("%s/%s", fr.baseURL, existingURL.ShortKey)}}, ErrURLAlreadyExist
+	if len(dead) > 0 {
+		if err := fr.replaceURLs(dead, urlEntities...); err != nil {
+			return nil, err
 		}
-
-		urlEntity := entity.URL{
-			ID:          urlItem.ID,
-			UserID:      userID,
-			ShortKey:    shortURL.ShortKey(),
-			OriginalURL: urlItem.OriginalURL,
+	} else {
+		if err := fr.saveURLsToFile(urlEntities...); err != nil {
+			return nil, err
 		}
 
-		if err := fr.saveURLToFile(urlEntity); err != nil {
-			return nil, err
+		for _, urlEntity := range urlEntities {
+			key := urlKey(urlEntity.Domain, urlEntity.OriginalURL)
+			fr.urls[key] = urlEntity
+			fr.keys[domainKey(urlEntity.Domain, urlEntity.ShortKey)] = key
 		}
-		shortUrls = append(shortUrls, &entity.URLItem{ID: urlEntity.ID, ShortURL: fmt.Sprintf("%s/%s", fr.baseURL, urlEntity.ShortKey)})
-		fr.urls[urlItem.OriginalURL] = urlEntity
+	}
+
+	for _, urlEntity := range urlEntities {
+		shortUrls = append(shortUrls, &entity.URLItem{ID: urlEntity.ID, ShortKey: urlEntity.ShortKey, Domain: urlEntity.Domain})
 	}
 
 	slog.Info("All URLs created successfully", slog.Int("count", len(shortUrls)))
 	return shortUrls, nil
 }
 
-// GetAll получает все ссылки определённого пользователя
-func (fr *ShortenerFile) GetAll(userID string) ([]*entity.URLItem, error) {
-	shortUrls := make([]*entity.URLItem, 0, len(fr.urls))
-
-	for _, urlItem := range fr.urls {
-		if urlItem.UserID == userID {
-			shortUrls = append(shortUrls, &entity.URLItem{ShortURL: fmt.Sprintf("%s/%s", fr.baseURL, urlItem.ShortKey), OriginalURL: urlItem.OriginalURL})
+// GetAll получает ссылки определённого владельца с учётом фильтров, сортировки и курсора
+func (fr *ShortenerFile) GetAll(owner entity.Owner, opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	fr.mu.Lock()
+	matched := make([]entity.URL, 0)
+	for _, url := range fr.urls {
+		if matchesListOptions(url, owner, opts) {
+			matched = append(matched, url)
 		}
 	}
+	fr.mu.Unlock()
+
+	page, nextCursor, err := paginateURLs(matched, opts)
+	if err != nil {
+		return nil, "", err
+	}
 
 	// Если ссылки не найдены
-	if len(shortUrls) == 0 {
-		return nil, fmt.Errorf("%w:%s", ErrUserListURL, userID)
+	if len(page) == 0 {
+		return nil, "", fmt.Errorf("%w:%s", ErrUserListURL, owner)
 	}
 
-	return shortUrls, nil
+	shortUrls := make([]*entity.URLItem, 0, len(page))
+	for _, url := range page {
+		shortUrls = append(shortUrls, toURLItem(url))
+	}
+
+	return shortUrls, nextCursor, nil
 }
 
-// MarkAsDeleted обновляет поле IsDeleted в true для списка URL по коротким ключам.
-func (fr *ShortenerFile) MarkAsDeleted(batch []string, userID string) error {
-	if err := fr.updateFile(userID, batch); err != nil {
-		return err // Возвращаем ошибку, чтобы она была обработана errgroup
+// FindAll получает ссылки всех пользователей с учётом фильтров, сортировки и курсора.
+func (fr *ShortenerFile) FindAll(opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	fr.mu.Lock()
+	matched := make([]entity.URL, 0)
+	for _, url := range fr.urls {
+		if matchesFilters(url, opts) {
+			matched = append(matched, url)
+		}
 	}
+	fr.mu.Unlock()
 
-	return nil
+	page, nextCursor, err := paginateURLs(matched, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return toOwnedURLItems(page), nextCursor, nil
+}
+
+// MarkAsDeleted обновляет поле IsDeleted в true для списка URL владельца на домене по коротким ключам.
+func (fr *ShortenerFile) MarkAsDeleted(domain string, batch []string, owner entity.Owner) error {
+	_, err := fr.updateFile(domain, batch, true, owner.Owns)
+	return err
+}
+
+// Restore обновляет поле IsDeleted в false для списка URL владельца на домене по коротким ключам
+// и возвращает количество восстановленных ссылок.
+func (fr *ShortenerFile) Restore(domain string, batch []string, owner entity.Owner) (int64, error) {
+	return fr.updateFile(domain, batch, false, owner.Owns)
 }
 
-// updateFile обновляет файл, установив поле IsDeleted в true для заданных коротких ключей.
-func (fr *ShortenerFile) updateFile(userID string, shortKeys []string) error {
+// ForceMarkAsDeleted помечает как удалённые ссылки на домене с заданными ключами независимо от владельца
+// и возвращает их количество.
+func (fr *ShortenerFile) ForceMarkAsDeleted(domain string, shortKeys []string) (int64, error) {
+	return fr.updateFile(domain, shortKeys, true, func(entity.URL) bool { return true })
+}
+
+// ForceRestore восстанавливает ссылки на домене с заданными ключами, срок жизни которых не истёк,
+// независимо от владельца и возвращает их количество.
+func (fr *ShortenerFile) ForceRestore(domain string, shortKeys []string) (int64, error) {
+	return fr.updateFile(domain, shortKeys, false, func(entity.URL) bool { return true })
+}
+
+// updateFile устанавливает признак удаления у ссылок на домене с заданными ключами, для которых owned возвращает true,
+// в памяти и перезаписывает файл, если хотя бы одна ссылка была изменена. Возвращает количество изменённых ссылок.
+// Ссылки с истёкшим сроком жизни не восстанавливаются.
+func (fr *ShortenerFile) updateFile(domain string, shortKeys []string, deleted bool, owned func(url entity.URL) bool) (int64, error) {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
 
-	tmpFilePath := fr.filePath + ".tmp"
+	now := time.Now()
+	previous := make(map[string]entity.URL, len(shortKeys))
+
+	for _, shortKey := range shortKeys {
+		key, ok := fr.keys[domainKey(domain, shortKey)]
+		if !ok {
+			continue
+		}
+
+		url := fr.urls[key]
+		if _, changed := previous[key]; changed || url.DeletedFlag == deleted || !owned(url) || (!deleted && url.IsExpired(now)) {
+			continue
+		}
+
+		previous[key] = url
+		if deleted {
+			url.MarkDeleted(now)
+		} else {
+			url.Restore()
+		}
+		fr.urls[key] = url
+	}
+
+	if len(previous) == 0 {
+		return 0, nil
+	}
+
+	if err := fr.rewriteFile(); err != nil {
+		// Откатываем изменения в памяти, чтобы они не расходились с файлом.
+		for key, url := range previous {
+			fr.urls[key] = url
+		}
+		return 0, err
+	}
+
+	return int64(len(previous)), nil
+}
+
+// TransferOwnership передаёт все ссылки пользователя fromUserID пользователю toUserID,
+// перезаписывает файл и возвращает количество переданных ссылок.
+func (fr *ShortenerFile) TransferOwnership(fromUserID, toUserID string) (int64, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	var transferred []string
+
+	for key, url := range fr.urls {
+		if url.UserID == fromUserID {
+			url.UserID = toUserID
+			fr.urls[key] = url
+			transferred = append(transferred, key)
+		}
+	}
+
+	if len(transferred) == 0 {
+		return 0, nil
+	}
+
+	if err := fr.rewriteFile(); err != nil {
+		// Откатываем изменения в памяти, чтобы они не расходились с файлом.
+		for _, key := range transferred {
+			url := fr.urls[key]
+			url.UserID = fromUserID
+			fr.urls[key] = url
+		}
+		return 0, err
+	}
+
+	return int64(len(transferred)), nil
+}
+
+// Update применяет изменения к ссылке владельца на домене с заданным коротким ключом.
+func (fr *ShortenerFile) Update(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URL, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	oldKey, url, ok := fr.ownedURL(domain, shortKey, owner)
+	if !ok {
+		return nil, ErrURLNotFound
+	}
+
+	if update.OriginalURL != "" && update.OriginalURL != url.OriginalURL {
+		if existingURL := fr.findExistingURL(domain, update.OriginalURL); existingURL != nil {
+			return nil, ErrURLAlreadyExist
+		}
+	}
+
+	previous := url
+	applyURLUpdate(&url, update)
+	key := urlKey(domain, url.OriginalURL)
+	delete(fr.urls, oldKey)
+	fr.urls[key] = url
+	fr.keys[domainKey(domain, shortKey)] = key
+
+	if err := fr.rewriteFile(); err != nil {
+		delete(fr.urls, key)
+		fr.urls[oldKey] = previous
+		fr.keys[domainKey(domain, shortKey)] = oldKey
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// AddTags добавляет теги к ссылке владельца на домене и возвращает итоговый набор тегов.
+func (fr *ShortenerFile) AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	return fr.updateTags(domain, shortKey, owner, func(current []string) ([]string, error) {
+		return valueobject.MergeTags(current, tags)
+	})
+}
+
+// RemoveTags удаляет теги у ссылки владельца на домене и возвращает итоговый набор тегов.
+func (fr *ShortenerFile) RemoveTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	return fr.updateTags(domain, shortKey, owner, func(current []string) ([]string, error) {
+		return slices.DeleteFunc(slices.Clone(current), func(tag string) bool {
+			return slices.Contains(tags, tag)
+		}), nil
+	})
+}
+
+// updateTags заменяет теги ссылки владельца на домене результатом функции update.
+func (fr *ShortenerFile) updateTags(domain, shortKey string, owner entity.Owner, update func(current []string) ([]string, error)) ([]string, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	key, url, ok := fr.ownedURL(domain, shortKey, owner)
+	if !ok {
+		return nil, ErrURLNotFound
+	}
+
+	tags, err := update(url.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := url
+	url.Tags = tags
+	fr.urls[key] = url
+
+	if err := fr.rewriteFile(); err != nil {
+		fr.urls[key] = previous
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// ownedURL возвращает не удалённую ссылку владельца на домене с заданным коротким ключом
+// и ключ, под которым она хранится. Вызывающий код должен удерживать мьютекс.
+func (fr *ShortenerFile) ownedURL(domain, shortKey string, owner entity.Owner) (string, entity.URL, bool) {
+	key, ok := fr.keys[domainKey(domain, shortKey)]
+	if !ok {
+		return "", entity.URL{}, false
+	}
+
+	url := fr.urls[key]
+	if !owner.Owns(url) || url.DeletedFlag {
+		return "", entity.URL{}, false
+	}
+
+	return key, url, true
+}
+
+// MarkExpiredAsDeleted помечает как удалённые все ссылки, срок жизни которых истёк к моменту now,
+// и перезаписывает файл, если хотя бы одна ссылка была изменена.
+func (fr *ShortenerFile) MarkExpiredAsDeleted(now time.Time) (int64, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	previous := make(map[string]entity.URL)
+
+	for key, url := range fr.urls {
+		if !url.DeletedFlag && url.IsExpired(now) {
+			previous[key] = url
+			url.MarkDeleted(now)
+			fr.urls[key] = url
+		}
+	}
+
+	if len(previous) == 0 {
+		return 0, nil
+	}
+
+	if err := fr.rewriteFile(); err != nil {
+		for key, url := range previous {
+			fr.urls[key] = url
+		}
+		return 0, err
+	}
+
+	return int64(len(previous)), nil
+}
+
+// SaveClicks дописывает пачку событий перехода в отдельный файл в формате JSON Lines.
+func (fr *ShortenerFile) SaveClicks(clicks []*entity.Click) error {
+	fr.clicksMu.Lock()
+	defer fr.clicksMu.Unlock()
+
+	if err := fr.makeDir(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fr.clicksFilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer fr.closeFile(f)
+
+	writer := bufio.NewWriter(f)
+	encoder := json.NewEncoder(writer)
+
+	for _, click := range clicks {
+		if err := encoder.Encode(click); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+		}
+	}
+
+	return writer.Flush()
+}
+
+// CountUsage возвращает количество активных ссылок пользователя, ссылок, созданных им начиная с createdSince,
+// и ссылок, созданных начиная с createdSince с IP-адреса с хешем ipHash.
+// Ссылки читаются из файла при запуске, поэтому счётчики сохраняются между перезапусками.
+func (fr *ShortenerFile) CountUsage(userID, ipHash string, createdSince time.Time) (entity.URLUsage, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return countUsage(fr.urls, userID, ipHash, createdSince, time.Now()), nil
+}
+
+// FindActive возвращает не удалённые ссылки, адрес назначения которых удовлетворяет match.
+func (fr *ShortenerFile) FindActive(match func(originalURL string) bool) ([]*entity.URL, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	var found []*entity.URL
+	for _, url := range fr.urls {
+		if !url.DeletedFlag && match(url.OriginalURL) {
+			found = append(found, &url)
+		}
+	}
+	return found, nil
+}
+
+// GetClickStats возвращает статистику переходов по ссылке на домене, принадлежащей владельцу.
+// События читаются из файла потоково, без загрузки всех переходов в память.
+func (fr *ShortenerFile) GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error) {
+	if !fr.owns(domain, shortKey, owner) {
+		return nil, ErrURLNotFound
+	}
+
+	fr.clicksMu.Lock()
+	defer fr.clicksMu.Unlock()
+
+	builder := newClickStatsBuilder(shortKey)
+
+	f, err := os.Open(fr.clicksFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return builder.build(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer fr.closeFile(f)
+
+	decoder := json.NewDecoder(f)
+
+	for {
+		var click entity.Click
+		if err := decoder.Decode(&click); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("%w: %v", ErrDecodeFile, err)
+		}
+
+		if click.Domain == domain && click.ShortKey == shortKey {
+			builder.add(click)
+		}
+	}
+
+	return builder.build(), nil
+}
+
+// owns проверяет, что ссылка на домене с коротким ключом принадлежит владельцу.
+func (fr *ShortenerFile) owns(domain, shortKey string, owner entity.Owner) bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	key, ok := fr.keys[domainKey(domain, shortKey)]
+	return ok && owner.Owns(fr.urls[key])
+}
+
+// Purge физически удаляет ссылки, помеченные как удалённые не позднее deletedBefore,
+// и уплотняет файл, убирая из него соответствующие строки. Переходы по удалённым ссылкам
+// убираются из файла переходов вместе с оставшимися от прошлых очисток.
+func (fr *ShortenerFile) Purge(deletedBefore time.Time) (int64, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	purged := make(map[string]entity.URL)
+
+	for key, url := range fr.urls {
+		if url.IsPurgeable(deletedBefore) {
+			purged[key] = url
+			delete(fr.urls, key)
+			if shortKey := domainKey(url.Domain, url.ShortKey); fr.keys[shortKey] == key {
+				delete(fr.keys, shortKey)
+			}
+		}
+	}
+
+	if len(purged) == 0 {
+		return 0, nil
+	}
+
+	if err := fr.rewriteFile(); err != nil {
+		for key, url := range purged {
+			fr.urls[key] = url
+			if shortKey := domainKey(url.Domain, url.ShortKey); fr.keys[shortKey] == "" {
+				fr.keys[shortKey] = key
+			}
+		}
+		return 0, err
+	}
+
+	if err := fr.rewriteClicksFile(); err != nil {
+		return int64(len(purged)), fmt.Errorf("failed to purge clicks: %w", err)
+	}
+
+	return int64(len(purged)), nil
+}
+
+// rewriteClicksFile перезаписывает файл переходов через временный файл, оставляя только переходы
+// по существующим ссылкам. Вызывающий код должен удерживать мьютекс fr.mu.
+func (fr *ShortenerFile) rewriteClicksFile() error {
+	fr.clicksMu.Lock()
+	defer fr.clicksMu.Unlock()
+
+	f, err := os.Open(fr.clicksFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer fr.closeFile(f)
+
+	tmpFilePath := fr.clicksFilePath + ".tmp"
 	tmpFile, err := os.Create(tmpFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
@@ -153,66 +674,87 @@ func (fr *ShortenerFile) updateFile(userID string, shortKeys []string) error {
 		os.Remove(tmpFilePath)
 	}()
 
-	file, err := os.Open(fr.filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
+	decoder := json.NewDecoder(f)
 	writer := bufio.NewWriter(tmpFile)
-	scanner := bufio.NewScanner(file)
+	encoder := json.NewEncoder(writer)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if containsKey(line, shortKeys) && containsUserID(line, userID) {
-			line = strings.ReplaceAll(line, `"is_deleted":false`, `"is_deleted":true`)
+	for {
+		var click entity.Click
+		if err := decoder.Decode(&click); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %v", ErrDecodeFile, err)
 		}
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			return fmt.Errorf("error writing to temp file: %w", err)
+
+		if _, exists := fr.keys[domainKey(click.Domain, click.ShortKey)]; !exists {
+			continue
 		}
-	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading file: %w", err)
+		if err := encoder.Encode(click); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+		}
 	}
 
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("error flushing temp file: %w", err)
 	}
 
-	if err := os.Rename(tmpFilePath, fr.filePath); err != nil {
+	if err := os.Rename(tmpFilePath, fr.clicksFilePath); err != nil {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
 	return nil
 }
 
-// containsKey проверяет, содержит ли строка любой из ключей.
-func containsKey(line string, shortKeys []string) bool {
-	for _, v := range shortKeys {
-		if strings.Contains(line, v) {
-			return true
+// rewriteFile перезаписывает файл текущим состоянием репозитория через временный файл.
+// Вызывающий код должен удерживать мьютекс.
+func (fr *ShortenerFile) rewriteFile() error {
+	if err := fr.makeDir(); err != nil {
+		return err
+	}
+
+	tmpFilePath := fr.filePath + ".tmp"
+	tmpFile, err := os.Create(tmpFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		tmpFile.Close()
+		os.Remove(tmpFilePath)
+	}()
+
+	writer := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(writer)
+
+	for _, url := range fr.urls {
+		if err := encoder.Encode(url); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFile, err)
 		}
 	}
-	return false
-}
 
-// containsUserID проверяет, принадлежит ли строка указанному userID.
-func containsUserID(line, userID string) bool {
-	return strings.Contains(line, fmt.Sprintf(`"user_id":"%s"`, userID))
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error flushing temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpFilePath, fr.filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
 }
 
-// findOrCreateURL ищет существующий URL в файле или создает новый, если не найден.
-func (fr *ShortenerFile) findExistingURL(originalURL string) *entity.URL {
-	if url, exists := fr.urls[originalURL]; exists {
+// findExistingURL ищет ссылку с оригинальным URL на домене среди загруженных из файла.
+func (fr *ShortenerFile) findExistingURL(domain, originalURL string) *entity.URL {
+	if url, exists := fr.urls[urlKey(domain, originalURL)]; exists {
 		return &url
 	}
 
 	return nil
 }
 
-// saveURLToFile сохраняет новый URL в файл в формате JSON.
-func (fr *ShortenerFile) saveURLToFile(url entity.URL) error {
+// saveURLsToFile дописывает новые URL в файл в формате JSON одной записью.
+func (fr *ShortenerFile) saveURLsToFile(urls ...entity.URL) error {
 	if err := fr.makeDir(); err != nil {
 		return err
 	}
@@ -224,8 +766,16 @@ func (fr *ShortenerFile) saveURLToFile(url entity.URL) error {
 		return fmt.Errorf("%w: %v", ErrOpenFile, err)
 	}
 
-	encoder := json.NewEncoder(f)
-	if err := encoder.Encode(url); err != nil {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, url := range urls {
+		if err := encoder.Encode(url); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+		}
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
 		return fmt.Errorf("%w: %v", ErrEncodeFile, err)
 	}
 
@@ -255,7 +805,24 @@ func (fr *ShortenerFile) readAll() error {
 			}
 			return fmt.Errorf("%w: %v", ErrDecodeFile, err)
 		}
-		fr.urls[url.OriginalURL] = url
+
+		// Для записей, удалённых до появления deleted_at, срок хранения отсчитывается от момента загрузки.
+		if url.DeletedFlag && url.DeletedAt == nil {
+			url.MarkDeleted(time.Now())
+		}
+
+		key := urlKey(url.Domain, url.OriginalURL)
+		fr.urls[key] = url
+
+		// Ключ, записанный до появления проверки уникальности, остаётся за первой ссылкой.
+		shortKey := domainKey(url.Domain, url.ShortKey)
+		if existing, taken := fr.keys[shortKey]; taken && existing != key {
+			slog.Warn("Duplicate short key in file, the URL is unreachable by short key",
+				slog.String("shortKey", url.ShortKey), slog.String("originalURL", url.OriginalURL))
+			continue
+		}
+
+		fr.keys[shortKey] = key
 	}
 
 	slog.Info("All URLs loaded successfully from file", slog.Int("count", len(fr.urls)))
@@ -281,6 +848,55 @@ func (fr *ShortenerFile) closeFile(f *os.File) {
 	}
 }
 
+// NextSequence увеличивает счётчик для последовательных ключей. Значения резервируются диапазонами
+// по sequenceBlockSize: в файл сохраняется верхняя граница диапазона до того, как будет выдано первое значение из него,
+// поэтому после перезапуска сервиса значения не повторяются, а неизрасходованный остаток диапазона пропускается.
+func (fr *ShortenerFile) NextSequence() (uint64, error) {
+	fr.seqMu.Lock()
+	defer fr.seqMu.Unlock()
+
+	next := fr.sequence + 1
+
+	if next > fr.sequenceLimit {
+		limit := fr.sequence + sequenceBlockSize
+
+		tmpFilePath := fr.seqFilePath + ".tmp"
+		if err := os.WriteFile(tmpFilePath, []byte(strconv.FormatUint(limit, 10)), 0666); err != nil {
+			return 0, fmt.Errorf("failed to write sequence file: %w", err)
+		}
+
+		if err := os.Rename(tmpFilePath, fr.seqFilePath); err != nil {
+			return 0, fmt.Errorf("failed to rename sequence file: %w", err)
+		}
+
+		fr.sequenceLimit = limit
+	}
+
+	fr.sequence = next
+	return next, nil
+}
+
+// readSequence загружает сохранённую границу диапазона счётчика последовательных ключей, если файл счётчика существует.
+// Выдача значений продолжается после этой границы.
+func (fr *ShortenerFile) readSequence() error {
+	data, err := os.ReadFile(fr.seqFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDecodeFile, err)
+	}
+
+	fr.sequence = n
+	fr.sequenceLimit = n
+	return nil
+}
+
 // CheckHealth проверяет состояние репозитория, проверяя наличие файла на диске.
 func (fr *ShortenerFile) CheckHealth() error {
 	if _, err := os.Stat(fr.filePath); os.IsNotExist(err) {
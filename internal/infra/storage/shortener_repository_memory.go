@@ -3,121 +3,467 @@ package storage
 import (
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
 	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/valueobject"
 )
 
 type ShortenerMemory struct {
-	baseURL string
-	urls    map[string]entity.URL
-	mu      sync.Mutex
+	urls     map[string]entity.URL // домен и оригинальный URL -> ссылка
+	keys     map[string]string     // домен и короткий ключ -> ключ ссылки в urls
+	clicks   map[string][]entity.Click
+	sequence atomic.Uint64
+	mu       sync.Mutex
 }
 
 // NewShortenerMemory создает новый репозиторий сокращения ссылок в памяти.
-func NewShortenerMemory(baseURL string) *ShortenerMemory {
+func NewShortenerMemory() *ShortenerMemory {
 	return &ShortenerMemory{
-		baseURL: baseURL,
-		urls:    make(map[string]entity.URL),
+		urls:   make(map[string]entity.URL),
+		keys:   make(map[string]string),
+		clicks: make(map[string][]entity.Click),
 	}
 }
 
-// Get возвращает URL-адрес по короткому ключу, если он существует.
-func (mr *ShortenerMemory) Get(shortKey string) (*entity.URL, error) {
-	for _, v := range mr.urls {
-		if v.ShortKey == shortKey && v.DeletedFlag {
-			return nil, ErrURLDeleted // URL помечен как удаленный
-		}
+// Get возвращает URL-адрес по домену и короткому ключу, если он существует.
+func (mr *ShortenerMemory) Get(domain, shortKey string) (*entity.URL, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
 
-		if v.ShortKey == shortKey && !v.DeletedFlag {
-			slog.Info("URL retrieved successfully", slog.String("shortKey", shortKey))
-			return &v, nil
-		}
+	key, ok := mr.keys[domainKey(domain, shortKey)]
+	if !ok {
+		return nil, fmt.Errorf("URL %v not found", shortKey)
 	}
-	return nil, fmt.Errorf("URL %v not found", shortKey)
+
+	v := mr.urls[key]
+
+	if v.DeletedFlag {
+		return nil, ErrURLDeleted // URL помечен как удаленный
+	}
+
+	if v.IsExpired(time.Now()) {
+		return nil, ErrURLExpired
+	}
+
+	slog.Info("URL retrieved successfully", slog.String("shortKey", shortKey))
+	return &v, nil
 }
 
 // Create добавляет новый URL в репозиторий, если его еще нет.
+// Удалённая или истёкшая ссылка на тот же адрес заменяется новой вместе с её переходами.
 func (mr *ShortenerMemory) Create(url *entity.URL) (*entity.URL, error) {
-	if v, exists := mr.urls[url.OriginalURL]; exists {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	key := urlKey(url.Domain, url.OriginalURL)
+	dead := deadURLs(mr.urls, time.Now(), key)
+
+	if v, exists := mr.urls[key]; exists && len(dead) == 0 {
 		return &v, ErrURLAlreadyExist
 	}
 
-	mr.urls[url.OriginalURL] = *url
+	if shortKeyTaken(mr.keys, dead, url.Domain, url.ShortKey) {
+		return nil, ErrShortKeyTaken
+	}
+
+	mr.removeURLs(dead)
+	mr.urls[key] = *url
+	mr.keys[domainKey(url.Domain, url.ShortKey)] = key
 	return url, nil
 }
 
+// removeURLs удаляет ссылки вместе с их переходами. Вызывающий код должен удерживать мьютекс.
+func (mr *ShortenerMemory) removeURLs(urls map[string]entity.URL) {
+	for key, url := range urls {
+		delete(mr.urls, key)
+		delete(mr.keys, domainKey(url.Domain, url.ShortKey))
+		delete(mr.clicks, domainKey(url.Domain, url.ShortKey))
+	}
+}
+
 // CreateList добавляет список новых URL в репозиторий, возвращая их сокращенные версии.
+// Пачка сохраняется целиком: если хотя бы один URL уже сокращён или ключ занят, не сохраняется ничего.
+// Удалённые или истёкшие ссылки на те же адреса заменяются новыми вместе с их переходами.
 func (mr *ShortenerMemory) CreateList(userID interface{}, urls []*entity.URLItem) ([]*entity.URLItem, error) {
-	shortUrls := make([]*entity.URLItem, 0, len(urls))
-	baseURL, err := valueobject.NewBaseURL(mr.baseURL)
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
 
-	if err != nil {
+	keys := make([]string, 0, len(urls))
+	for _, urlItem := range urls {
+		keys = append(keys, urlKey(urlItem.Domain, urlItem.OriginalURL))
+	}
+	dead := deadURLs(mr.urls, time.Now(), keys...)
+
+	for i, urlItem := range urls {
+		existingURL, exists := mr.urls[keys[i]]
+		if _, replaced := dead[keys[i]]; exists && !replaced {
+			return []*entity.URLItem{{ID: urlItem.ID, ShortKey: existingURL.ShortKey, Domain: existingURL.Domain}}, ErrURLAlreadyExist
+		}
+	}
+
+	if err := checkBatchKeys(urls, func(domain, shortKey string) bool {
+		return shortKeyTaken(mr.keys, dead, domain, shortKey)
+	}); err != nil {
 		return nil, err
 	}
 
-	for _, urlItem := range urls {
-		shortURL := valueobject.NewShortURL(baseURL)
+	mr.removeURLs(dead)
 
-		if existingURL, exists := mr.urls[urlItem.OriginalURL]; exists {
-			return []*entity.URLItem{{ID: urlItem.ID, ShortURL: fmt.Sprintf("%s/%s", mr.baseURL, existingURL.ShortKey)}}, ErrURLAlreadyExist
-		}
+	shortUrls := make([]*entity.URLItem, 0, len(urls))
 
+	for _, urlItem := range urls {
 		urlEntity := entity.URL{
-			ID:          urlItem.ID,
-			UserID:      userID,
-			ShortKey:    shortURL.ShortKey(),
-			OriginalURL: urlItem.OriginalURL,
+			ID:            urlItem.ID,
+			UserID:        userID,
+			WorkspaceID:   urlItem.WorkspaceID,
+			ShortKey:      urlItem.ShortKey,
+			Domain:        urlItem.Domain,
+			OriginalURL:   urlItem.OriginalURL,
+			ExpiresAt:     urlItem.ExpiresAt,
+			CreatedAt:     time.Now().UTC(),
+			Tags:          urlItem.Tags,
+			RedirectType:  urlItem.RedirectType,
+			PassQuery:     urlItem.PassQuery,
+			UTM:           urlItem.UTM,
+			Title:         urlItem.Title,
+			CreatorIPHash: urlItem.CreatorIPHash,
 		}
 
-		shortUrls = append(shortUrls, &entity.URLItem{ID: urlEntity.ID, ShortURL: fmt.Sprintf("%s/%s", mr.baseURL, urlEntity.ShortKey)})
-		mr.urls[urlItem.OriginalURL] = urlEntity
+		shortUrls = append(shortUrls, &entity.URLItem{ID: urlEntity.ID, ShortKey: urlEntity.ShortKey, Domain: urlEntity.Domain})
+		key := urlKey(urlEntity.Domain, urlEntity.OriginalURL)
+		mr.urls[key] = urlEntity
+		mr.keys[domainKey(urlEntity.Domain, urlEntity.ShortKey)] = key
 	}
 
 	slog.Info("All URLs created successfully", slog.Int("count", len(shortUrls)))
 	return shortUrls, nil
 }
 
-// GetAll получает все ссылки определённого пользователя
-func (mr *ShortenerMemory) GetAll(userID string) ([]*entity.URLItem, error) {
-	shortUrls := make([]*entity.URLItem, 0, len(mr.urls))
-
-	for _, urlItem := range mr.urls {
-		if urlItem.UserID == userID {
-			shortUrls = append(shortUrls, &entity.URLItem{ShortURL: fmt.Sprintf("%s/%s", mr.baseURL, urlItem.ShortKey), OriginalURL: urlItem.OriginalURL})
+// GetAll получает ссылки определённого владельца с учётом фильтров, сортировки и курсора
+func (mr *ShortenerMemory) GetAll(owner entity.Owner, opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	mr.mu.Lock()
+	matched := make([]entity.URL, 0)
+	for _, url := range mr.urls {
+		if matchesListOptions(url, owner, opts) {
+			matched = append(matched, url)
 		}
 	}
+	mr.mu.Unlock()
+
+	page, nextCursor, err := paginateURLs(matched, opts)
+	if err != nil {
+		return nil, "", err
+	}
 
 	// Если ссылки не найдены
-	if len(shortUrls) == 0 {
-		return nil, fmt.Errorf("%w:%s", ErrUserListURL, userID)
+	if len(page) == 0 {
+		return nil, "", fmt.Errorf("%w:%s", ErrUserListURL, owner)
 	}
 
-	return shortUrls, nil
+	shortUrls := make([]*entity.URLItem, 0, len(page))
+	for _, url := range page {
+		shortUrls = append(shortUrls, toURLItem(url))
+	}
+
+	return shortUrls, nextCursor, nil
 }
 
-// MarkAsDeleted устанавливает поле IsDeleted в true для списка URL по коротким ключам.
-func (mr *ShortenerMemory) MarkAsDeleted(batch []string, userID string) error {
-	mr.updateUrls(userID, batch)
+// FindAll получает ссылки всех пользователей с учётом фильтров, сортировки и курсора.
+func (mr *ShortenerMemory) FindAll(opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	mr.mu.Lock()
+	matched := make([]entity.URL, 0)
+	for _, url := range mr.urls {
+		if matchesFilters(url, opts) {
+			matched = append(matched, url)
+		}
+	}
+	mr.mu.Unlock()
+
+	page, nextCursor, err := paginateURLs(matched, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return toOwnedURLItems(page), nextCursor, nil
+}
+
+// MarkAsDeleted устанавливает поле IsDeleted в true для списка URL владельца на домене по коротким ключам.
+func (mr *ShortenerMemory) MarkAsDeleted(domain string, batch []string, owner entity.Owner) error {
+	mr.updateUrls(domain, batch, true, owner.Owns)
 	return nil
 }
 
-// updateUrls обновление urls
-func (mr *ShortenerMemory) updateUrls(userID string, shortKeys []string) {
+// Restore устанавливает поле IsDeleted в false для списка URL владельца на домене по коротким ключам
+// и возвращает количество восстановленных ссылок.
+func (mr *ShortenerMemory) Restore(domain string, batch []string, owner entity.Owner) (int64, error) {
+	return mr.updateUrls(domain, batch, false, owner.Owns), nil
+}
+
+// ForceMarkAsDeleted помечает как удалённые ссылки на домене с заданными ключами независимо от владельца
+// и возвращает их количество.
+func (mr *ShortenerMemory) ForceMarkAsDeleted(domain string, shortKeys []string) (int64, error) {
+	return mr.updateUrls(domain, shortKeys, true, func(entity.URL) bool { return true }), nil
+}
+
+// ForceRestore восстанавливает ссылки на домене с заданными ключами, срок жизни которых не истёк,
+// независимо от владельца и возвращает их количество.
+func (mr *ShortenerMemory) ForceRestore(domain string, shortKeys []string) (int64, error) {
+	return mr.updateUrls(domain, shortKeys, false, func(entity.URL) bool { return true }), nil
+}
+
+// updateUrls устанавливает признак удаления у ссылок на домене с заданными ключами, для которых owned возвращает true,
+// и возвращает количество изменённых ссылок. Ссылки с истёкшим сроком жизни не восстанавливаются.
+func (mr *ShortenerMemory) updateUrls(domain string, shortKeys []string, deleted bool, owned func(url entity.URL) bool) int64 {
 	mr.mu.Lock()
 	defer mr.mu.Unlock()
 
-	// Обновление записей по ключам.
+	now := time.Now()
+	var count int64
+
 	for _, shortKey := range shortKeys {
-		for originalURL, url := range mr.urls {
-			if url.ShortKey == shortKey && !url.DeletedFlag && url.UserID == userID {
-				url.DeletedFlag = true
-				mr.urls[originalURL] = url
-				break
-			}
+		key, ok := mr.keys[domainKey(domain, shortKey)]
+		if !ok {
+			continue
 		}
+
+		url := mr.urls[key]
+		if url.DeletedFlag == deleted || !owned(url) || (!deleted && url.IsExpired(now)) {
+			continue
+		}
+
+		if deleted {
+			url.MarkDeleted(now)
+		} else {
+			url.Restore()
+		}
+		mr.urls[key] = url
+		count++
 	}
+
+	return count
+}
+
+// TransferOwnership передаёт все ссылки пользователя fromUserID пользователю toUserID
+// и возвращает количество переданных ссылок.
+func (mr *ShortenerMemory) TransferOwnership(fromUserID, toUserID string) (int64, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var count int64
+
+	for key, url := range mr.urls {
+		if url.UserID == fromUserID {
+			url.UserID = toUserID
+			mr.urls[key] = url
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Update применяет изменения к ссылке владельца на домене с заданным коротким ключом.
+func (mr *ShortenerMemory) Update(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URL, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	oldKey, url, ok := mr.ownedURL(domain, shortKey, owner)
+	if !ok {
+		return nil, ErrURLNotFound
+	}
+
+	if update.OriginalURL != "" && update.OriginalURL != url.OriginalURL {
+		if _, exists := mr.urls[urlKey(domain, update.OriginalURL)]; exists {
+			return nil, ErrURLAlreadyExist
+		}
+	}
+
+	applyURLUpdate(&url, update)
+	key := urlKey(domain, url.OriginalURL)
+	delete(mr.urls, oldKey)
+	mr.urls[key] = url
+	mr.keys[domainKey(domain, shortKey)] = key
+
+	return &url, nil
+}
+
+// AddTags добавляет теги к ссылке владельца на домене и возвращает итоговый набор тегов.
+func (mr *ShortenerMemory) AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	return mr.updateTags(domain, shortKey, owner, func(current []string) ([]string, error) {
+		return valueobject.MergeTags(current, tags)
+	})
+}
+
+// RemoveTags удаляет теги у ссылки владельца на домене и возвращает итоговый набор тегов.
+func (mr *ShortenerMemory) RemoveTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	return mr.updateTags(domain, shortKey, owner, func(current []string) ([]string, error) {
+		return slices.DeleteFunc(slices.Clone(current), func(tag string) bool {
+			return slices.Contains(tags, tag)
+		}), nil
+	})
+}
+
+// updateTags заменяет теги ссылки владельца на домене результатом функции update.
+func (mr *ShortenerMemory) updateTags(domain, shortKey string, owner entity.Owner, update func(current []string) ([]string, error)) ([]string, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	key, url, ok := mr.ownedURL(domain, shortKey, owner)
+	if !ok {
+		return nil, ErrURLNotFound
+	}
+
+	tags, err := update(url.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	url.Tags = tags
+	mr.urls[key] = url
+	return tags, nil
+}
+
+// ownedURL возвращает не удалённую ссылку владельца на домене с заданным коротким ключом
+// и ключ, под которым она хранится. Вызывается под блокировкой mr.mu.
+func (mr *ShortenerMemory) ownedURL(domain, shortKey string, owner entity.Owner) (string, entity.URL, bool) {
+	key, ok := mr.keys[domainKey(domain, shortKey)]
+	if !ok {
+		return "", entity.URL{}, false
+	}
+
+	url := mr.urls[key]
+	if !owner.Owns(url) || url.DeletedFlag {
+		return "", entity.URL{}, false
+	}
+
+	return key, url, true
+}
+
+// MarkExpiredAsDeleted помечает как удалённые все ссылки, срок жизни которых истёк к моменту now.
+func (mr *ShortenerMemory) MarkExpiredAsDeleted(now time.Time) (int64, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var count int64
+
+	for key, url := range mr.urls {
+		if !url.DeletedFlag && url.IsExpired(now) {
+			url.MarkDeleted(now)
+			mr.urls[key] = url
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Purge физически удаляет ссылки, помеченные как удалённые не позднее deletedBefore, вместе с их переходами.
+func (mr *ShortenerMemory) Purge(deletedBefore time.Time) (int64, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var count int64
+
+	for key, url := range mr.urls {
+		if url.IsPurgeable(deletedBefore) {
+			delete(mr.urls, key)
+			delete(mr.keys, domainKey(url.Domain, url.ShortKey))
+			delete(mr.clicks, domainKey(url.Domain, url.ShortKey))
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// SaveClicks сохраняет пачку событий перехода по ссылкам.
+func (mr *ShortenerMemory) SaveClicks(clicks []*entity.Click) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	for _, click := range clicks {
+		key := domainKey(click.Domain, click.ShortKey)
+		mr.clicks[key] = append(mr.clicks[key], *click)
+	}
+
+	return nil
+}
+
+// CountUsage возвращает количество активных ссылок пользователя, ссылок, созданных им начиная с createdSince,
+// и ссылок, созданных начиная с createdSince с IP-адреса с хешем ipHash.
+func (mr *ShortenerMemory) CountUsage(userID, ipHash string, createdSince time.Time) (entity.URLUsage, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return countUsage(mr.urls, userID, ipHash, createdSince, time.Now()), nil
+}
+
+// countUsage подсчитывает ссылки пользователя userID и IP-адреса с хешем ipHash для проверки квот:
+// активными считаются не удалённые ссылки, срок жизни которых не истёк к моменту now.
+// Пустые userID и ipHash не подсчитываются.
+func countUsage(urls map[string]entity.URL, userID, ipHash string, createdSince, now time.Time) entity.URLUsage {
+	var usage entity.URLUsage
+
+	for _, url := range urls {
+		createdRecently := !url.CreatedAt.Before(createdSince)
+
+		if ipHash != "" && url.CreatorIPHash == ipHash && createdRecently {
+			usage.IPCreated++
+		}
+
+		if userID == "" || url.UserID != userID {
+			continue
+		}
+		if url.IsActive(now) {
+			usage.Active++
+		}
+		if createdRecently {
+			usage.Created++
+		}
+	}
+
+	return usage
+}
+
+// FindActive возвращает не удалённые ссылки, адрес назначения которых удовлетворяет match.
+func (mr *ShortenerMemory) FindActive(match func(originalURL string) bool) ([]*entity.URL, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	var found []*entity.URL
+	for _, url := range mr.urls {
+		if !url.DeletedFlag && match(url.OriginalURL) {
+			found = append(found, &url)
+		}
+	}
+	return found, nil
+}
+
+// GetClickStats возвращает статистику переходов по ссылке на домене, принадлежащей владельцу.
+func (mr *ShortenerMemory) GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	key, ok := mr.keys[domainKey(domain, shortKey)]
+	if !ok || !owner.Owns(mr.urls[key]) {
+		return nil, ErrURLNotFound
+	}
+
+	builder := newClickStatsBuilder(shortKey)
+	for _, click := range mr.clicks[domainKey(domain, shortKey)] {
+		builder.add(click)
+	}
+
+	return builder.build(), nil
+}
+
+// NextSequence возвращает следующее значение счётчика для последовательных ключей.
+func (mr *ShortenerMemory) NextSequence() (uint64, error) {
+	return mr.sequence.Add(1), nil
 }
 
 // CheckHealth проверяет состояние репозитория, возвращая ошибку, если он не инициализирован.
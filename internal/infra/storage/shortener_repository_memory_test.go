@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+// TestShortenerMemoryShortKeyUniqueness проверяет, что занятый короткий ключ не выдаётся повторно,
+// а пачка с коллизией не сохраняется даже частично.
+func TestShortenerMemoryShortKeyUniqueness(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   []*entity.URLItem
+		wantErr error
+	}{
+		{
+			name: "batch_with_free_keys",
+			batch: []*entity.URLItem{
+				{ID: "1", ShortKey: "bbbbb", OriginalURL: "https://b.ru"},
+				{ID: "2", ShortKey: "ccccc", OriginalURL: "https://c.ru"},
+			},
+		},
+		{
+			name: "batch_with_taken_key",
+			batch: []*entity.URLItem{
+				{ID: "1", ShortKey: "bbbbb", OriginalURL: "https://b.ru"},
+				{ID: "2", ShortKey: "aaaaa", OriginalURL: "https://c.ru"},
+			},
+			wantErr: ErrShortKeyTaken,
+		},
+		{
+			name: "batch_with_duplicate_keys",
+			batch: []*entity.URLItem{
+				{ID: "1", ShortKey: "bbbbb", OriginalURL: "https://b.ru"},
+				{ID: "2", ShortKey: "bbbbb", OriginalURL: "https://c.ru"},
+			},
+			wantErr: ErrShortKeyTaken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewShortenerMemory()
+
+			if _, err := repo.Create(entity.NewURL("user", "https://a.ru", "aaaaa")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := repo.Create(entity.NewURL("user", "https://z.ru", "aaaaa")); !errors.Is(err, ErrShortKeyTaken) {
+				t.Errorf("expected error for taken key: got %v, want %v", err, ErrShortKeyTaken)
+			}
+
+			_, err := repo.CreateList("user", tt.batch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error: got %v, want %v", err, tt.wantErr)
+			}
+
+			for _, item := range tt.batch {
+				if item.ShortKey == "aaaaa" {
+					continue
+				}
+
+				_, getErr := repo.Get("", item.ShortKey)
+				if saved := getErr == nil; saved != (tt.wantErr == nil) {
+					t.Errorf("unexpected state of key %q after batch: saved=%v", item.ShortKey, saved)
+				}
+			}
+
+			url, err := repo.Get("", "aaaaa")
+			if err != nil || url.OriginalURL != "https://a.ru" {
+				t.Errorf("expected key aaaaa to keep pointing at https://a.ru: got %v, %v", url, err)
+			}
+		})
+	}
+}
+
+// TestShortenerMemoryTransferOwnership проверяет, что передаются только ссылки исходного пользователя.
+func TestShortenerMemoryTransferOwnership(t *testing.T) {
+	repo := NewShortenerMemory()
+
+	for _, url := range []*entity.URL{
+		entity.NewURL("anonymous", "https://a.ru", "aaaaa"),
+		entity.NewURL("anonymous", "https://b.ru", "bbbbb"),
+		entity.NewURL("other", "https://c.ru", "ccccc"),
+	} {
+		if _, err := repo.Create(url); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	count, err := repo.TransferOwnership("anonymous", "user")
+	if err != nil || count != 2 {
+		t.Fatalf("unexpected transfer result: got %v, %v, want 2", count, err)
+	}
+
+	for key, wantOwner := range map[string]string{"aaaaa": "user", "bbbbb": "user", "ccccc": "other"} {
+		url, err := repo.Get("", key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if url.UserID != wantOwner {
+			t.Errorf("unexpected owner of %q: got %v, want %v", key, url.UserID, wantOwner)
+		}
+	}
+}
+
+// TestShortenerMemoryWorkspaceOwnership проверяет, что ссылки рабочего пространства доступны всем его участникам,
+// но не считаются личными ссылками создавшего их пользователя.
+func TestShortenerMemoryWorkspaceOwnership(t *testing.T) {
+	repo := NewShortenerMemory()
+
+	personal := entity.NewURL("alice", "https://a.ru", "aaaaa")
+	shared := entity.NewURL("alice", "https://b.ru", "bbbbb")
+	shared.WorkspaceID = "team"
+	colleagues := entity.NewURL("bob", "https://c.ru", "ccccc")
+	colleagues.WorkspaceID = "team"
+
+	for _, url := range []*entity.URL{personal, shared, colleagues} {
+		if _, err := repo.Create(url); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		owner    entity.Owner
+		wantKeys []string
+	}{
+		{
+			name:     "personal_links",
+			owner:    entity.Owner{UserID: "alice"},
+			wantKeys: []string{"aaaaa"},
+		},
+		{
+			name:     "workspace_links_of_all_members",
+			owner:    entity.Owner{UserID: "bob", WorkspaceID: "team"},
+			wantKeys: []string{"bbbbb", "ccccc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, _, err := repo.GetAll(tt.owner, entity.ListOptions{SortBy: entity.SortByOriginalURL})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var keys []string
+			for _, url := range urls {
+				keys = append(keys, url.ShortKey)
+			}
+
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("unexpected links: got %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+
+	title := "Team link"
+	if _, err := repo.Update("", "bbbbb", entity.Owner{UserID: "alice"}, entity.URLUpdate{Title: &title}); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("expected workspace link to be unavailable as a personal one: got %v", err)
+	}
+
+	if err := repo.MarkAsDeleted("", []string{"bbbbb"}, entity.Owner{UserID: "bob", WorkspaceID: "team"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.Get("", "bbbbb"); !errors.Is(err, ErrURLDeleted) {
+		t.Errorf("expected workspace member to delete a colleague's link: got %v", err)
+	}
+}
+
+// TestShortenerMemoryForceDelete проверяет, что административные удаление и восстановление
+// затрагивают ссылки всех пользователей, а выборка по всем пользователям возвращает владельцев.
+func TestShortenerMemoryForceDelete(t *testing.T) {
+	repo := NewShortenerMemory()
+
+	for _, url := range []*entity.URL{
+		entity.NewURL("alice", "https://a.ru", "aaaaa"),
+		entity.NewURL("bob", "https://b.ru", "bbbbb"),
+		entity.NewURL(nil, "https://c.ru", "ccccc"),
+	} {
+		if _, err := repo.Create(url); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	count, err := repo.ForceMarkAsDeleted("", []string{"aaaaa", "bbbbb", "zzzzz"})
+	if err != nil || count != 2 {
+		t.Fatalf("unexpected delete result: got %v, %v, want 2", count, err)
+	}
+
+	deleted, _, err := repo.FindAll(entity.ListOptions{Deleted: true, SortBy: entity.SortByOriginalURL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var owners []string
+	for _, item := range deleted {
+		owners = append(owners, item.UserID)
+	}
+
+	if len(owners) != 2 || owners[0] != "alice" || owners[1] != "bob" {
+		t.Errorf("unexpected owners of deleted URLs: got %v, want [alice bob]", owners)
+	}
+
+	count, err = repo.ForceRestore("", []string{"bbbbb", "ccccc"})
+	if err != nil || count != 1 {
+		t.Fatalf("unexpected restore result: got %v, %v, want 1", count, err)
+	}
+
+	active, _, err := repo.FindAll(entity.ListOptions{Contains: "b.ru", SortBy: entity.SortByCreatedAt})
+	if err != nil || len(active) != 1 || active[0].ShortKey != "bbbbb" {
+		t.Errorf("expected restored URL to be found by destination: got %v, %v", active, err)
+	}
+}
+
+// TestShortenerMemoryCountUsage проверяет подсчёт ссылок пользователя для квот: удалённые и истёкшие ссылки
+// не считаются активными, но учитываются среди созданных за сутки. Ссылки, созданные с IP-адреса,
+// подсчитываются по хешу адреса независимо от пользователя.
+func TestShortenerMemoryCountUsage(t *testing.T) {
+	repo := NewShortenerMemory()
+	now := time.Now().UTC()
+	past := now.Add(-time.Hour)
+
+	withCreatorIP := func(url *entity.URL, ipHash string) *entity.URL {
+		url.CreatorIPHash = ipHash
+		return url
+	}
+
+	yesterday := withCreatorIP(entity.NewURL("alice", "https://old.ru", "ooooo"), "ip-hash")
+	yesterday.CreatedAt = now.AddDate(0, 0, -1)
+
+	expired := entity.NewURL("alice", "https://expired.ru", "eeeee")
+	expired.ExpiresAt = &past
+
+	for _, url := range []*entity.URL{
+		withCreatorIP(entity.NewURL("alice", "https://a.ru", "aaaaa"), "ip-hash"),
+		entity.NewURL("alice", "https://b.ru", "bbbbb"),
+		withCreatorIP(entity.NewURL("bob", "https://c.ru", "ccccc"), "ip-hash"),
+		withCreatorIP(entity.NewURL("bob", "https://d.ru", "ddddd"), "other-hash"),
+		yesterday,
+		expired,
+	} {
+		if _, err := repo.Create(url); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := repo.ForceMarkAsDeleted("", []string{"bbbbb"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	usage, err := repo.CountUsage("alice", "ip-hash", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := entity.URLUsage{Active: 2, Created: 3, IPCreated: 2}
+	if usage != want {
+		t.Errorf("unexpected usage: got %+v, want %+v", usage, want)
+	}
+}
@@ -0,0 +1,426 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+)
+
+// testRepository — общие методы репозиториев ссылок в памяти и в файле, которые проверяются одинаково.
+type testRepository interface {
+	Get(domain, shortKey string) (*entity.URL, error)
+	Create(url *entity.URL) (*entity.URL, error)
+	CreateList(userID interface{}, urls []*entity.URLItem) ([]*entity.URLItem, error)
+	Update(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URL, error)
+	AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error)
+	MarkAsDeleted(domain string, batch []string, owner entity.Owner) error
+	Restore(domain string, batch []string, owner entity.Owner) (int64, error)
+	ForceMarkAsDeleted(domain string, shortKeys []string) (int64, error)
+	ForceRestore(domain string, shortKeys []string) (int64, error)
+	Purge(deletedBefore time.Time) (int64, error)
+	SaveClicks(clicks []*entity.Click) error
+	GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error)
+}
+
+// newTestRepositories возвращает репозитории ссылок в памяти и в файле для проверки одинакового поведения хранилищ.
+func newTestRepositories(t *testing.T) map[string]testRepository {
+	fileRepo, err := NewShortenerFile(filepath.Join(t.TempDir(), "urls.json"))
+	if err != nil {
+		t.Fatalf("failed to create file repository: %v", err)
+	}
+
+	return map[string]testRepository{
+		"memory": NewShortenerMemory(),
+		"file":   fileRepo,
+	}
+}
+
+// TestShortenerDomainScopedMutations проверяет, что изменение, удаление, восстановление и статистика ссылки
+// затрагивают только ссылку на указанном домене, даже если на другом домене есть ссылка с тем же ключом.
+func TestShortenerDomainScopedMutations(t *testing.T) {
+	owner := entity.Owner{UserID: "alice"}
+
+	for name, repo := range newTestRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			main := entity.NewURL("alice", "https://a.ru", "same")
+			brand := entity.NewURL("alice", "https://b.ru", "same")
+			brand.Domain = "brand.io"
+
+			for _, url := range []*entity.URL{main, brand} {
+				if _, err := repo.Create(url); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			title := "Brand link"
+			updated, err := repo.Update("brand.io", "same", owner, entity.URLUpdate{Title: &title})
+			if err != nil || updated.OriginalURL != "https://b.ru" {
+				t.Fatalf("unexpected update result: got %v, %v", updated, err)
+			}
+
+			if _, err := repo.AddTags("brand.io", "same", owner, []string{"promo"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if url, err := repo.Get("", "same"); err != nil || url.Title != "" || len(url.Tags) != 0 {
+				t.Errorf("expected link on the default domain to stay unchanged: got %+v, %v", url, err)
+			}
+
+			if err := repo.MarkAsDeleted("brand.io", []string{"same"}, owner); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := repo.Get("", "same"); err != nil {
+				t.Errorf("expected link on the default domain to stay active: got %v", err)
+			}
+
+			if count, err := repo.Restore("", []string{"same"}, owner); err != nil || count != 0 {
+				t.Fatalf("unexpected restore result: got %v, %v, want 0", count, err)
+			}
+
+			if _, err := repo.Get("brand.io", "same"); !errors.Is(err, ErrURLDeleted) {
+				t.Errorf("expected restore on the default domain to keep the brand link deleted: got %v", err)
+			}
+
+			if count, err := repo.ForceRestore("brand.io", []string{"same"}); err != nil || count != 1 {
+				t.Errorf("unexpected force restore result: got %v, %v, want 1", count, err)
+			}
+
+			if count, err := repo.ForceMarkAsDeleted("", []string{"same"}); err != nil || count != 1 {
+				t.Errorf("unexpected force delete result: got %v, %v, want 1", count, err)
+			}
+
+			if _, err := repo.Get("brand.io", "same"); err != nil {
+				t.Errorf("expected brand link to stay active: got %v", err)
+			}
+
+			now := time.Now()
+			clicks := []*entity.Click{
+				entity.NewClick("", "same", "", "", "127.0.0.1", now),
+				entity.NewClick("", "same", "", "", "127.0.0.1", now),
+				entity.NewClick("brand.io", "same", "", "", "127.0.0.1", now),
+			}
+			if err := repo.SaveClicks(clicks); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stats, err := repo.GetClickStats("brand.io", "same", owner)
+			if err != nil || stats.Total != 1 {
+				t.Errorf("unexpected click stats of the brand link: got %+v, %v, want total 1", stats, err)
+			}
+
+			if _, err := repo.GetClickStats("other.io", "same", owner); !errors.Is(err, ErrURLNotFound) {
+				t.Errorf("expected unknown domain to have no link: got %v", err)
+			}
+		})
+	}
+}
+
+// TestShortenerOriginalURLUniquePerDomain проверяет, что один адрес можно сократить на каждом домене,
+// но повторное сокращение на том же домене возвращает существующую ссылку.
+func TestShortenerOriginalURLUniquePerDomain(t *testing.T) {
+	owner := entity.Owner{UserID: "alice"}
+
+	for name, repo := range newTestRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			main := entity.NewURL("alice", "https://a.ru", "aaaaa")
+			brand := entity.NewURL("alice", "https://a.ru", "bbbbb")
+			brand.Domain = "brand.io"
+
+			for _, url := range []*entity.URL{main, brand} {
+				if _, err := repo.Create(url); err != nil {
+					t.Fatalf("expected URL to be shortened on each domain: %v", err)
+				}
+			}
+
+			duplicate := entity.NewURL("alice", "https://a.ru", "ccccc")
+			duplicate.Domain = "brand.io"
+			existing, err := repo.Create(duplicate)
+			if !errors.Is(err, ErrURLAlreadyExist) || existing == nil || existing.ShortKey != "bbbbb" {
+				t.Errorf("expected existing brand link: got %+v, %v", existing, err)
+			}
+
+			items, err := repo.CreateList("alice", []*entity.URLItem{{ID: "1", ShortKey: "ddddd", Domain: "", OriginalURL: "https://a.ru"}})
+			if !errors.Is(err, ErrURLAlreadyExist) || len(items) != 1 || items[0].ShortKey != "aaaaa" {
+				t.Errorf("expected existing default link in batch: got %v, %v", items, err)
+			}
+
+			other := entity.NewURL("alice", "https://b.ru", "eeeee")
+			if _, err := repo.Create(other); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			target := "https://a.ru"
+			if _, err := repo.Update("", "eeeee", owner, entity.URLUpdate{OriginalURL: target}); !errors.Is(err, ErrURLAlreadyExist) {
+				t.Errorf("expected conflict with a link on the same domain: got %v", err)
+			}
+
+			if _, err := repo.Update("", "aaaaa", owner, entity.URLUpdate{OriginalURL: "https://c.ru"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if url, err := repo.Get("brand.io", "bbbbb"); err != nil || url.OriginalURL != target {
+				t.Errorf("expected brand link to keep its destination: got %+v, %v", url, err)
+			}
+		})
+	}
+}
+
+// TestShortenerCreateReplacesDeadURL проверяет, что удалённая или истёкшая ссылка не занимает адрес:
+// повторное сокращение создаёт новую ссылку, а прежняя удаляется вместе с переходами.
+func TestShortenerCreateReplacesDeadURL(t *testing.T) {
+	owner := entity.Owner{UserID: "alice"}
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+
+	fileRepo, err := NewShortenerFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to create file repository: %v", err)
+	}
+
+	repositories := map[string]testRepository{
+		"memory": NewShortenerMemory(),
+		"file":   fileRepo,
+	}
+
+	for name, repo := range repositories {
+		t.Run(name, func(t *testing.T) {
+			expiresAt := time.Now().Add(-time.Minute)
+			expired := entity.NewURL("alice", "https://b.ru", "bbbbb")
+			expired.ExpiresAt = &expiresAt
+
+			for _, url := range []*entity.URL{
+				entity.NewURL("alice", "https://a.ru", "aaaaa"),
+				expired,
+				entity.NewURL("alice", "https://c.ru", "ccccc"),
+			} {
+				if _, err := repo.Create(url); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			clicks := []*entity.Click{entity.NewClick("", "aaaaa", "", "", "127.0.0.1", time.Now())}
+			if err := repo.SaveClicks(clicks); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := repo.ForceMarkAsDeleted("", []string{"aaaaa", "ccccc"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := repo.Create(entity.NewURL("alice", "https://a.ru", "ddddd")); err != nil {
+				t.Fatalf("expected deleted link to be replaced: %v", err)
+			}
+
+			// Алиас может совпадать с ключом заменяемой ссылки.
+			if _, err := repo.Create(entity.NewURL("alice", "https://b.ru", "bbbbb")); err != nil {
+				t.Fatalf("expected expired link to be replaced: %v", err)
+			}
+
+			items, err := repo.CreateList("alice", []*entity.URLItem{{ID: "1", ShortKey: "eeeee", OriginalURL: "https://c.ru"}})
+			if err != nil || len(items) != 1 || items[0].ShortKey != "eeeee" {
+				t.Fatalf("expected deleted link to be replaced in batch: got %v, %v", items, err)
+			}
+
+			for _, shortKey := range []string{"aaaaa", "ccccc"} {
+				if _, err := repo.Get("", shortKey); err == nil || errors.Is(err, ErrURLDeleted) {
+					t.Errorf("expected replaced link %s to be removed, got %v", shortKey, err)
+				}
+			}
+
+			if url, err := repo.Get("", "bbbbb"); err != nil || url.ExpiresAt != nil {
+				t.Errorf("expected new link in place of the expired one: got %+v, %v", url, err)
+			}
+
+			if _, err := repo.Create(entity.NewURL("alice", "https://a.ru", "fffff")); !errors.Is(err, ErrURLAlreadyExist) {
+				t.Errorf("expected active link to keep its address: got %v", err)
+			}
+
+			if _, err := repo.Create(entity.NewURL("alice", "https://g.ru", "aaaaa")); err != nil {
+				t.Fatalf("expected short key of the replaced link to be free: %v", err)
+			}
+			if stats, err := repo.GetClickStats("", "aaaaa", owner); err != nil || stats.Total != 0 {
+				t.Errorf("expected clicks of the replaced link to be removed: got %+v, %v", stats, err)
+			}
+		})
+	}
+
+	reopened, err := NewShortenerFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to reopen file repository: %v", err)
+	}
+	if url, err := reopened.Get("", "ddddd"); err != nil || url.OriginalURL != "https://a.ru" {
+		t.Errorf("expected new link after reopening: got %+v, %v", url, err)
+	}
+	if url, err := reopened.Get("", "aaaaa"); err != nil || url.OriginalURL != "https://g.ru" {
+		t.Errorf("expected replaced short key to belong to the new link after reopening: got %+v, %v", url, err)
+	}
+}
+
+// TestShortenerRestoreSkipsExpired проверяет, что восстанавливаются только удалённые ссылки,
+// срок жизни которых не истёк, и возвращается их количество.
+func TestShortenerRestoreSkipsExpired(t *testing.T) {
+	owner := entity.Owner{UserID: "alice"}
+
+	for name, repo := range newTestRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			expiresAt := time.Now().Add(-time.Minute)
+			expired := entity.NewURL("alice", "https://b.ru", "bbbbb")
+			expired.ExpiresAt = &expiresAt
+
+			for _, url := range []*entity.URL{entity.NewURL("alice", "https://a.ru", "aaaaa"), expired} {
+				if _, err := repo.Create(url); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if err := repo.MarkAsDeleted("", []string{"aaaaa", "bbbbb"}, owner); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			count, err := repo.Restore("", []string{"aaaaa", "bbbbb", "zzzzz"}, owner)
+			if err != nil || count != 1 {
+				t.Fatalf("unexpected restore result: got %v, %v, want 1", count, err)
+			}
+
+			if _, err := repo.Get("", "aaaaa"); err != nil {
+				t.Errorf("expected link to be restored: got %v", err)
+			}
+
+			if count, err := repo.ForceRestore("", []string{"bbbbb"}); err != nil || count != 0 {
+				t.Errorf("expected expired link not to be restored: got %v, %v", count, err)
+			}
+
+			if count, err := repo.Restore("", []string{"aaaaa"}, owner); err != nil || count != 0 {
+				t.Errorf("expected active link not to be counted: got %v, %v", count, err)
+			}
+		})
+	}
+}
+
+// TestShortenerPurgeRemovesClicks проверяет, что очистка удаляет переходы по удалённым ссылкам,
+// и ссылка, созданная позже с тем же ключом, не наследует их статистику.
+func TestShortenerPurgeRemovesClicks(t *testing.T) {
+	owner := entity.Owner{UserID: "alice"}
+
+	for name, repo := range newTestRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, url := range []*entity.URL{
+				entity.NewURL("alice", "https://a.ru", "aaaaa"),
+				entity.NewURL("alice", "https://b.ru", "bbbbb"),
+			} {
+				if _, err := repo.Create(url); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			now := time.Now()
+			clicks := []*entity.Click{
+				entity.NewClick("", "aaaaa", "", "", "127.0.0.1", now),
+				entity.NewClick("", "aaaaa", "", "", "127.0.0.1", now),
+				entity.NewClick("", "bbbbb", "", "", "127.0.0.1", now),
+			}
+			if err := repo.SaveClicks(clicks); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, err := repo.ForceMarkAsDeleted("", []string{"aaaaa"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			count, err := repo.Purge(time.Now().Add(time.Minute))
+			if err != nil || count != 1 {
+				t.Fatalf("unexpected purge result: got %v, %v, want 1", count, err)
+			}
+
+			if stats, err := repo.GetClickStats("", "bbbbb", owner); err != nil || stats.Total != 1 {
+				t.Errorf("expected clicks of the active link to stay: got %+v, %v", stats, err)
+			}
+
+			if _, err := repo.Create(entity.NewURL("alice", "https://c.ru", "aaaaa")); err != nil {
+				t.Fatalf("expected purged short key to be free: %v", err)
+			}
+
+			if stats, err := repo.GetClickStats("", "aaaaa", owner); err != nil || stats.Total != 0 {
+				t.Errorf("expected new link to have no clicks of the purged one: got %+v, %v", stats, err)
+			}
+		})
+	}
+}
+
+// TestShortenerFileSequence проверяет, что файловый счётчик сохраняется в файл один раз на диапазон значений
+// и после перезапуска продолжает выдачу за границей сохранённого диапазона.
+func TestShortenerFileSequence(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+
+	repo, err := NewShortenerFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to create file repository: %v", err)
+	}
+
+	var last uint64
+	for i := 0; i < sequenceBlockSize+1; i++ {
+		n, err := repo.NextSequence()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n != last+1 {
+			t.Fatalf("expected consecutive values: got %d after %d", n, last)
+		}
+		last = n
+	}
+
+	data, err := os.ReadFile(filePath + ".seq")
+	if err != nil {
+		t.Fatalf("failed to read sequence file: %v", err)
+	}
+	if got, want := string(data), strconv.Itoa(2*sequenceBlockSize); got != want {
+		t.Errorf("expected reserved range limit in sequence file: got %q, want %q", got, want)
+	}
+
+	reopened, err := NewShortenerFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to reopen file repository: %v", err)
+	}
+
+	if n, err := reopened.NextSequence(); err != nil || n != 2*sequenceBlockSize+1 {
+		t.Errorf("unexpected value after restart: got %d, %v, want %d", n, err, 2*sequenceBlockSize+1)
+	}
+}
+
+// TestShortenerFileMarkExpiredRollback проверяет, что при ошибке перезаписи файла
+// истёкшие ссылки остаются в памяти в прежнем состоянии.
+func TestShortenerFileMarkExpiredRollback(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+
+	repo, err := NewShortenerFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to create file repository: %v", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(-time.Minute)
+	url := entity.NewURL("alice", "https://a.ru", "aaaaa")
+	url.ExpiresAt = &expiresAt
+
+	if _, err := repo.Create(url); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Каталог на месте временного файла не даёт перезаписать файл ссылок.
+	if err := os.Mkdir(filePath+".tmp", 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if _, err := repo.MarkExpiredAsDeleted(now); err == nil {
+		t.Fatal("expected rewrite error")
+	}
+
+	if got := repo.urls[repo.keys[domainKey("", "aaaaa")]]; got.DeletedFlag {
+		t.Error("expected link to stay not deleted after failed rewrite")
+	}
+}
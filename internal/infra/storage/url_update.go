@@ -0,0 +1,42 @@
+package storage
+
+import "github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
+
+// applyURLUpdate переносит в ссылку заданные поля изменения.
+func applyURLUpdate(url *entity.URL, update entity.URLUpdate) {
+	if update.OriginalURL != "" {
+		url.OriginalURL = update.OriginalURL
+	}
+
+	if update.RedirectType != nil {
+		url.RedirectType = *update.RedirectType
+	}
+
+	if update.PassQuery != nil {
+		url.PassQuery = *update.PassQuery
+	}
+
+	if update.UTM != nil {
+		url.UTM = utmOrNil(*update.UTM)
+	}
+
+	if update.Title != nil {
+		url.Title = *update.Title
+	}
+}
+
+// utmOrNil возвращает указатель на UTM-метки или nil, если ни одна метка не задана.
+func utmOrNil(utm entity.UTM) *entity.UTM {
+	if utm.IsEmpty() {
+		return nil
+	}
+	return &utm
+}
+
+// utmColumns возвращает значения колонок utm_source, utm_medium и utm_campaign.
+func utmColumns(utm *entity.UTM) (source, medium, campaign string) {
+	if utm == nil {
+		return "", "", ""
+	}
+	return utm.Source, utm.Medium, utm.Campaign
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+type UserDatabase struct {
+	db *pgxpool.Pool
+}
+
+// NewUserDatabase создает хранилище пользователей, использующее пул подключений репозитория ссылок.
+func NewUserDatabase(repo *ShortenerDatabase) *UserDatabase {
+	return &UserDatabase{db: repo.db}
+}
+
+// CreateUser сохраняет нового пользователя в базе данных, если email ещё не занят.
+func (ud *UserDatabase) CreateUser(user *entity.User) error {
+	query := `
+        INSERT INTO users (id, email, username, password_hash, role, created_at, oidc_issuer, oidc_subject)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))`
+
+	_, err := ud.db.Exec(context.Background(), query, user.ID, user.Email, user.Username, user.PasswordHash, user.UserRole(), user.CreatedAt, user.OIDCIssuer, user.OIDCSubject)
+	if err != nil {
+		if uniqueViolation(err) != nil {
+			return ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUser сохраняет роль, признак отключения и привязку к провайдеру OpenID Connect пользователя.
+func (ud *UserDatabase) UpdateUser(user *entity.User) error {
+	query := `
+        UPDATE users
+        SET role = $1, disabled_at = $2, oidc_issuer = NULLIF($3, ''), oidc_subject = NULLIF($4, '')
+        WHERE id = $5`
+
+	tag, err := ud.db.Exec(context.Background(), query, user.UserRole(), user.DisabledAt, user.OIDCIssuer, user.OIDCSubject, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetUserByEmail возвращает пользователя по email.
+func (ud *UserDatabase) GetUserByEmail(email string) (*entity.User, error) {
+	return ud.getUser("email = $1", email)
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (ud *UserDatabase) GetUserByID(id string) (*entity.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return ud.getUser("id = $1", id)
+}
+
+// GetUserByOIDCSubject возвращает пользователя, привязанного к пользователю subject провайдера issuer.
+func (ud *UserDatabase) GetUserByOIDCSubject(issuer, subject string) (*entity.User, error) {
+	return ud.getUser("oidc_issuer = $1 AND oidc_subject = $2", issuer, subject)
+}
+
+// getUser возвращает пользователя, удовлетворяющего условию where.
+func (ud *UserDatabase) getUser(where string, args ...interface{}) (*entity.User, error) {
+	var user entity.User
+
+	query := `
+        SELECT id, email, username, password_hash, role, created_at, disabled_at,
+            COALESCE(oidc_issuer, ''), COALESCE(oidc_subject, '')
+        FROM users WHERE ` + where
+
+	err := ud.db.QueryRow(context.Background(), query, args...).
+		Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.DisabledAt,
+			&user.OIDCIssuer, &user.OIDCSubject)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+// UserFile хранит пользователей в файле в формате JSON Lines.
+// Изменённый пользователь дописывается в конец файла, при чтении последняя запись пользователя заменяет предыдущие.
+type UserFile struct {
+	filePath string
+	users    map[string]entity.User // email -> пользователь
+	mu       sync.Mutex
+}
+
+// NewUserFile создает новое хранилище пользователей с сохранением данных в файл.
+func NewUserFile(filePath string) (*UserFile, error) {
+	repo := &UserFile{
+		filePath: filePath,
+		users:    make(map[string]entity.User),
+	}
+
+	if err := repo.readAll(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// CreateUser сохраняет нового пользователя в файл, если email ещё не занят.
+func (uf *UserFile) CreateUser(user *entity.User) error {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	if _, ok := uf.users[user.Email]; ok {
+		return ErrUserAlreadyExists
+	}
+
+	if err := uf.append(user); err != nil {
+		return err
+	}
+
+	uf.users[user.Email] = *user
+	return nil
+}
+
+// UpdateUser сохраняет роль, признак отключения и привязку к провайдеру OpenID Connect пользователя, дописывая изменённую запись в файл.
+func (uf *UserFile) UpdateUser(user *entity.User) error {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	current, ok := uf.users[user.Email]
+	if !ok || current.ID != user.ID {
+		return ErrUserNotFound
+	}
+
+	current.Role = user.Role
+	current.DisabledAt = user.DisabledAt
+	current.OIDCIssuer = user.OIDCIssuer
+	current.OIDCSubject = user.OIDCSubject
+
+	if err := uf.append(&current); err != nil {
+		return err
+	}
+
+	uf.users[user.Email] = current
+	return nil
+}
+
+// append дописывает запись пользователя в конец файла.
+func (uf *UserFile) append(user *entity.User) error {
+	f, err := os.OpenFile(uf.filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(user); err != nil {
+		return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+	}
+
+	return nil
+}
+
+// GetUserByEmail возвращает пользователя по email.
+func (uf *UserFile) GetUserByEmail(email string) (*entity.User, error) {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	user, ok := uf.users[email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// readAll читает всех пользователей из файла и загружает их в память.
+func (uf *UserFile) readAll() error {
+	if err := os.MkdirAll(path.Dir(uf.filePath), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrCreateDir, err)
+	}
+
+	f, err := os.OpenFile(uf.filePath, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+
+	for {
+		var user entity.User
+		if err := decoder.Decode(&user); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %v", ErrDecodeFile, err)
+		}
+
+		uf.users[user.Email] = user
+	}
+
+	return nil
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (uf *UserFile) GetUserByID(id string) (*entity.User, error) {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	for _, user := range uf.users {
+		if user.ID.String() == id {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+// GetUserByOIDCSubject возвращает пользователя, привязанного к пользователю subject провайдера issuer.
+func (uf *UserFile) GetUserByOIDCSubject(issuer, subject string) (*entity.User, error) {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	for _, user := range uf.users {
+		if user.OIDCIssuer == issuer && user.OIDCSubject == subject {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/auth/entity"
+)
+
+type UserMemory struct {
+	users map[string]entity.User // email -> пользователь
+	mu    sync.Mutex
+}
+
+// NewUserMemory создает новое хранилище пользователей в памяти.
+func NewUserMemory() *UserMemory {
+	return &UserMemory{
+		users: make(map[string]entity.User),
+	}
+}
+
+// CreateUser сохраняет нового пользователя, если email ещё не занят.
+func (um *UserMemory) CreateUser(user *entity.User) error {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	if _, ok := um.users[user.Email]; ok {
+		return ErrUserAlreadyExists
+	}
+
+	um.users[user.Email] = *user
+	return nil
+}
+
+// UpdateUser сохраняет роль, признак отключения и привязку к провайдеру OpenID Connect пользователя.
+func (um *UserMemory) UpdateUser(user *entity.User) error {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	current, ok := um.users[user.Email]
+	if !ok || current.ID != user.ID {
+		return ErrUserNotFound
+	}
+
+	current.Role = user.Role
+	current.DisabledAt = user.DisabledAt
+	current.OIDCIssuer = user.OIDCIssuer
+	current.OIDCSubject = user.OIDCSubject
+	um.users[user.Email] = current
+	return nil
+}
+
+// GetUserByEmail возвращает пользователя по email.
+func (um *UserMemory) GetUserByEmail(email string) (*entity.User, error) {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	user, ok := um.users[email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// GetUserByID возвращает пользователя по идентификатору.
+func (um *UserMemory) GetUserByID(id string) (*entity.User, error) {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	for _, user := range um.users {
+		if user.ID.String() == id {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+// GetUserByOIDCSubject возвращает пользователя, привязанного к пользователю subject провайдера issuer.
+func (um *UserMemory) GetUserByOIDCSubject(issuer, subject string) (*entity.User, error) {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	for _, user := range um.users {
+		if user.OIDCIssuer == issuer && user.OIDCSubject == subject {
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+// workspaceIndex хранит рабочие пространства и их участников в памяти.
+// Используется хранилищами в памяти и в файле; синхронизацию обеспечивает вызывающий.
+type workspaceIndex struct {
+	workspaces map[string]entity.Workspace         // идентификатор -> рабочее пространство
+	members    map[string]map[string]entity.Member // идентификатор пространства -> пользователь -> участник
+}
+
+func newWorkspaceIndex() workspaceIndex {
+	return workspaceIndex{
+		workspaces: make(map[string]entity.Workspace),
+		members:    make(map[string]map[string]entity.Member),
+	}
+}
+
+// putWorkspace сохраняет рабочее пространство.
+func (wi workspaceIndex) putWorkspace(workspace entity.Workspace) {
+	wi.workspaces[workspace.ID.String()] = workspace
+}
+
+// putMember добавляет участника или заменяет сохранённого.
+func (wi workspaceIndex) putMember(member entity.Member) {
+	workspaceID := member.WorkspaceID.String()
+	if wi.members[workspaceID] == nil {
+		wi.members[workspaceID] = make(map[string]entity.Member)
+	}
+	wi.members[workspaceID][member.UserID] = member
+}
+
+// deleteMember удаляет участника.
+func (wi workspaceIndex) deleteMember(workspaceID, userID string) {
+	delete(wi.members[workspaceID], userID)
+}
+
+// checkNewMember проверяет, что участника можно добавить: пространство существует, а пользователь ещё не участник.
+func (wi workspaceIndex) checkNewMember(member *entity.Member) error {
+	workspaceID := member.WorkspaceID.String()
+	if _, ok := wi.workspaces[workspaceID]; !ok {
+		return ErrWorkspaceNotFound
+	}
+	if _, ok := wi.members[workspaceID][member.UserID]; ok {
+		return ErrMemberExists
+	}
+	return nil
+}
+
+// member возвращает участника пространства.
+func (wi workspaceIndex) member(workspaceID, userID string) (*entity.Member, error) {
+	member, ok := wi.members[workspaceID][userID]
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+	return &member, nil
+}
+
+// checkOwnerRemains возвращает ErrLastOwner, если участник userID — единственный владелец пространства
+// и после изменения его роли на role или исключения (пустая role) в пространстве не останется владельцев.
+func (wi workspaceIndex) checkOwnerRemains(workspaceID, userID, role string) error {
+	member, ok := wi.members[workspaceID][userID]
+	if !ok || !member.IsOwner() || role == entity.RoleOwner {
+		return nil
+	}
+
+	for id, other := range wi.members[workspaceID] {
+		if id != userID && other.IsOwner() {
+			return nil
+		}
+	}
+
+	return ErrLastOwner
+}
+
+// memberList возвращает участников пространства в порядке добавления.
+func (wi workspaceIndex) memberList(workspaceID string) []*entity.Member {
+	members := make([]*entity.Member, 0, len(wi.members[workspaceID]))
+	for _, member := range wi.members[workspaceID] {
+		members = append(members, &member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].AddedAt.Before(members[j].AddedAt)
+	})
+	return members
+}
+
+// memberships возвращает пространства, участником которых является пользователь, в порядке создания.
+func (wi workspaceIndex) memberships(userID string) []*entity.Membership {
+	memberships := make([]*entity.Membership, 0)
+	for workspaceID, members := range wi.members {
+		member, ok := members[userID]
+		if !ok {
+			continue
+		}
+		memberships = append(memberships, &entity.Membership{Workspace: wi.workspaces[workspaceID], Role: member.Role})
+	}
+
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].CreatedAt.Before(memberships[j].CreatedAt)
+	})
+	return memberships
+}
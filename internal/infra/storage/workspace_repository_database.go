@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+type WorkspaceDatabase struct {
+	db *pgxpool.Pool
+}
+
+// NewWorkspaceDatabase создает хранилище рабочих пространств, использующее пул подключений репозитория ссылок.
+func NewWorkspaceDatabase(repo *ShortenerDatabase) *WorkspaceDatabase {
+	return &WorkspaceDatabase{db: repo.db}
+}
+
+// CreateWorkspace сохраняет новое рабочее пространство вместе с его владельцем в одной транзакции.
+func (wd *WorkspaceDatabase) CreateWorkspace(workspace *entity.Workspace, owner *entity.Member) error {
+	ctx := context.Background()
+
+	tx, err := wd.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := "INSERT INTO workspaces (id, name, created_at) VALUES ($1, $2, $3)"
+	if _, err := tx.Exec(ctx, query, workspace.ID, workspace.Name, workspace.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert workspace: %w", err)
+	}
+
+	if err := insertMember(ctx, tx, owner); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListWorkspaces возвращает рабочие пространства, участником которых является пользователь, с его ролью в каждом.
+func (wd *WorkspaceDatabase) ListWorkspaces(userID string) ([]*entity.Membership, error) {
+	if !isUUID(userID) {
+		return []*entity.Membership{}, nil
+	}
+
+	query := `
+        SELECT w.id, w.name, w.created_at, m.role
+        FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+        WHERE m.user_id = $1
+        ORDER BY w.created_at`
+
+	rows, err := wd.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := make([]*entity.Membership, 0)
+	for rows.Next() {
+		var membership entity.Membership
+		if err := rows.Scan(&membership.ID, &membership.Name, &membership.CreatedAt, &membership.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+		memberships = append(memberships, &membership)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return memberships, nil
+}
+
+// GetMember возвращает участника рабочего пространства.
+func (wd *WorkspaceDatabase) GetMember(workspaceID, userID string) (*entity.Member, error) {
+	if !isUUID(workspaceID) || !isUUID(userID) {
+		return nil, ErrMemberNotFound
+	}
+
+	var member entity.Member
+	query := "SELECT workspace_id, user_id::text, email, role, added_at FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+
+	err := wd.db.QueryRow(context.Background(), query, workspaceID, userID).
+		Scan(&member.WorkspaceID, &member.UserID, &member.Email, &member.Role, &member.AddedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to get workspace member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// ListMembers возвращает участников рабочего пространства в порядке добавления.
+func (wd *WorkspaceDatabase) ListMembers(workspaceID string) ([]*entity.Member, error) {
+	if !isUUID(workspaceID) {
+		return []*entity.Member{}, nil
+	}
+
+	query := `
+        SELECT workspace_id, user_id::text, email, role, added_at
+        FROM workspace_members WHERE workspace_id = $1
+        ORDER BY added_at`
+
+	rows, err := wd.db.Query(context.Background(), query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]*entity.Member, 0)
+	for rows.Next() {
+		var member entity.Member
+		if err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Email, &member.Role, &member.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member: %w", err)
+		}
+		members = append(members, &member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember добавляет участника в рабочее пространство, если пользователь ещё не участник.
+func (wd *WorkspaceDatabase) AddMember(member *entity.Member) error {
+	ctx := context.Background()
+
+	tx, err := wd.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertMember(ctx, tx, member); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertMember добавляет участника рабочего пространства в транзакции tx.
+func insertMember(ctx context.Context, tx pgx.Tx, member *entity.Member) error {
+	query := `
+        INSERT INTO workspace_members (workspace_id, user_id, email, role, added_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, member.WorkspaceID, member.UserID, member.Email, member.Role, member.AddedAt)
+	if err != nil {
+		if uniqueViolation(err) != nil {
+			return ErrMemberExists
+		}
+		return fmt.Errorf("failed to insert workspace member: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateMember сохраняет роль участника рабочего пространства.
+// Возвращает ErrLastOwner, если роль понижается у последнего владельца.
+func (wd *WorkspaceDatabase) UpdateMember(member *entity.Member) error {
+	ctx := context.Background()
+
+	tx, err := wd.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkOwnerRemains(ctx, tx, member.WorkspaceID.String(), member.UserID, member.Role); err != nil {
+		return err
+	}
+
+	query := "UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3"
+
+	tag, err := tx.Exec(ctx, query, member.Role, member.WorkspaceID, member.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to update workspace member: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveMember удаляет участника из рабочего пространства.
+// Возвращает ErrLastOwner, если исключается последний владелец.
+func (wd *WorkspaceDatabase) RemoveMember(workspaceID, userID string) error {
+	if !isUUID(workspaceID) || !isUUID(userID) {
+		return ErrMemberNotFound
+	}
+
+	ctx := context.Background()
+
+	tx, err := wd.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkOwnerRemains(ctx, tx, workspaceID, userID, ""); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+
+	tag, err := tx.Exec(ctx, query, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// checkOwnerRemains блокирует записи владельцев пространства до конца транзакции tx и возвращает ErrLastOwner,
+// если участник userID — единственный владелец и после изменения его роли на role или исключения (пустая role)
+// владельцев не останется. Блокировка не даёт одновременным запросам понизить или исключить двух последних владельцев.
+func checkOwnerRemains(ctx context.Context, tx pgx.Tx, workspaceID, userID, role string) error {
+	if role == entity.RoleOwner {
+		return nil
+	}
+
+	query := "SELECT user_id::text FROM workspace_members WHERE workspace_id = $1 AND role = $2 FOR UPDATE"
+
+	rows, err := tx.Query(ctx, query, workspaceID, entity.RoleOwner)
+	if err != nil {
+		return fmt.Errorf("failed to lock workspace owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := make([]string, 0)
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return fmt.Errorf("failed to scan workspace owner: %w", err)
+		}
+		owners = append(owners, owner)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(owners) == 1 && owners[0] == userID {
+		return ErrLastOwner
+	}
+
+	return nil
+}
+
+// isUUID сообщает, что строка является UUID; иначе запрос к колонке типа UUID завершился бы ошибкой.
+func isUUID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+// workspaceRecord — строка файла рабочих пространств: новое пространство, изменённый участник
+// или отметка об исключении участника.
+type workspaceRecord struct {
+	Workspace *entity.Workspace `json:"workspace,omitempty"`
+	Member    *entity.Member    `json:"member,omitempty"`
+	Removed   bool              `json:"removed,omitempty"` // Участник Member исключён из пространства
+}
+
+// WorkspaceFile хранит рабочие пространства и их участников в файле в формате JSON Lines.
+// Изменения дописываются в конец файла, при чтении последняя запись участника заменяет предыдущие.
+type WorkspaceFile struct {
+	filePath string
+	index    workspaceIndex
+	mu       sync.Mutex
+}
+
+// NewWorkspaceFile создает новое хранилище рабочих пространств с сохранением данных в файл.
+func NewWorkspaceFile(filePath string) (*WorkspaceFile, error) {
+	repo := &WorkspaceFile{
+		filePath: filePath,
+		index:    newWorkspaceIndex(),
+	}
+
+	if err := repo.readAll(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// CreateWorkspace сохраняет в файл новое рабочее пространство вместе с его владельцем.
+func (wf *WorkspaceFile) CreateWorkspace(workspace *entity.Workspace, owner *entity.Member) error {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	if err := wf.append(workspaceRecord{Workspace: workspace}, workspaceRecord{Member: owner}); err != nil {
+		return err
+	}
+
+	wf.index.putWorkspace(*workspace)
+	wf.index.putMember(*owner)
+	return nil
+}
+
+// ListWorkspaces возвращает рабочие пространства, участником которых является пользователь, с его ролью в каждом.
+func (wf *WorkspaceFile) ListWorkspaces(userID string) ([]*entity.Membership, error) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	return wf.index.memberships(userID), nil
+}
+
+// GetMember возвращает участника рабочего пространства.
+func (wf *WorkspaceFile) GetMember(workspaceID, userID string) (*entity.Member, error) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	return wf.index.member(workspaceID, userID)
+}
+
+// ListMembers возвращает участников рабочего пространства.
+func (wf *WorkspaceFile) ListMembers(workspaceID string) ([]*entity.Member, error) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	return wf.index.memberList(workspaceID), nil
+}
+
+// AddMember добавляет участника в рабочее пространство, если пользователь ещё не участник.
+func (wf *WorkspaceFile) AddMember(member *entity.Member) error {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	if err := wf.index.checkNewMember(member); err != nil {
+		return err
+	}
+
+	if err := wf.append(workspaceRecord{Member: member}); err != nil {
+		return err
+	}
+
+	wf.index.putMember(*member)
+	return nil
+}
+
+// UpdateMember сохраняет роль участника рабочего пространства, дописывая изменённую запись в файл.
+// Возвращает ErrLastOwner, если роль понижается у последнего владельца; проверка выполняется под блокировкой хранилища.
+func (wf *WorkspaceFile) UpdateMember(member *entity.Member) error {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	current, err := wf.index.member(member.WorkspaceID.String(), member.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := wf.index.checkOwnerRemains(member.WorkspaceID.String(), member.UserID, member.Role); err != nil {
+		return err
+	}
+
+	current.Role = member.Role
+
+	if err := wf.append(workspaceRecord{Member: current}); err != nil {
+		return err
+	}
+
+	wf.index.putMember(*current)
+	return nil
+}
+
+// RemoveMember исключает участника из рабочего пространства, дописывая в файл отметку об исключении.
+// Возвращает ErrLastOwner, если исключается последний владелец; проверка выполняется под блокировкой хранилища.
+func (wf *WorkspaceFile) RemoveMember(workspaceID, userID string) error {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	current, err := wf.index.member(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := wf.index.checkOwnerRemains(workspaceID, userID, ""); err != nil {
+		return err
+	}
+
+	if err := wf.append(workspaceRecord{Member: current, Removed: true}); err != nil {
+		return err
+	}
+
+	wf.index.deleteMember(workspaceID, userID)
+	return nil
+}
+
+// append дописывает записи в конец файла.
+func (wf *WorkspaceFile) append(records ...workspaceRecord) error {
+	f, err := os.OpenFile(wf.filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("%w: %v", ErrEncodeFile, err)
+		}
+	}
+
+	return nil
+}
+
+// readAll читает записи из файла и восстанавливает по ним рабочие пространства и их участников.
+func (wf *WorkspaceFile) readAll() error {
+	if err := os.MkdirAll(path.Dir(wf.filePath), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrCreateDir, err)
+	}
+
+	f, err := os.OpenFile(wf.filePath, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOpenFile, err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+
+	for {
+		var record workspaceRecord
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %v", ErrDecodeFile, err)
+		}
+
+		switch {
+		case record.Workspace != nil:
+			wf.index.putWorkspace(*record.Workspace)
+		case record.Member != nil && record.Removed:
+			wf.index.deleteMember(record.Member.WorkspaceID.String(), record.Member.UserID)
+		case record.Member != nil:
+			wf.index.putMember(*record.Member)
+		}
+	}
+
+	return nil
+}
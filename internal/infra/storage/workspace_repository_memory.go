@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+type WorkspaceMemory struct {
+	index workspaceIndex
+	mu    sync.Mutex
+}
+
+// NewWorkspaceMemory создает новое хранилище рабочих пространств в памяти.
+func NewWorkspaceMemory() *WorkspaceMemory {
+	return &WorkspaceMemory{
+		index: newWorkspaceIndex(),
+	}
+}
+
+// CreateWorkspace сохраняет новое рабочее пространство вместе с его владельцем.
+func (wm *WorkspaceMemory) CreateWorkspace(workspace *entity.Workspace, owner *entity.Member) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	wm.index.putWorkspace(*workspace)
+	wm.index.putMember(*owner)
+	return nil
+}
+
+// ListWorkspaces возвращает рабочие пространства, участником которых является пользователь, с его ролью в каждом.
+func (wm *WorkspaceMemory) ListWorkspaces(userID string) ([]*entity.Membership, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	return wm.index.memberships(userID), nil
+}
+
+// GetMember возвращает участника рабочего пространства.
+func (wm *WorkspaceMemory) GetMember(workspaceID, userID string) (*entity.Member, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	return wm.index.member(workspaceID, userID)
+}
+
+// ListMembers возвращает участников рабочего пространства.
+func (wm *WorkspaceMemory) ListMembers(workspaceID string) ([]*entity.Member, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	return wm.index.memberList(workspaceID), nil
+}
+
+// AddMember добавляет участника в рабочее пространство, если пользователь ещё не участник.
+func (wm *WorkspaceMemory) AddMember(member *entity.Member) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	if err := wm.index.checkNewMember(member); err != nil {
+		return err
+	}
+
+	wm.index.putMember(*member)
+	return nil
+}
+
+// UpdateMember сохраняет роль участника рабочего пространства.
+// Возвращает ErrLastOwner, если роль понижается у последнего владельца; проверка выполняется под блокировкой хранилища.
+func (wm *WorkspaceMemory) UpdateMember(member *entity.Member) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	current, err := wm.index.member(member.WorkspaceID.String(), member.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := wm.index.checkOwnerRemains(member.WorkspaceID.String(), member.UserID, member.Role); err != nil {
+		return err
+	}
+
+	current.Role = member.Role
+	wm.index.putMember(*current)
+	return nil
+}
+
+// RemoveMember удаляет участника из рабочего пространства.
+// Возвращает ErrLastOwner, если исключается последний владелец; проверка выполняется под блокировкой хранилища.
+func (wm *WorkspaceMemory) RemoveMember(workspaceID, userID string) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	if _, err := wm.index.member(workspaceID, userID); err != nil {
+		return err
+	}
+
+	if err := wm.index.checkOwnerRemains(workspaceID, userID, ""); err != nil {
+		return err
+	}
+
+	wm.index.deleteMember(workspaceID, userID)
+	return nil
+}
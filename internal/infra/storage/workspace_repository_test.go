@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Kenny201/go-yandex-shortener.git/internal/domain/workspace/entity"
+)
+
+// workspaceTestRepository — методы хранилищ рабочих пространств в памяти и в файле, которые проверяются одинаково.
+type workspaceTestRepository interface {
+	CreateWorkspace(workspace *entity.Workspace, owner *entity.Member) error
+	AddMember(member *entity.Member) error
+	UpdateMember(member *entity.Member) error
+	RemoveMember(workspaceID, userID string) error
+	ListMembers(workspaceID string) ([]*entity.Member, error)
+}
+
+// TestWorkspaceKeepsLastOwner проверяет, что одновременное понижение и исключение двух последних владельцев
+// оставляет в пространстве одного владельца, а последнего владельца нельзя понизить или исключить.
+func TestWorkspaceKeepsLastOwner(t *testing.T) {
+	fileRepo, err := NewWorkspaceFile(filepath.Join(t.TempDir(), "urls.json.workspaces"))
+	if err != nil {
+		t.Fatalf("failed to create file repository: %v", err)
+	}
+
+	repositories := map[string]workspaceTestRepository{
+		"memory": NewWorkspaceMemory(),
+		"file":   fileRepo,
+	}
+
+	for name, repo := range repositories {
+		t.Run(name, func(t *testing.T) {
+			workspace := entity.NewWorkspace("Team")
+			alice := entity.NewMember(workspace.ID, "alice", "alice@example.com", entity.RoleOwner)
+			bob := entity.NewMember(workspace.ID, "bob", "bob@example.com", entity.RoleOwner)
+
+			if err := repo.CreateWorkspace(workspace, alice); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := repo.AddMember(bob); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make([]error, 2)
+
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				errs[0] = repo.UpdateMember(entity.NewMember(workspace.ID, "alice", "alice@example.com", entity.RoleEditor))
+			}()
+			go func() {
+				defer wg.Done()
+				errs[1] = repo.RemoveMember(workspace.ID.String(), "bob")
+			}()
+			wg.Wait()
+
+			failed := 0
+			for _, err := range errs {
+				if errors.Is(err, ErrLastOwner) {
+					failed++
+				} else if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if failed != 1 {
+				t.Fatalf("expected exactly one of the changes to be rejected: got %v", errs)
+			}
+
+			members, err := repo.ListMembers(workspace.ID.String())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var owners []string
+			for _, member := range members {
+				if member.IsOwner() {
+					owners = append(owners, member.UserID)
+				}
+			}
+			if len(owners) != 1 {
+				t.Fatalf("expected one owner to remain: got %v", owners)
+			}
+
+			last := owners[0]
+			if err := repo.UpdateMember(entity.NewMember(workspace.ID, last, "", entity.RoleViewer)); !errors.Is(err, ErrLastOwner) {
+				t.Errorf("expected last owner not to be demoted: got %v", err)
+			}
+			if err := repo.RemoveMember(workspace.ID.String(), last); !errors.Is(err, ErrLastOwner) {
+				t.Errorf("expected last owner not to be removed: got %v", err)
+			}
+			if err := repo.UpdateMember(entity.NewMember(workspace.ID, last, "", entity.RoleOwner)); err != nil {
+				t.Errorf("expected owner role to be kept: got %v", err)
+			}
+		})
+	}
+}
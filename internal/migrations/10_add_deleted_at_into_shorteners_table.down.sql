@@ -0,0 +1,2 @@
+ALTER TABLE shorteners
+    DROP COLUMN deleted_at;
\ No newline at end of file
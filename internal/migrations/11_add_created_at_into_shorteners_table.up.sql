@@ -0,0 +1,3 @@
+ALTER TABLE shorteners ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS shorteners_user_id_created_at_idx ON shorteners (user_id, created_at, id);
\ No newline at end of file
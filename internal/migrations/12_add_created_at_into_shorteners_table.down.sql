@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS shorteners_user_id_created_at_idx;
+
+ALTER TABLE shorteners
+    DROP COLUMN created_at;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS tags
+(
+    id   BIGSERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS shortener_tags
+(
+    shortener_id UUID   NOT NULL REFERENCES shorteners (id) ON DELETE CASCADE,
+    tag_id       BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (shortener_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS shortener_tags_tag_id_idx ON shortener_tags (tag_id);
\ No newline at end of file
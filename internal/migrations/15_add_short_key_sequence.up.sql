@@ -0,0 +1 @@
+CREATE SEQUENCE IF NOT EXISTS short_key_seq;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Ссылки, получившие уже занятый ключ до появления ограничения, получают ключ с суффиксом из своего id.
+UPDATE shorteners s
+SET short_key = s.short_key || '_' || substr(replace(s.id::text, '-', ''), 1, 8)
+FROM (SELECT id, row_number() OVER (PARTITION BY short_key ORDER BY created_at, id) AS rn FROM shorteners) d
+WHERE s.id = d.id AND d.rn > 1;
+
+ALTER TABLE shorteners ADD CONSTRAINT shorteners_short_key_unique UNIQUE (short_key);
\ No newline at end of file
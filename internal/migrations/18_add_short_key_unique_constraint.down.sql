@@ -0,0 +1 @@
+ALTER TABLE shorteners DROP CONSTRAINT IF EXISTS shorteners_short_key_unique;
\ No newline at end of file
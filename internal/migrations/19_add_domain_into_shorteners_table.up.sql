@@ -0,0 +1,6 @@
+ALTER TABLE shorteners ADD COLUMN domain VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE shorteners DROP CONSTRAINT IF EXISTS shorteners_short_key_unique;
+ALTER TABLE shorteners ADD CONSTRAINT shorteners_domain_short_key_unique UNIQUE (domain, short_key);
+
+ALTER TABLE clicks ADD COLUMN domain VARCHAR NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE clicks DROP COLUMN IF EXISTS domain;
+
+ALTER TABLE shorteners DROP CONSTRAINT IF EXISTS shorteners_domain_short_key_unique;
+ALTER TABLE shorteners ADD CONSTRAINT shorteners_short_key_unique UNIQUE (short_key);
+
+ALTER TABLE shorteners DROP COLUMN IF EXISTS domain;
\ No newline at end of file
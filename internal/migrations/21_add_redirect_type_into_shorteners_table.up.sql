@@ -0,0 +1 @@
+ALTER TABLE shorteners ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
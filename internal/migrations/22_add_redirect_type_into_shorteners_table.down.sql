@@ -0,0 +1 @@
+ALTER TABLE shorteners DROP COLUMN IF EXISTS redirect_type;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE shorteners ADD COLUMN pass_query BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE shorteners ADD COLUMN utm_source VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE shorteners ADD COLUMN utm_medium VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE shorteners ADD COLUMN utm_campaign VARCHAR NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE shorteners DROP COLUMN IF EXISTS utm_campaign;
+ALTER TABLE shorteners DROP COLUMN IF EXISTS utm_medium;
+ALTER TABLE shorteners DROP COLUMN IF EXISTS utm_source;
+ALTER TABLE shorteners DROP COLUMN IF EXISTS pass_query;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE shorteners ADD COLUMN IF NOT EXISTS title VARCHAR NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE shorteners DROP COLUMN IF EXISTS title;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users
+(
+    id            UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email         VARCHAR     NOT NULL UNIQUE,
+    username      VARCHAR     NOT NULL DEFAULT '',
+    password_hash VARCHAR     NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id         UUID PRIMARY KEY,
+    user_id    UUID        NOT NULL,
+    name       VARCHAR     NOT NULL DEFAULT '',
+    prefix     VARCHAR     NOT NULL,
+    key_hash   VARCHAR     NOT NULL UNIQUE,
+    scopes     VARCHAR[]   NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
\ No newline at end of file
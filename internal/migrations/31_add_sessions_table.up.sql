@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id         UUID PRIMARY KEY,
+    user_id    UUID        NOT NULL,
+    token_hash VARCHAR     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role        VARCHAR NOT NULL DEFAULT 'user',
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
\ No newline at end of file
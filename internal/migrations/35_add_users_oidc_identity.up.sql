@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS oidc_issuer  VARCHAR,
+    ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_identity_idx ON users (oidc_issuer, oidc_subject);
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS workspaces
+(
+    id         UUID PRIMARY KEY,
+    name       VARCHAR     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members
+(
+    workspace_id UUID        NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id      UUID        NOT NULL,
+    email        VARCHAR     NOT NULL,
+    role         VARCHAR     NOT NULL,
+    added_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members (user_id);
+
+ALTER TABLE shorteners
+    ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES workspaces (id);
+
+CREATE INDEX IF NOT EXISTS shorteners_workspace_id_idx ON shorteners (workspace_id);
\ No newline at end of file
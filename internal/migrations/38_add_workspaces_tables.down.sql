@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS shorteners_workspace_id_idx;
+
+ALTER TABLE shorteners
+    DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspace_members;
+
+DROP TABLE IF EXISTS workspaces;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Один и тот же адрес можно сократить на каждом из доменов, но только один раз в пределах домена.
+ALTER TABLE shorteners DROP CONSTRAINT IF EXISTS shorteners_original_url_key;
+ALTER TABLE shorteners ADD CONSTRAINT shorteners_domain_original_url_unique UNIQUE (domain, original_url);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE shorteners DROP CONSTRAINT IF EXISTS shorteners_domain_original_url_unique;
+ALTER TABLE shorteners ADD CONSTRAINT shorteners_original_url_key UNIQUE (original_url);
\ No newline at end of file
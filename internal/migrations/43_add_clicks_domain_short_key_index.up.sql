@@ -0,0 +1,3 @@
+-- Клики ищутся и удаляются по домену и короткому ключу ссылки.
+CREATE INDEX IF NOT EXISTS clicks_domain_short_key_clicked_at_idx ON clicks (domain, short_key, clicked_at);
+DROP INDEX IF EXISTS clicks_short_key_clicked_at_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS clicks_short_key_clicked_at_idx ON clicks (short_key, clicked_at);
+DROP INDEX IF EXISTS clicks_domain_short_key_clicked_at_idx;
@@ -0,0 +1,3 @@
+-- Хеш IP-адреса создателя ссылки: по нему считается суточная квота IP-адреса.
+ALTER TABLE shorteners ADD COLUMN IF NOT EXISTS creator_ip_hash VARCHAR;
+CREATE INDEX IF NOT EXISTS shorteners_creator_ip_hash_created_at_idx ON shorteners (creator_ip_hash, created_at) WHERE creator_ip_hash IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS shorteners_creator_ip_hash_created_at_idx;
+ALTER TABLE shorteners DROP COLUMN IF EXISTS creator_ip_hash;
@@ -0,0 +1 @@
+ALTER TABLE shorteners ADD COLUMN expires_at TIMESTAMPTZ DEFAULT NULL;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS clicks
+(
+    id         BIGSERIAL PRIMARY KEY,
+    short_key  VARCHAR     NOT NULL,
+    clicked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    referrer   VARCHAR,
+    user_agent VARCHAR,
+    ip         VARCHAR
+);
+
+CREATE INDEX IF NOT EXISTS clicks_short_key_clicked_at_idx ON clicks (short_key, clicked_at);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE shorteners ADD COLUMN deleted_at TIMESTAMPTZ DEFAULT NULL;
+
+UPDATE shorteners SET deleted_at = now() WHERE is_deleted = true;
\ No newline at end of file
@@ -6,6 +6,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	entity "github.com/Kenny201/go-yandex-shortener.git/internal/domain/shortener/entity"
 	gomock "github.com/golang/mock/gomock"
@@ -34,6 +35,21 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddTags mocks base method.
+func (m *MockRepository) AddTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTags", domain, shortKey, owner, tags)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddTags indicates an expected call of AddTags.
+func (mr *MockRepositoryMockRecorder) AddTags(domain, shortKey, owner, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTags", reflect.TypeOf((*MockRepository)(nil).AddTags), domain, shortKey, owner, tags)
+}
+
 // CheckHealth mocks base method.
 func (m *MockRepository) CheckHealth() error {
 	m.ctrl.T.Helper()
@@ -48,6 +64,21 @@ func (mr *MockRepositoryMockRecorder) CheckHealth() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckHealth", reflect.TypeOf((*MockRepository)(nil).CheckHealth))
 }
 
+// CountUsage mocks base method.
+func (m *MockRepository) CountUsage(userID, ipHash string, createdSince time.Time) (entity.URLUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsage", userID, ipHash, createdSince)
+	ret0, _ := ret[0].(entity.URLUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsage indicates an expected call of CountUsage.
+func (mr *MockRepositoryMockRecorder) CountUsage(userID, ipHash, createdSince interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsage", reflect.TypeOf((*MockRepository)(nil).CountUsage), userID, ipHash, createdSince)
+}
+
 // Create mocks base method.
 func (m *MockRepository) Create(url *entity.URL) (*entity.URL, error) {
 	m.ctrl.T.Helper()
@@ -78,46 +109,265 @@ func (mr *MockRepositoryMockRecorder) CreateList(userID, urls interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateList", reflect.TypeOf((*MockRepository)(nil).CreateList), userID, urls)
 }
 
+// FindActive mocks base method.
+func (m *MockRepository) FindActive(match func(string) bool) ([]*entity.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindActive", match)
+	ret0, _ := ret[0].([]*entity.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindActive indicates an expected call of FindActive.
+func (mr *MockRepositoryMockRecorder) FindActive(match interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindActive", reflect.TypeOf((*MockRepository)(nil).FindActive), match)
+}
+
+// FindAll mocks base method.
+func (m *MockRepository) FindAll(opts entity.ListOptions) ([]*entity.URLItem, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", opts)
+	ret0, _ := ret[0].([]*entity.URLItem)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockRepositoryMockRecorder) FindAll(opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockRepository)(nil).FindAll), opts)
+}
+
+// ForceMarkAsDeleted mocks base method.
+func (m *MockRepository) ForceMarkAsDeleted(domain string, shortKeys []string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceMarkAsDeleted", domain, shortKeys)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ForceMarkAsDeleted indicates an expected call of ForceMarkAsDeleted.
+func (mr *MockRepositoryMockRecorder) ForceMarkAsDeleted(domain, shortKeys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceMarkAsDeleted", reflect.TypeOf((*MockRepository)(nil).ForceMarkAsDeleted), domain, shortKeys)
+}
+
+// ForceRestore mocks base method.
+func (m *MockRepository) ForceRestore(domain string, shortKeys []string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceRestore", domain, shortKeys)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ForceRestore indicates an expected call of ForceRestore.
+func (mr *MockRepositoryMockRecorder) ForceRestore(domain, shortKeys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceRestore", reflect.TypeOf((*MockRepository)(nil).ForceRestore), domain, shortKeys)
+}
+
 // Get mocks base method.
-func (m *MockRepository) Get(shortKey string) (*entity.URL, error) {
+func (m *MockRepository) Get(domain, shortKey string) (*entity.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", shortKey)
+	ret := m.ctrl.Call(m, "Get", domain, shortKey)
 	ret0, _ := ret[0].(*entity.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockRepositoryMockRecorder) Get(shortKey interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Get(domain, shortKey interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRepository)(nil).Get), shortKey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRepository)(nil).Get), domain, shortKey)
 }
 
 // GetAll mocks base method.
-func (m *MockRepository) GetAll(userID string) ([]*entity.URLItem, error) {
+func (m *MockRepository) GetAll(owner entity.Owner, opts entity.ListOptions) ([]*entity.URLItem, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", userID)
+	ret := m.ctrl.Call(m, "GetAll", owner, opts)
 	ret0, _ := ret[0].([]*entity.URLItem)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockRepositoryMockRecorder) GetAll(owner, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockRepository)(nil).GetAll), owner, opts)
+}
+
+// GetClickStats mocks base method.
+func (m *MockRepository) GetClickStats(domain, shortKey string, owner entity.Owner) (*entity.ClickStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClickStats", domain, shortKey, owner)
+	ret0, _ := ret[0].(*entity.ClickStats)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockRepositoryMockRecorder) GetAll(userID interface{}) *gomock.Call {
+// GetClickStats indicates an expected call of GetClickStats.
+func (mr *MockRepositoryMockRecorder) GetClickStats(domain, shortKey, owner interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockRepository)(nil).GetAll), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClickStats", reflect.TypeOf((*MockRepository)(nil).GetClickStats), domain, shortKey, owner)
 }
 
 // MarkAsDeleted mocks base method.
-func (m *MockRepository) MarkAsDeleted(batch []string, userID string) error {
+func (m *MockRepository) MarkAsDeleted(domain string, batch []string, owner entity.Owner) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MarkAsDeleted", batch, userID)
+	ret := m.ctrl.Call(m, "MarkAsDeleted", domain, batch, owner)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // MarkAsDeleted indicates an expected call of MarkAsDeleted.
-func (mr *MockRepositoryMockRecorder) MarkAsDeleted(batch, userID interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) MarkAsDeleted(domain, batch, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAsDeleted", reflect.TypeOf((*MockRepository)(nil).MarkAsDeleted), domain, batch, owner)
+}
+
+// MarkExpiredAsDeleted mocks base method.
+func (m *MockRepository) MarkExpiredAsDeleted(now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkExpiredAsDeleted", now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkExpiredAsDeleted indicates an expected call of MarkExpiredAsDeleted.
+func (mr *MockRepositoryMockRecorder) MarkExpiredAsDeleted(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkExpiredAsDeleted", reflect.TypeOf((*MockRepository)(nil).MarkExpiredAsDeleted), now)
+}
+
+// Purge mocks base method.
+func (m *MockRepository) Purge(deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockRepositoryMockRecorder) Purge(deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockRepository)(nil).Purge), deletedBefore)
+}
+
+// RemoveTags mocks base method.
+func (m *MockRepository) RemoveTags(domain, shortKey string, owner entity.Owner, tags []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTags", domain, shortKey, owner, tags)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveTags indicates an expected call of RemoveTags.
+func (mr *MockRepositoryMockRecorder) RemoveTags(domain, shortKey, owner, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTags", reflect.TypeOf((*MockRepository)(nil).RemoveTags), domain, shortKey, owner, tags)
+}
+
+// Restore mocks base method.
+func (m *MockRepository) Restore(domain string, batch []string, owner entity.Owner) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", domain, batch, owner)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockRepositoryMockRecorder) Restore(domain, batch, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockRepository)(nil).Restore), domain, batch, owner)
+}
+
+// SaveClicks mocks base method.
+func (m *MockRepository) SaveClicks(clicks []*entity.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveClicks", clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveClicks indicates an expected call of SaveClicks.
+func (mr *MockRepositoryMockRecorder) SaveClicks(clicks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveClicks", reflect.TypeOf((*MockRepository)(nil).SaveClicks), clicks)
+}
+
+// TransferOwnership mocks base method.
+func (m *MockRepository) TransferOwnership(fromUserID, toUserID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", fromUserID, toUserID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockRepositoryMockRecorder) TransferOwnership(fromUserID, toUserID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockRepository)(nil).TransferOwnership), fromUserID, toUserID)
+}
+
+// Update mocks base method.
+func (m *MockRepository) Update(domain, shortKey string, owner entity.Owner, update entity.URLUpdate) (*entity.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", domain, shortKey, owner, update)
+	ret0, _ := ret[0].(*entity.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRepositoryMockRecorder) Update(domain, shortKey, owner, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), domain, shortKey, owner, update)
+}
+
+// MockBlocklist is a mock of Blocklist interface.
+type MockBlocklist struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlocklistMockRecorder
+}
+
+// MockBlocklistMockRecorder is the mock recorder for MockBlocklist.
+type MockBlocklistMockRecorder struct {
+	mock *MockBlocklist
+}
+
+// NewMockBlocklist creates a new mock instance.
+func NewMockBlocklist(ctrl *gomock.Controller) *MockBlocklist {
+	mock := &MockBlocklist{ctrl: ctrl}
+	mock.recorder = &MockBlocklistMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlocklist) EXPECT() *MockBlocklistMockRecorder {
+	return m.recorder
+}
+
+// Match mocks base method.
+func (m *MockBlocklist) Match(rawURL string) (string, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Match", rawURL)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Match indicates an expected call of Match.
+func (mr *MockBlocklistMockRecorder) Match(rawURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAsDeleted", reflect.TypeOf((*MockRepository)(nil).MarkAsDeleted), batch, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Match", reflect.TypeOf((*MockBlocklist)(nil).Match), rawURL)
 }
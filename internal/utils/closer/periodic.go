@@ -0,0 +1,45 @@
+package closer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunPeriodically выполняет job с заданным интервалом в отдельной горутине
+// и регистрирует её остановку в CL.
+func RunPeriodically(name string, interval time.Duration, job func() error) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		slog.Info("Periodic job started", slog.String("job", name), slog.Duration("interval", interval))
+		for {
+			select {
+			case <-ticker.C:
+				if err := job(); err != nil {
+					slog.Error("Periodic job failed", slog.String("job", name), slog.String("error", err.Error()))
+				}
+			case <-stop:
+				slog.Info("Periodic job stopped", slog.String("job", name))
+				return
+			}
+		}
+	}()
+
+	CL.Add(func(ctx context.Context) error {
+		close(stop)
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}